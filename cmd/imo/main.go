@@ -0,0 +1,370 @@
+// imo - eksperymenty z heurystykami dla problemu kilku cykli (zastępuje programy zad1-zad5)
+//
+// użycie: imo <polecenie> -instance <plik.tsp> [flagi]
+//
+//	construct  heurystyka konstrukcyjna (-algorithm nn, gc, reg, ...)
+//	improve    lokalne przeszukiwanie rozwiązania z heurystyki (-algorithm se, c, lk, ...)
+//	msls       multiple start local search (-parallel - starty rozłożone na gorutyny jednego uruchomienia)
+//	ils        iterated local search
+//	lns        large neighbourhood search (-with-ls - z lokalnym przeszukiwaniem po naprawie)
+//	hae        hybrydowy algorytm ewolucyjny (-with-ls - z lokalnym przeszukiwaniem potomków, -islands - model wyspowy)
+//	bench      porównanie metaheurystyk (-algorithms) na jednej instancji
+//	list       zarejestrowane algorytmy
+//
+// imo <polecenie> -h wypisuje flagi polecenia
+package main
+
+import (
+	"IMO/experiment"
+	"IMO/solver"
+	"IMO/utils"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// polecenie CLI
+type command struct {
+	name        string
+	description string
+	repetitions int                  // domyślna liczba powtórzeń
+	metaheur    solver.Metaheuristic // metaheurystyka polecenia; "" - construct, improve, bench
+}
+
+var commands = []command{
+	{"construct", "construction heuristic", 100, ""},
+	{"improve", "local search of a constructed solution", 100, ""},
+	{"msls", "multiple start local search", 10, solver.MetaheuristicMSLS},
+	{"ils", "iterated local search", 10, solver.MetaheuristicILS},
+	{"lns", "large neighbourhood search", 10, solver.MetaheuristicLNS},
+	{"hae", "hybrid evolutionary algorithm", 10, solver.MetaheuristicHAE},
+	{"bench", "compare metaheuristics on one instance", 10, ""},
+}
+
+// flagi wspólne dla poleceń
+type options struct {
+	spec           experiment.ProblemSpec
+	config_path    string
+	output         string
+	algorithm      string
+	algorithms     string
+	heuristic      string
+	local_search   string
+	repetitions    int
+	workers        int
+	time_limit     time.Duration
+	iterations     int
+	max_iterations int
+	seed           int64
+	trace_interval time.Duration
+	with_ls        bool
+	parallel       int
+	crossover      string
+	duplicate      int
+	replacement    string
+	islands        int
+	topology       string
+	migration      int
+	migrants       int
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: imo <command> -instance <file.tsp> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(os.Stderr, "  %-10s %s\n", "list", "list registered algorithms")
+	fmt.Fprintln(os.Stderr, "run 'imo <command> -h' for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "list" {
+		solver.PrintAlgorithms(os.Stdout)
+		return
+	}
+	if name == "-h" || name == "-help" || name == "help" {
+		usage()
+		return
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := run(cmd, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd *command, args []string) error {
+	opts, set, err := parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	cfg, err := buildConfig(cmd, opts, set)
+	if err != nil {
+		return err
+	}
+	problem, err := experiment.LoadProblem(opts.spec)
+	if err != nil {
+		return err
+	}
+	instance_name := strings.TrimSuffix(filepath.Base(opts.spec.Path), filepath.Ext(opts.spec.Path))
+
+	// Ctrl-C kończy bieżące powtórzenia z najlepszym dotąd rozwiązaniem i pomija pozostałe
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if cmd.name == "bench" {
+		bench := &experiment.Bench{Instance: opts.spec.Path}
+		for _, name := range strings.Split(opts.algorithms, ",") {
+			alg_cfg := *cfg
+			alg_cfg.Metaheuristic = solver.Metaheuristic(strings.TrimSpace(name))
+			if err := alg_cfg.Validate(); err != nil {
+				return err
+			}
+			exp := newExperiment(cmd, opts, problem, &alg_cfg)
+			bench.Add(exp.Run(ctx))
+		}
+		bench.Print(os.Stdout)
+		return bench.WriteJSON(outputPath(opts, "bench", instance_name))
+	}
+
+	exp := newExperiment(cmd, opts, problem, cfg)
+	result := exp.Run(ctx)
+	s := result.Summary()
+	fmt.Printf("%s: %d runs (%d failed), best %d, mean %.1f, worst %d, mean time %.3fs\n", s.Algorithm, s.Runs, s.Failed, s.Best, s.Mean, s.Worst, s.MeanTime)
+	if x := result.Crossovers; x != nil {
+		fmt.Printf("%s: %d crossovers (%d relabelled), parents share %.1f%% nodes and %.1f%% edges\n", s.Algorithm, x.Crossovers, x.Relabelled, 100*x.MeanNodeRatio, 100*x.MeanEdgeRatio)
+	}
+	return result.WriteJSON(outputPath(opts, exp.Name, instance_name))
+}
+
+func parseFlags(cmd *command, args []string) (*options, map[string]bool, error) {
+	opts := &options{}
+	fs := flag.NewFlagSet("imo "+cmd.name, flag.ExitOnError)
+	fs.StringVar(&opts.spec.Path, "instance", "", "path to the TSPLIB instance (required)")
+	fs.StringVar(&opts.spec.Metric, "metric", "auto", "distance metric ("+strings.Join(utils.MetricNames(), ", ")+")")
+	fs.IntVar(&opts.spec.NumCycles, "cycles", solver.DefaultNumCycles, "number of cycles")
+	fs.StringVar(&opts.spec.CycleSizes, "sizes", "", "cycle sizes or proportions, e.g. 60,40 (overrides -cycles)")
+	fs.IntVar(&opts.spec.Tolerance, "tolerance", 0, "allowed deviation of cycle sizes from target")
+	fs.IntVar(&opts.spec.MinSize, "min", 0, "minimal number of nodes in a cycle, at least 3 (0 - from tolerance)")
+	fs.IntVar(&opts.spec.MaxSize, "max", 0, "maximal number of nodes in a cycle (0 - from tolerance)")
+	fs.StringVar(&opts.config_path, "config", "", "solver configuration file (JSON or YAML)")
+	fs.StringVar(&opts.output, "o", "", "output JSON file, - for stdout (default Res_<algorithm>_<instance>.json)")
+	fs.IntVar(&opts.repetitions, "reps", cmd.repetitions, "number of repetitions")
+	fs.IntVar(&opts.workers, "workers", runtime.NumCPU(), "number of repetitions run concurrently")
+	fs.Int64Var(&opts.seed, "seed", 0, "random seed of the first repetition (0 - from config or random)")
+	switch cmd.name {
+	case "construct":
+		fs.StringVar(&opts.algorithm, "algorithm", "", "construction heuristic (default from config)")
+	case "improve":
+		fs.StringVar(&opts.algorithm, "algorithm", "", "local search algorithm (default from config)")
+		fs.StringVar(&opts.heuristic, "heuristic", "", "construction heuristic of the start solution (default from config)")
+	default:
+		fs.StringVar(&opts.heuristic, "heuristic", "", "construction heuristic of start solutions (default from config)")
+		fs.StringVar(&opts.local_search, "ls", "", "inner local search algorithm (default from config)")
+		fs.DurationVar(&opts.time_limit, "time", 0, "time limit of a run, e.g. 30s (default from config)")
+		fs.IntVar(&opts.iterations, "iterations", 0, "number of MSLS iterations (default from config)")
+		fs.IntVar(&opts.max_iterations, "max-iterations", 0, "iteration limit of ILS, LNS and HAE besides the time limit (default from config)")
+		fs.DurationVar(&opts.trace_interval, "trace-interval", 100*time.Millisecond, "minimal time between iterations recorded in the convergence trace (0 - every iteration)")
+	}
+	switch cmd.name {
+	case "msls":
+		fs.IntVar(&opts.parallel, "parallel", 0, "run the starts of each repetition on this many goroutines sharing the best solution, bounded also by -time (0 - sequential)")
+	case "lns":
+		fs.BoolVar(&opts.with_ls, "with-ls", false, "local search after each repair")
+	case "hae":
+		fs.BoolVar(&opts.with_ls, "with-ls", false, "local search of each offspring")
+		fs.StringVar(&opts.crossover, "crossover", "", "crossover operator (default from config; see imo list)")
+		fs.IntVar(&opts.duplicate, "duplicate-distance", 0, "reject individuals differing from a population member by at most this many edges (default from config; 0 - identical only)")
+		fs.StringVar(&opts.replacement, "replacement", "", "individual replaced by an offspring: worst, closest or biased-fitness (default from config)")
+		fs.IntVar(&opts.islands, "islands", 0, "number of concurrently evolving populations exchanging migrants (0 - single population; per-island algorithms in -config)")
+		fs.StringVar(&opts.topology, "topology", "", "migration topology of islands: ring or full (default from config)")
+		fs.IntVar(&opts.migration, "migration-interval", 0, "iterations of an island between migrations (default from config)")
+		fs.IntVar(&opts.migrants, "migrants", 0, "number of best individuals sent in a migration (default from config)")
+	case "bench":
+		fs.StringVar(&opts.algorithms, "algorithms", strings.Join([]string{
+			string(solver.MetaheuristicMSLS), string(solver.MetaheuristicILS), string(solver.MetaheuristicLNS),
+			string(solver.MetaheuristicLNSWithLS), string(solver.MetaheuristicHAE), string(solver.MetaheuristicHAEWithLS),
+		}, ","), "comma separated metaheuristics to compare")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+	if opts.spec.Path == "" {
+		return nil, nil, fmt.Errorf("imo %s: -instance is required", cmd.name)
+	}
+	if opts.repetitions < 1 {
+		return nil, nil, fmt.Errorf("imo %s: -reps must be positive, got %d", cmd.name, opts.repetitions)
+	}
+	if opts.parallel < 0 {
+		return nil, nil, fmt.Errorf("imo %s: -parallel cannot be negative, got %d", cmd.name, opts.parallel)
+	}
+	if opts.islands < 0 {
+		return nil, nil, fmt.Errorf("imo %s: -islands cannot be negative, got %d", cmd.name, opts.islands)
+	}
+	if opts.workers < 1 {
+		return nil, nil, fmt.Errorf("imo %s: -workers must be positive, got %d", cmd.name, opts.workers)
+	}
+	set := make(map[string]bool) // flagi podane jawnie - nadpisują plik konfiguracyjny
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return opts, set, nil
+}
+
+// konfiguracja: domyślna, z pliku -config, nadpisana jawnie podanymi flagami
+func buildConfig(cmd *command, opts *options, set map[string]bool) (*solver.Config, error) {
+	cfg := solver.DefaultConfig()
+	if opts.config_path != "" {
+		loaded, err := solver.LoadConfig(opts.config_path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+	switch cmd.name {
+	case "construct":
+		if set["algorithm"] {
+			cfg.Heuristic = solver.Heuristic(opts.algorithm)
+		}
+	case "improve":
+		if set["algorithm"] {
+			cfg.LocalSearch = solver.LocalSearch(opts.algorithm)
+		}
+	}
+	if set["heuristic"] {
+		cfg.Heuristic = solver.Heuristic(opts.heuristic)
+	}
+	if set["ls"] {
+		cfg.LocalSearch = solver.LocalSearch(opts.local_search)
+	}
+	if set["time"] {
+		cfg.TimeLimit = solver.Duration{Duration: opts.time_limit}
+	}
+	if set["iterations"] {
+		cfg.Iterations = opts.iterations
+	}
+	if set["max-iterations"] {
+		cfg.MaxIterations = opts.max_iterations
+	}
+	if cmd.metaheur != "" {
+		cfg.Metaheuristic = cmd.metaheur
+		if opts.parallel > 0 {
+			cfg.Metaheuristic = solver.MetaheuristicParallelMSLS
+			cfg.Workers = opts.parallel
+		}
+		if opts.islands > 0 {
+			cfg.Metaheuristic = solver.MetaheuristicIslandHAE
+			islands := make([]solver.Island, opts.islands) // wyspy z pliku konfiguracyjnego zachowane, brakujące z heuristic i ls
+			copy(islands, cfg.Islands)
+			cfg.Islands = islands
+		}
+		if opts.with_ls {
+			switch cfg.Metaheuristic {
+			case solver.MetaheuristicLNS:
+				cfg.Metaheuristic = solver.MetaheuristicLNSWithLS
+			case solver.MetaheuristicHAE:
+				cfg.Metaheuristic = solver.MetaheuristicHAEWithLS
+			case solver.MetaheuristicIslandHAE:
+				cfg.Metaheuristic = solver.MetaheuristicIslandHAELS
+			}
+		}
+	}
+	if set["crossover"] {
+		cfg.Crossover = solver.Crossover(opts.crossover)
+	}
+	if set["duplicate-distance"] {
+		cfg.DuplicateDistance = opts.duplicate
+	}
+	if set["replacement"] {
+		cfg.Replacement = solver.Replacement(opts.replacement)
+	}
+	if set["topology"] {
+		cfg.Topology = solver.Topology(opts.topology)
+	}
+	if set["migration-interval"] {
+		cfg.MigrationInterval = opts.migration
+	}
+	if set["migrants"] {
+		cfg.Migrants = opts.migrants
+	}
+	if set["seed"] {
+		cfg.Seed = opts.seed
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = solver.NewSeed() // zapisane w wynikach - pozwala powtórzyć eksperyment
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func newExperiment(cmd *command, opts *options, problem *experiment.Problem, cfg *solver.Config) *experiment.Experiment {
+	exp := &experiment.Experiment{
+		Problem:     problem,
+		Config:      cfg,
+		Repetitions: opts.repetitions,
+		Workers:     opts.workers,
+		Log:         os.Stderr,
+	}
+	switch cmd.name {
+	case "construct":
+		exp.Name = string(cfg.Heuristic)
+		exp.Trial = experiment.Construct
+	case "improve":
+		exp.Name = string(cfg.Heuristic) + "_" + string(cfg.LocalSearch)
+		exp.Trial = experiment.Improve
+	default:
+		exp.Name = string(cfg.Metaheuristic) + "_" + string(cfg.LocalSearch)
+		if cfg.Crossover != solver.CrossoverCommonEdges && usesParameter(cfg.Metaheuristic, "crossover") {
+			exp.Name += "_" + string(cfg.Crossover) // porównywane operatory krzyżowania w osobnych plikach wyników
+		}
+		if cfg.Replacement != solver.ReplacementWorst && usesParameter(cfg.Metaheuristic, "replacement") {
+			exp.Name += "_" + string(cfg.Replacement)
+		}
+		exp.Trial = experiment.Metaheuristic(opts.trace_interval)
+		exp.Iterative = true
+	}
+	return exp
+}
+
+// czy metaheurystyka korzysta z parametru konfiguracji
+func usesParameter(metaheuristic solver.Metaheuristic, parameter string) bool {
+	for _, info := range solver.Metaheuristics() {
+		if info.Name == string(metaheuristic) {
+			return slices.Contains(info.Parameters, parameter)
+		}
+	}
+	return false
+}
+
+func outputPath(opts *options, name string, instance_name string) string {
+	if opts.output != "" {
+		return opts.output
+	}
+	return fmt.Sprintf("Res_%s_%s.json", name, instance_name)
+}
@@ -0,0 +1,66 @@
+package experiment
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// podsumowanie wyników jednego algorytmu
+type Summary struct {
+	Algorithm      string  `json:"algorithm"`
+	Runs           int     `json:"runs"`            // udane powtórzenia
+	Failed         int     `json:"failed"`          // powtórzenia zakończone błędem
+	Best           int     `json:"best"`            // najmniejsza suma długości cykli
+	Mean           float64 `json:"mean"`            // średnia suma długości cykli
+	Worst          int     `json:"worst"`           // największa suma długości cykli
+	MeanTime       float64 `json:"mean time"`       // średni czas powtórzenia w sekundach
+	MeanIterations float64 `json:"mean iterations"` // średnia liczba iteracji metaheurystyki
+}
+
+func (r *Result) Summary() Summary {
+	summary := Summary{Algorithm: r.Algorithm, Runs: len(r.Scores), Failed: r.Failed}
+	if summary.Runs == 0 {
+		return summary
+	}
+	summary.Best = r.Scores[r.best()]
+	summary.Worst = r.Scores[r.worst()]
+	for k := range r.Scores {
+		summary.Mean += float64(r.Scores[k])
+		summary.MeanTime += r.Times[k]
+	}
+	for _, iter := range r.Iter {
+		summary.MeanIterations += float64(iter)
+	}
+	summary.Mean /= float64(summary.Runs)
+	summary.MeanTime /= float64(summary.Runs)
+	summary.MeanIterations /= float64(summary.Runs)
+	return summary
+}
+
+// porównanie algorytmów na jednej instancji
+type Bench struct {
+	Instance  string    `json:"instance"`
+	Summaries []Summary `json:"summaries"`
+	Results   []*Result `json:"results"`
+}
+
+func (b *Bench) Add(result *Result) {
+	b.Results = append(b.Results, result)
+	b.Summaries = append(b.Summaries, result.Summary())
+}
+
+// tabela podsumowań
+func (b *Bench) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "algorithm\truns\tfailed\tbest\tmean\tworst\tmean time [s]\tmean iterations\t\n")
+	for _, s := range b.Summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\t%d\t%.3f\t%.1f\t\n", s.Algorithm, s.Runs, s.Failed, s.Best, s.Mean, s.Worst, s.MeanTime, s.MeanIterations)
+	}
+	tw.Flush()
+}
+
+// zapis porównania w JSON; path "-" - standardowe wyjście
+func (b *Bench) WriteJSON(path string) error {
+	return writeJSON(path, b)
+}
@@ -0,0 +1,323 @@
+package experiment
+
+import (
+	"IMO/reader"
+	"IMO/solver"
+	"IMO/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// parametry instancji z linii poleceń
+type ProblemSpec struct {
+	Path       string // plik instancji TSPLIB
+	Metric     string // nazwa metryki (utils.MetricNames)
+	NumCycles  int    // liczba cykli przy równym podziale
+	CycleSizes string // rozmiary lub proporcje cykli, np. 60,40; nadpisuje NumCycles
+	Tolerance  int    // dopuszczalne odchylenie rozmiarów cykli
+	MinSize    int    // minimalny rozmiar cyklu; 0 - z tolerancji
+	MaxSize    int    // maksymalny rozmiar cyklu; 0 - z tolerancji
+}
+
+// wczytana instancja z macierzą odległości i ograniczeniami rozmiarów - wspólna dla wszystkich powtórzeń
+type Problem struct {
+	Path           string
+	Instance       *reader.Instance
+	DistanceMatrix *utils.DistanceMatrix
+	Limits         *solver.SizeLimits
+}
+
+func LoadProblem(spec ProblemSpec) (*Problem, error) {
+	instance, err := reader.ReadInstance(spec.Path)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := utils.MetricByName(spec.Metric)
+	if err != nil {
+		return nil, err
+	}
+	distance_matrix, err := utils.NewDistanceMatrix(instance, metric)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec.Path, err)
+	}
+	limits, err := solver.SizeLimitsFromSpec(len(instance.Nodes), spec.NumCycles, spec.CycleSizes, spec.Tolerance, spec.MinSize, spec.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Problem{Path: spec.Path, Instance: instance, DistanceMatrix: distance_matrix, Limits: limits}, nil
+}
+
+// czy order jest dopuszczalnym rozwiązaniem instancji
+func (p *Problem) Check(order [][]int) error {
+	if err := solver.ValidateOrder(order, p.Instance.Nodes); err != nil {
+		return err
+	}
+	return p.Limits.Check(order)
+}
+
+// wynik jednego powtórzenia
+type Run struct {
+	Order      [][]int                // rozwiązanie końcowe
+	StartOrder [][]int                // rozwiązanie startowe (improve); nil - brak
+	Iterations int                    // wykonane iteracje metaheurystyki
+	Trace      []solver.TracePoint    // przebieg metaheurystyki
+	Crossovers *solver.CrossoverStats // zgodność rodziców w krzyżowaniach HAE; nil - bez krzyżowań
+}
+
+// jedno powtórzenie eksperymentu; cfg - kopia konfiguracji z ziarnem powtórzenia
+type Trial func(ctx context.Context, problem *Problem, cfg *solver.Config) (*Run, error)
+
+// heurystyka konstrukcyjna cfg.Heuristic
+func Construct(ctx context.Context, problem *Problem, cfg *solver.Config) (*Run, error) {
+	order, err := solver.Solve(problem.Instance.Nodes, cfg, problem.DistanceMatrix, problem.Limits)
+	if err != nil {
+		return nil, err
+	}
+	return &Run{Order: order}, nil
+}
+
+// lokalne przeszukiwanie cfg.LocalSearch rozwiązania z heurystyki cfg.Heuristic
+func Improve(ctx context.Context, problem *Problem, cfg *solver.Config) (*Run, error) {
+	start_order, err := solver.Solve(problem.Instance.Nodes, cfg, problem.DistanceMatrix, problem.Limits)
+	if err != nil {
+		return nil, err
+	}
+	order, err := solver.Local_search(ctx, start_order, cfg, problem.DistanceMatrix, problem.Limits)
+	if err != nil {
+		return nil, err
+	}
+	return &Run{Order: order, StartOrder: start_order}, nil
+}
+
+// metaheurystyka cfg.Metaheuristic z zapisem przebiegu (iteracje co najmniej co trace_interval)
+func Metaheuristic(trace_interval time.Duration) Trial {
+	return func(ctx context.Context, problem *Problem, cfg *solver.Config) (*Run, error) {
+		trace := solver.NewTrace(trace_interval)
+		stats := &solver.CrossoverStats{}
+		cfg.Observer = solver.Observers{trace, stats}
+		order, iter, err := solver.Local_search_alternatives(ctx, problem.Instance.Nodes, cfg, problem.DistanceMatrix, problem.Limits)
+		if err != nil {
+			return nil, err
+		}
+		run := &Run{Order: order, Iterations: iter, Trace: trace.Points}
+		if stats.Crossovers > 0 {
+			run.Crossovers = stats
+		}
+		return run, nil
+	}
+}
+
+// powtarzany eksperyment: Repetitions uruchomień Trial z kolejnymi ziarnami Config.Repetition(i)
+type Experiment struct {
+	Name        string         // nazwa w wynikach i komunikatach
+	Problem     *Problem       // instancja
+	Config      *solver.Config // konfiguracja pierwszego powtórzenia
+	Repetitions int            // liczba powtórzeń
+	Trial       Trial          // jedno powtórzenie
+	Iterative   bool           // metaheurystyka - zapisywane liczby iteracji i przebiegi
+	Workers     int            // liczba powtórzeń wykonywanych równolegle; <= 1 - po kolei
+	Log         io.Writer      // postęp i błędy powtórzeń; nil - bez komunikatów
+}
+
+// wynik powtórzenia przed zebraniem w Result
+type outcome struct {
+	started bool
+	run     *Run
+	err     error
+	seed    int64
+	elapsed time.Duration
+}
+
+// uruchomienie wszystkich powtórzeń na Workers gorutynach; każde powtórzenie ma własną kopię konfiguracji
+// (ziarno Config.Repetition(i), własny generator) i własne bufory rozwiązań, a wyniki są zbierane w kolejności powtórzeń -
+// niezależnie od kolejności zakończenia. Powtórzenie zakończone błędem jest zgłaszane i pomijane,
+// zakończenie ctx przerywa trwające powtórzenia (z najlepszym dotąd rozwiązaniem) i pomija pozostałe
+func (e *Experiment) Run(ctx context.Context) *Result {
+	log := &syncWriter{w: e.Log}
+	if e.Log == nil {
+		log.w = io.Discard
+	}
+	outcomes := make([]outcome, e.Repetitions)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, min(e.Workers, e.Repetitions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				outcomes[i] = e.trial(ctx, i, log)
+			}
+		}()
+	}
+	started := 0
+	for started < e.Repetitions && ctx.Err() == nil {
+		select {
+		case jobs <- started:
+			started++
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if started < e.Repetitions {
+		fmt.Fprintf(log, "%s: interrupted after %d of %d trials\n", e.Name, started, e.Repetitions)
+	}
+
+	result := &Result{
+		Algorithm: e.Name,
+		Instance:  e.Problem.Path,
+		Config:    e.Config,
+		Result:    make([][]int, e.Problem.Limits.NumCycles()),
+		Nodes:     e.Problem.Instance.Nodes,
+		Seed:      e.Config.Seed,
+		iterative: e.Iterative,
+	}
+	for c := range result.Result {
+		result.Result[c] = make([]int, 0, e.Repetitions)
+	}
+	for _, o := range outcomes {
+		switch {
+		case !o.started:
+		case o.err != nil:
+			result.Failed++
+		default:
+			result.add(o.run, e.Problem.DistanceMatrix, o.seed, o.elapsed)
+		}
+	}
+	return result
+}
+
+// i-te powtórzenie ze sprawdzeniem rozwiązania
+func (e *Experiment) trial(ctx context.Context, i int, log io.Writer) outcome {
+	rep_cfg := e.Config.Repetition(i)
+	start_time := time.Now()
+	run, err := e.Trial(ctx, e.Problem, rep_cfg)
+	elapsed := time.Since(start_time)
+	if err == nil {
+		err = e.Problem.Check(run.Order)
+	}
+	if err != nil {
+		fmt.Fprintf(log, "%s: trial %d (seed %d) failed: %v\n", e.Name, i+1, rep_cfg.Seed, err)
+	} else {
+		fmt.Fprintf(log, "%s: trial %d: %d (%v)\n", e.Name, i+1, utils.CalculateCyclesLen(run.Order, e.Problem.DistanceMatrix), elapsed)
+	}
+	return outcome{started: true, run: run, err: err, seed: rep_cfg.Seed, elapsed: elapsed}
+}
+
+// Writer z blokadą - komunikaty z wielu gorutyn się nie przeplatają
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// wyniki powtórzeń - format plików wynikowych dawnych programów zad1-zad5 (notatniki visualization.ipynb)
+type Result struct {
+	Algorithm         string                 `json:"algorithm"`
+	Instance          string                 `json:"instance"`
+	Config            *solver.Config         `json:"config"`
+	Result            [][]int                `json:"result"` // Result[c][k] - długość cyklu c w k-tym udanym powtórzeniu
+	Start_Worst_Order [][]int                `json:"start worst order,omitempty"`
+	Start_Best_Order  [][]int                `json:"start best order,omitempty"`
+	Worst_Order       [][]int                `json:"worst order"`
+	Best_Order        [][]int                `json:"best order"`
+	Nodes             []reader.Node          `json:"unordered nodes"`
+	Times             []float64              `json:"times"`
+	Longest_Time      float64                `json:"longest time"`
+	Shortest_Time     float64                `json:"shortest time"`
+	Iter              []int                  `json:"iterations,omitempty"`
+	Seed              int64                  `json:"seed"`
+	Seeds             []int64                `json:"seeds"`                // ziarna udanych powtórzeń - w kolejności wyników
+	Traces            [][]solver.TracePoint  `json:"traces,omitempty"`     // przebiegi udanych powtórzeń metaheurystyk
+	Crossovers        *solver.CrossoverStats `json:"crossovers,omitempty"` // zgodność rodziców we wszystkich krzyżowaniach HAE
+	Failed            int                    `json:"failed"`               // liczba powtórzeń zakończonych błędem
+	Scores            []int                  `json:"scores"`               // suma długości cykli w kolejnych udanych powtórzeniach
+	iterative         bool
+}
+
+// dopisanie udanego powtórzenia
+func (r *Result) add(run *Run, distance_matrix *utils.DistanceMatrix, seed int64, elapsed time.Duration) {
+	score := 0
+	for c := range run.Order {
+		cycle_len := utils.CalculateCycleLen(run.Order[c], distance_matrix)
+		r.Result[c] = append(r.Result[c], cycle_len)
+		score += cycle_len
+	}
+	first := len(r.Scores) == 0
+	if first || score > r.Scores[r.worst()] {
+		r.Worst_Order = run.Order
+		r.Start_Worst_Order = run.StartOrder
+	}
+	if first || score < r.Scores[r.best()] {
+		r.Best_Order = run.Order
+		r.Start_Best_Order = run.StartOrder
+	}
+	if first || elapsed.Seconds() > r.Longest_Time {
+		r.Longest_Time = elapsed.Seconds()
+	}
+	if first || elapsed.Seconds() < r.Shortest_Time {
+		r.Shortest_Time = elapsed.Seconds()
+	}
+	r.Scores = append(r.Scores, score)
+	r.Times = append(r.Times, elapsed.Seconds())
+	r.Seeds = append(r.Seeds, seed)
+	if r.iterative {
+		r.Iter = append(r.Iter, run.Iterations)
+		r.Traces = append(r.Traces, run.Trace)
+	}
+	if run.Crossovers != nil {
+		if r.Crossovers == nil {
+			r.Crossovers = &solver.CrossoverStats{}
+		}
+		r.Crossovers.Merge(run.Crossovers)
+	}
+}
+
+// indeks najlepszego udanego powtórzenia (pierwszy przy remisie); -1 - brak
+func (r *Result) best() int {
+	best := -1
+	for k, score := range r.Scores {
+		if best == -1 || score < r.Scores[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// indeks najgorszego udanego powtórzenia (pierwszy przy remisie); -1 - brak
+func (r *Result) worst() int {
+	worst := -1
+	for k, score := range r.Scores {
+		if worst == -1 || score > r.Scores[worst] {
+			worst = k
+		}
+	}
+	return worst
+}
+
+// zapis wyników w JSON; path "-" - standardowe wyjście
+func (r *Result) WriteJSON(path string) error {
+	return writeJSON(path, r)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,99 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+)
+
+// funkcje odległości zgodne ze specyfikacją TSPLIB95 (rozdział 2)
+
+// zaokrąglenie do najbliższej liczby całkowitej - nint() z TSPLIB
+func nint(x float64) int {
+	return int(x + 0.5)
+}
+
+func EucDist2D(a, b Node) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return nint(math.Sqrt(dx*dx + dy*dy))
+}
+
+func EucDist3D(a, b Node) int {
+	dx, dy, dz := a.X-b.X, a.Y-b.Y, a.Z-b.Z
+	return nint(math.Sqrt(dx*dx + dy*dy + dz*dz))
+}
+
+func CeilDist2D(a, b Node) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return int(math.Ceil(math.Sqrt(dx*dx + dy*dy)))
+}
+
+func ManDist2D(a, b Node) int {
+	return nint(math.Abs(a.X-b.X) + math.Abs(a.Y-b.Y))
+}
+
+func ManDist3D(a, b Node) int {
+	return nint(math.Abs(a.X-b.X) + math.Abs(a.Y-b.Y) + math.Abs(a.Z-b.Z))
+}
+
+func MaxDist2D(a, b Node) int {
+	return max(nint(math.Abs(a.X-b.X)), nint(math.Abs(a.Y-b.Y)))
+}
+
+func MaxDist3D(a, b Node) int {
+	return max(MaxDist2D(a, b), nint(math.Abs(a.Z-b.Z)))
+}
+
+// odległość pseudo-euklidesowa (instancje att48, att532)
+func AttDist(a, b Node) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	r := math.Sqrt((dx*dx + dy*dy) / 10.0)
+	t := nint(r)
+	if float64(t) < r {
+		return t + 1
+	}
+	return t
+}
+
+// współrzędne geograficzne w formacie DDD.MM (X - szerokość, Y - długość)
+func geoRadians(x float64) float64 {
+	const pi = 3.141592 // wartość z TSPLIB
+	deg := math.Trunc(x)
+	min := x - deg
+	return pi * (deg + 5.0*min/3.0) / 180.0
+}
+
+// odległość geograficzna na idealnej kuli (km)
+func GeoDist(a, b Node) int {
+	const rrr = 6378.388 // promień Ziemi z TSPLIB
+	lat_a, lon_a := geoRadians(a.X), geoRadians(a.Y)
+	lat_b, lon_b := geoRadians(b.X), geoRadians(b.Y)
+	q1 := math.Cos(lon_a - lon_b)
+	q2 := math.Cos(lat_a - lat_b)
+	q3 := math.Cos(lat_a + lat_b)
+	return int(rrr*math.Acos(0.5*((1.0+q1)*q2-(1.0-q1)*q3)) + 1.0)
+}
+
+// funkcja odległości dla EDGE_WEIGHT_TYPE; EXPLICIT nie ma funkcji - wagi z EDGE_WEIGHT_SECTION
+func DistanceFunc(edge_weight_type string) (func(a, b Node) int, error) {
+	switch edge_weight_type {
+	case "EUC_2D":
+		return EucDist2D, nil
+	case "EUC_3D":
+		return EucDist3D, nil
+	case "CEIL_2D":
+		return CeilDist2D, nil
+	case "MAN_2D":
+		return ManDist2D, nil
+	case "MAN_3D":
+		return ManDist3D, nil
+	case "MAX_2D":
+		return MaxDist2D, nil
+	case "MAX_3D":
+		return MaxDist3D, nil
+	case "ATT":
+		return AttDist, nil
+	case "GEO":
+		return GeoDist, nil
+	}
+	return nil, fmt.Errorf("unsupported EDGE_WEIGHT_TYPE %q", edge_weight_type)
+}
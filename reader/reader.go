@@ -1,85 +1,321 @@
-package reader
-
-import (
-	"bufio"
-	"fmt"
-	"os"
-	"strconv"
-	"strings"
-)
-
-type Node struct {
-	X int
-	Y int
-}
-
-// wczytywanie instancji z pliku
-// instancje TSPLIB kroA200, kroB200 z https://github.com/mastqe/tsplib
-// oryginalne źródło: http://comopt.ifi.uni-heidelberg.de/software/TSPLIB95/tsp/
-func ReadInstance(srcPath string) (nodes []Node, headers map[string]string, err error) {
-	var num_nodes int                 // liczba wierzchołków
-	headers = make(map[string]string) // nagłówki z pliku
-
-	file, err := os.Open(srcPath)
-	if err != nil {
-		return
-	}
-	defer file.Close()
-
-	// stworzenie skanera
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	// wczytywanie nagłówków
-	for i := 0; scanner.Scan(); i++ {
-		line := scanner.Text()
-		// rozpoczęcie sekcji z wierzchołkami
-		if line == "NODE_COORD_SECTION" {
-			break
-		}
-		// rozdzielenie nagłówka od wartości na dwukropku i usunięcie białych znaków
-		fields := strings.Split(line, ":")
-		if len(fields) < 2 {
-			err = fmt.Errorf("invalid instance file format (reading %d-th header)", i+1)
-			return
-		}
-		for field, _ := range fields {
-			fields[field] = strings.TrimSpace(fields[field])
-		}
-		var h, val *string = &fields[0], &fields[1] // ćwiczonko wskaźników
-		headers[*h] = *val
-		// alternatywnie na wartościach
-		// h, val := fields[0], fields[1]
-		// headers[h] = val
-	}
-	// odczytanie liczby wierzchołków
-	dim, ok := headers["DIMENSION"]
-	num_nodes, err = strconv.Atoi(dim)
-	// błąd konwersji lub brak nagłówka DIMENSION
-	if err != nil || !ok {
-		return
-	}
-	// wczytywanie wierzchołków
-	for i := 0; scanner.Scan() && i < num_nodes; i++ {
-		var (
-			x_str, y_str string
-			x, y         int
-		)
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 3 {
-			err = fmt.Errorf("invalid instance file format (reading %d-th node)", i+1)
-			return
-		}
-		_, x_str, y_str = fields[0], fields[1], fields[2]
-		x, err = strconv.Atoi(x_str)
-		if err != nil {
-			return
-		}
-		y, err = strconv.Atoi(y_str)
-		if err != nil {
-			return
-		}
-		nodes = append(nodes, Node{x, y})
-	}
-
-	return
-}
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// współrzędne jako float64 - zaokrąglanie odległości należy do metryki (nint dla EUC_2D, ceil dla CEIL_2D)
+type Node struct {
+	X float64
+	Y float64
+	Z float64 `json:"Z,omitempty"` // tylko dla instancji *_3D
+}
+
+// instancja TSPLIB95 - nagłówki, wierzchołki i (dla EXPLICIT) macierz wag
+type Instance struct {
+	Name             string
+	Dimension        int
+	EdgeWeightType   string            // EUC_2D, GEO, ATT, EXPLICIT, ...
+	EdgeWeightFormat string            // FUNCTION, FULL_MATRIX, UPPER_ROW, ...
+	Headers          map[string]string // wszystkie nagłówki z pliku
+	Nodes            []Node            // współrzędne wierzchołków (NODE_COORD_SECTION lub DISPLAY_DATA_SECTION)
+	DisplayNodes     []Node            // współrzędne do wizualizacji (DISPLAY_DATA_SECTION)
+	Weights          [][]int           // jawna macierz wag (EDGE_WEIGHT_SECTION)
+	HasCoordinates   bool              // czy wierzchołki mają współrzędne
+}
+
+// sekcje pliku TSPLIB95 - liczba wartości jest znana z DIMENSION lub kończy je -1
+var sections = map[string]bool{
+	"NODE_COORD_SECTION":   true,
+	"DISPLAY_DATA_SECTION": true,
+	"EDGE_WEIGHT_SECTION":  true,
+	"DEPOT_SECTION":        true,
+	"DEMAND_SECTION":       true,
+	"EDGE_DATA_SECTION":    true,
+	"FIXED_EDGES_SECTION":  true,
+	"TOUR_SECTION":         true,
+}
+
+// wczytywanie instancji z pliku
+// instancje TSPLIB kroA200, kroB200 z https://github.com/mastqe/tsplib
+// oryginalne źródło: http://comopt.ifi.uni-heidelberg.de/software/TSPLIB95/tsp/
+// obsługiwane wszystkie EDGE_WEIGHT_TYPE oprócz XRAY1, XRAY2 i SPECIAL
+func ReadInstance(srcPath string) (instance *Instance, err error) {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	instance = &Instance{
+		Headers:          make(map[string]string),
+		EdgeWeightFormat: "FUNCTION",
+	}
+	// stworzenie skanera po tokenach - sekcje mogą mieć dowolny podział na linie
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	tokens := &tokenReader{scanner: scanner}
+
+	for i := 0; ; i++ {
+		line, ok := tokens.nextLine()
+		if !ok || line == "EOF" {
+			break
+		}
+		// nazwa sekcji może mieć dwukropek na końcu
+		keyword := strings.TrimSpace(strings.TrimSuffix(line, ":"))
+		if sections[keyword] {
+			if instance.Dimension == 0 {
+				err = fmt.Errorf("invalid instance file format (%s before DIMENSION)", keyword)
+				return
+			}
+			err = instance.readSection(keyword, tokens)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		// rozdzielenie nagłówka od wartości na pierwszym dwukropku (COMMENT może zawierać dwukropki)
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) < 2 {
+			err = fmt.Errorf("invalid instance file format (reading %d-th header)", i+1)
+			return
+		}
+		h, val := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		instance.Headers[h] = val
+		switch h {
+		case "NAME":
+			instance.Name = val
+		case "DIMENSION":
+			instance.Dimension, err = strconv.Atoi(val)
+			if err != nil {
+				return
+			}
+			if instance.Dimension < 1 {
+				err = fmt.Errorf("invalid instance file format (DIMENSION %d must be positive)", instance.Dimension)
+				return
+			}
+		case "EDGE_WEIGHT_TYPE":
+			instance.EdgeWeightType = val
+		case "EDGE_WEIGHT_FORMAT":
+			instance.EdgeWeightFormat = val
+		}
+	}
+	if err = tokens.scanner.Err(); err != nil {
+		return
+	}
+	// brak nagłówka DIMENSION
+	if instance.Dimension == 0 {
+		err = fmt.Errorf("invalid instance file format (missing DIMENSION)")
+		return
+	}
+	if instance.EdgeWeightType == "EXPLICIT" {
+		if instance.Weights == nil {
+			err = fmt.Errorf("invalid instance file format (EXPLICIT without EDGE_WEIGHT_SECTION)")
+			return
+		}
+	} else {
+		// nieobsługiwany typ odległości
+		if _, err = DistanceFunc(instance.EdgeWeightType); err != nil {
+			return
+		}
+		if !instance.HasCoordinates {
+			err = fmt.Errorf("invalid instance file format (%s without NODE_COORD_SECTION)", instance.EdgeWeightType)
+			return
+		}
+	}
+	// instancje jawne bez współrzędnych - wierzchołki do wizualizacji lub puste (solver korzysta tylko z ich liczby)
+	if instance.Nodes == nil {
+		if instance.DisplayNodes != nil {
+			instance.Nodes = instance.DisplayNodes
+		} else {
+			instance.Nodes = make([]Node, instance.Dimension)
+		}
+	}
+
+	return
+}
+
+// odległość między wierzchołkami i oraz j zgodnie z EDGE_WEIGHT_TYPE instancji
+func (instance *Instance) Distance(i, j int) int {
+	if instance.EdgeWeightType == "EXPLICIT" {
+		return instance.Weights[i][j]
+	}
+	dist, _ := DistanceFunc(instance.EdgeWeightType) // typ sprawdzony przy wczytywaniu
+	return dist(instance.Nodes[i], instance.Nodes[j])
+}
+
+func (instance *Instance) readSection(section string, tokens *tokenReader) error {
+	switch section {
+	case "NODE_COORD_SECTION":
+		nodes, err := readNodes(tokens, instance.Dimension, instance.coordDimension())
+		if err != nil {
+			return err
+		}
+		instance.Nodes = nodes
+		instance.HasCoordinates = true
+	case "DISPLAY_DATA_SECTION":
+		nodes, err := readNodes(tokens, instance.Dimension, 2)
+		if err != nil {
+			return err
+		}
+		instance.DisplayNodes = nodes
+	case "EDGE_WEIGHT_SECTION":
+		weights, err := readWeights(tokens, instance.Dimension, instance.EdgeWeightFormat)
+		if err != nil {
+			return err
+		}
+		instance.Weights = weights
+	case "DEMAND_SECTION":
+		// pomijanie - po 2 wartości na wierzchołek
+		for i := 0; i < 2*instance.Dimension; i++ {
+			if _, ok := tokens.next(); !ok {
+				return fmt.Errorf("invalid instance file format (unexpected end of %s)", section)
+			}
+		}
+	default:
+		// pomijanie sekcji zakończonych -1 (DEPOT, EDGE_DATA, FIXED_EDGES, TOUR)
+		for {
+			token, ok := tokens.next()
+			if !ok {
+				return fmt.Errorf("invalid instance file format (unexpected end of %s)", section)
+			}
+			if token == "-1" {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// liczba współrzędnych wierzchołka - z NODE_COORD_TYPE lub z EDGE_WEIGHT_TYPE
+func (instance *Instance) coordDimension() int {
+	switch instance.Headers["NODE_COORD_TYPE"] {
+	case "THREED_COORDS":
+		return 3
+	case "TWOD_COORDS":
+		return 2
+	}
+	if strings.HasSuffix(instance.EdgeWeightType, "_3D") {
+		return 3
+	}
+	return 2
+}
+
+// wczytywanie wierzchołków: <nr> <x> <y> [z]; numeracja od 1
+func readNodes(tokens *tokenReader, num_nodes int, coords int) ([]Node, error) {
+	nodes := make([]Node, num_nodes)
+	seen := make([]bool, num_nodes) // wczytane numery wierzchołków
+	for i := 0; i < num_nodes; i++ {
+		var values [3]float64
+		token, ok := tokens.next()
+		if !ok {
+			return nil, fmt.Errorf("invalid instance file format (reading %d-th node)", i+1)
+		}
+		id, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, err
+		}
+		for k := 0; k < coords; k++ {
+			token, ok = tokens.next()
+			if !ok {
+				return nil, fmt.Errorf("invalid instance file format (reading %d-th node)", i+1)
+			}
+			// współrzędne dziesiętne lub w notacji naukowej (np. d1291, pr2392)
+			values[k], err = strconv.ParseFloat(token, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if id < 1 || id > num_nodes {
+			return nil, fmt.Errorf("invalid instance file format (node number %d out of range)", id)
+		}
+		if seen[id-1] {
+			return nil, fmt.Errorf("invalid instance file format (node number %d repeated)", id)
+		}
+		seen[id-1] = true
+		nodes[id-1] = Node{X: values[0], Y: values[1], Z: values[2]}
+	}
+	return nodes, nil
+}
+
+// wczytywanie macierzy wag w formacie EDGE_WEIGHT_FORMAT
+// formaty kolumnowe dla macierzy symetrycznej to transpozycja odpowiednich formatów wierszowych
+func readWeights(tokens *tokenReader, num_nodes int, format string) ([][]int, error) {
+	weights := make([][]int, num_nodes)
+	for i := range weights {
+		weights[i] = make([]int, num_nodes)
+	}
+	// zakres kolumn j dla wiersza i
+	var row func(i int) (int, int)
+	switch format {
+	case "FULL_MATRIX":
+		row = func(i int) (int, int) { return 0, num_nodes }
+	case "UPPER_ROW", "LOWER_COL":
+		row = func(i int) (int, int) { return i + 1, num_nodes }
+	case "LOWER_ROW", "UPPER_COL":
+		row = func(i int) (int, int) { return 0, i }
+	case "UPPER_DIAG_ROW", "LOWER_DIAG_COL":
+		row = func(i int) (int, int) { return i, num_nodes }
+	case "LOWER_DIAG_ROW", "UPPER_DIAG_COL":
+		row = func(i int) (int, int) { return 0, i + 1 }
+	default:
+		return nil, fmt.Errorf("unsupported EDGE_WEIGHT_FORMAT %q", format)
+	}
+
+	for i := 0; i < num_nodes; i++ {
+		from, to := row(i)
+		for j := from; j < to; j++ {
+			token, ok := tokens.next()
+			if !ok {
+				return nil, fmt.Errorf("invalid instance file format (reading weight %d-%d)", i+1, j+1)
+			}
+			value, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return nil, err
+			}
+			weights[i][j] = int(math.Round(value))
+			if format != "FULL_MATRIX" {
+				weights[j][i] = weights[i][j] // macierz symetryczna
+			}
+		}
+	}
+	return weights, nil
+}
+
+// czytnik tokenów rozdzielonych białymi znakami, z dostępem do całych linii dla nagłówków
+type tokenReader struct {
+	scanner *bufio.Scanner
+	pending []string // tokeny pozostałe z ostatnio wczytanej linii
+}
+
+func (t *tokenReader) nextLine() (string, bool) {
+	if len(t.pending) > 0 {
+		line := strings.Join(t.pending, " ")
+		t.pending = nil
+		return line, true
+	}
+	for t.scanner.Scan() {
+		line := strings.TrimSpace(t.scanner.Text())
+		if line != "" {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+func (t *tokenReader) next() (string, bool) {
+	for len(t.pending) == 0 {
+		if !t.scanner.Scan() {
+			return "", false
+		}
+		t.pending = strings.Fields(t.scanner.Text())
+	}
+	token := t.pending[0]
+	t.pending = t.pending[1:]
+	return token, true
+}
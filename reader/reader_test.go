@@ -0,0 +1,172 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// zapis instancji do pliku tymczasowego
+func writeInstance(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.tsp")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readTestInstance(t *testing.T, content string) *Instance {
+	t.Helper()
+	instance, err := ReadInstance(writeInstance(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instance
+}
+
+func TestReadWeightFormats(t *testing.T) {
+	expected := [][]int{
+		{0, 1, 2, 3},
+		{1, 0, 4, 5},
+		{2, 4, 0, 6},
+		{3, 5, 6, 0},
+	}
+	for _, tc := range []struct {
+		format  string
+		section string
+	}{
+		{"FULL_MATRIX", "0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6 0"},
+		{"UPPER_ROW", "1 2 3\n4 5\n6"},
+		{"LOWER_ROW", "1\n2 4\n3 5 6"},
+		{"UPPER_DIAG_ROW", "0 1 2 3\n0 4 5\n0 6\n0"},
+		{"LOWER_DIAG_ROW", "0\n1 0\n2 4 0\n3 5 6 0"},
+		{"UPPER_COL", "1\n2 4\n3 5 6"},
+		{"LOWER_COL", "1 2 3\n4 5\n6"},
+		{"UPPER_DIAG_COL", "0\n1 0\n2 4 0\n3 5 6 0"},
+		{"LOWER_DIAG_COL", "0 1 2 3\n0 4 5\n0 6\n0"},
+		{"FULL_MATRIX", "0 1 2 3 1 0 4 5 2 4 0 6 3 5 6 0"}, // dowolny podział na linie
+		{"UPPER_ROW", "1\n2 3 4\n5\n6.0"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			instance := readTestInstance(t, "NAME: test\nDIMENSION: 4\nEDGE_WEIGHT_TYPE: EXPLICIT\nEDGE_WEIGHT_FORMAT: "+tc.format+
+				"\nEDGE_WEIGHT_SECTION\n"+tc.section+"\nEOF\n")
+			if !slices.EqualFunc(instance.Weights, expected, slices.Equal) {
+				t.Errorf("got %v, expected %v", instance.Weights, expected)
+			}
+			if len(instance.Nodes) != 4 || instance.HasCoordinates {
+				t.Errorf("explicit instance: %d nodes, coordinates %v", len(instance.Nodes), instance.HasCoordinates)
+			}
+		})
+	}
+}
+
+// burma14 (GEO) - optymalna trasa z TSPLIB ma długość 3323
+const burma14 = `NAME: burma14
+TYPE: TSP
+COMMENT: 14-Staedte in Burma (Zaw Win)
+DIMENSION: 14
+EDGE_WEIGHT_TYPE: GEO
+EDGE_WEIGHT_FORMAT: FUNCTION
+DISPLAY_DATA_TYPE: COORD_DISPLAY
+NODE_COORD_SECTION
+   1  16.47       96.10
+   2  16.47       94.44
+   3  20.09       92.54
+   4  22.39       93.37
+   5  25.23       97.24
+   6  22.00       96.05
+   7  20.47       97.02
+   8  17.20       96.29
+   9  16.30       97.38
+  10  14.05       98.12
+  11  16.53       97.38
+  12  21.52       95.59
+  13  19.41       97.13
+  14  20.09       94.55
+EOF
+`
+
+func TestReadGeo(t *testing.T) {
+	instance := readTestInstance(t, burma14)
+	if instance.Name != "burma14" || instance.Dimension != 14 || instance.Headers["COMMENT"] != "14-Staedte in Burma (Zaw Win)" {
+		t.Fatalf("headers: %q, %d, %v", instance.Name, instance.Dimension, instance.Headers)
+	}
+	// pierwszy wiersz macierzy odległości burma14 (d(i, i) = 1 z wzoru GEO)
+	row := []int{1, 153, 510, 706, 966, 581, 455, 70, 160, 372, 157, 567, 342, 398}
+	for j, d := range row {
+		if got := instance.Distance(0, j); got != d {
+			t.Errorf("distance 1-%d: got %d, expected %d", j+1, got, d)
+		}
+	}
+	tour := []int{1, 2, 14, 3, 4, 5, 6, 12, 7, 13, 8, 11, 9, 10}
+	length := 0
+	for i := range tour {
+		length += instance.Distance(tour[i]-1, tour[(i+1)%len(tour)]-1)
+	}
+	if length != 3323 {
+		t.Errorf("optimal tour length: got %d, expected 3323", length)
+	}
+}
+
+func TestDistanceFunc(t *testing.T) {
+	for _, tc := range []struct {
+		edge_weight_type string
+		a, b             Node
+		expected         int
+	}{
+		{"EUC_2D", Node{X: 0, Y: 0}, Node{X: 3, Y: 4}, 5},
+		{"EUC_2D", Node{X: 0, Y: 0}, Node{X: 1.2, Y: 0}, 1},
+		{"CEIL_2D", Node{X: 0, Y: 0}, Node{X: 3, Y: 4}, 5},
+		{"CEIL_2D", Node{X: 0, Y: 0}, Node{X: 1, Y: 1}, 2},
+		{"CEIL_2D", Node{X: 0, Y: 0}, Node{X: 1.2, Y: 0}, 2},
+		{"ATT", Node{X: 0, Y: 0}, Node{X: 10, Y: 30}, 10}, // sqrt(1000 / 10) = 10 dokładnie
+		{"ATT", Node{X: 0, Y: 0}, Node{X: 10, Y: 0}, 4},   // sqrt(10) = 3.16 -> nint 3 < r -> 4
+		{"ATT", Node{X: 0, Y: 0}, Node{X: 30, Y: 40}, 16}, // sqrt(250) = 15.81 -> nint 16
+		{"ATT", Node{X: 6734, Y: 1453}, Node{X: 2233, Y: 10}, 1495},
+		{"GEO", Node{X: 16.47, Y: 96.10}, Node{X: 16.47, Y: 94.44}, 153},
+		{"MAN_2D", Node{X: 0, Y: 0}, Node{X: 3, Y: -4}, 7},
+		{"MAX_2D", Node{X: 0, Y: 0}, Node{X: 3, Y: -4}, 4},
+		{"EUC_3D", Node{X: 0, Y: 0, Z: 0}, Node{X: 2, Y: 3, Z: 6}, 7},
+	} {
+		dist, err := DistanceFunc(tc.edge_weight_type)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dist(tc.a, tc.b); got != tc.expected {
+			t.Errorf("%s %v-%v: got %d, expected %d", tc.edge_weight_type, tc.a, tc.b, got, tc.expected)
+		}
+	}
+	if _, err := DistanceFunc("XRAY1"); err == nil {
+		t.Error("XRAY1: expected unsupported EDGE_WEIGHT_TYPE error")
+	}
+}
+
+func TestReadInstanceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"zero dimension", "NAME: x\nDIMENSION: 0\nEDGE_WEIGHT_TYPE: EUC_2D\nNODE_COORD_SECTION\nEOF\n", "DIMENSION 0 must be positive"},
+		{"negative dimension", "NAME: x\nDIMENSION: -3\nEDGE_WEIGHT_TYPE: EUC_2D\nNODE_COORD_SECTION\n1 0 0\nEOF\n", "DIMENSION -3 must be positive"},
+		{"missing dimension", "NAME: x\nEDGE_WEIGHT_TYPE: EUC_2D\nEOF\n", "missing DIMENSION"},
+		{"repeated node", "NAME: x\nDIMENSION: 3\nEDGE_WEIGHT_TYPE: EUC_2D\nNODE_COORD_SECTION\n1 0 0\n2 1 1\n2 3 3\nEOF\n", "node number 2 repeated"},
+		{"node out of range", "NAME: x\nDIMENSION: 2\nEDGE_WEIGHT_TYPE: EUC_2D\nNODE_COORD_SECTION\n1 0 0\n3 1 1\nEOF\n", "node number 3 out of range"},
+		{"section before dimension", "NAME: x\nNODE_COORD_SECTION\n1 0 0\nEOF\n", "NODE_COORD_SECTION before DIMENSION"},
+		{"missing weights", "NAME: x\nDIMENSION: 2\nEDGE_WEIGHT_TYPE: EXPLICIT\nEOF\n", "EXPLICIT without EDGE_WEIGHT_SECTION"},
+		{"short weights", "NAME: x\nDIMENSION: 3\nEDGE_WEIGHT_TYPE: EXPLICIT\nEDGE_WEIGHT_FORMAT: UPPER_ROW\nEDGE_WEIGHT_SECTION\n1 2\n", "reading weight 2-3"},
+		{"unsupported format", "NAME: x\nDIMENSION: 2\nEDGE_WEIGHT_TYPE: EXPLICIT\nEDGE_WEIGHT_FORMAT: FUNCTION\nEDGE_WEIGHT_SECTION\n1\n", "unsupported EDGE_WEIGHT_FORMAT"},
+		{"unsupported type", "NAME: x\nDIMENSION: 1\nEDGE_WEIGHT_TYPE: SPECIAL\nNODE_COORD_SECTION\n1 0 0\nEOF\n", "unsupported EDGE_WEIGHT_TYPE"},
+		{"missing coordinates", "NAME: x\nDIMENSION: 2\nEDGE_WEIGHT_TYPE: EUC_2D\nEOF\n", "EUC_2D without NODE_COORD_SECTION"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadInstance(writeInstance(t, tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("got error %v, expected %q", err, tc.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package solver
+
+// największa liczba cykli, dla której dopasowanie etykiet sprawdza wszystkie permutacje; powyżej - zachłannie
+const maxExhaustiveAlignment = 8
+
+// zgodność cykli dwóch rozwiązań po dopasowaniu etykiet
+type Overlap struct {
+	Mapping     []int   // Mapping[c] - cykl p2 dopasowany do cyklu c rozwiązania p1
+	Relabelled  bool    // dopasowanie różne od identyczności - etykiety cykli były zamienione
+	SharedNodes int     // wierzchołki w dopasowanych cyklach obu rozwiązań
+	SharedEdges int     // krawędzie wspólne dopasowanych cykli
+	NodeRatio   float64 // SharedNodes / liczba wierzchołków p1
+	EdgeRatio   float64 // SharedEdges / liczba krawędzi p1
+}
+
+// p2 z cyklami przestawionymi tak, by cykl c odpowiadał cyklowi c z p1 - maksymalna suma wspólnych wierzchołków
+// i krawędzi dopasowanych par (przy remisie bez zamiany); cykle p2 nie są kopiowane
+func AlignParents(p1 [][]int, p2 [][]int) ([][]int, Overlap) {
+	return alignParents(p1, p2, true)
+}
+
+// search == false - etykiety bez zmian, tylko statystyki zgodności
+func alignParents(p1 [][]int, p2 [][]int, search bool) ([][]int, Overlap) {
+	nodes, edges := cycleOverlaps(p1, p2)
+	score := func(c1, c2 int) int {
+		return nodes[c1][c2] + edges[c1][c2]
+	}
+	var mapping []int
+	switch {
+	case !search:
+		mapping = make([]int, len(p1))
+		for c := range mapping {
+			mapping[c] = c
+		}
+	case len(p1) <= maxExhaustiveAlignment:
+		mapping = bestPermutation(len(p1), score)
+	default:
+		mapping = greedyMatching(len(p1), score)
+	}
+
+	overlap := Overlap{Mapping: mapping}
+	aligned := make([][]int, len(p1))
+	num_nodes := 0
+	for c := range p1 {
+		aligned[c] = p2[mapping[c]]
+		overlap.Relabelled = overlap.Relabelled || mapping[c] != c
+		overlap.SharedNodes += nodes[c][mapping[c]]
+		overlap.SharedEdges += edges[c][mapping[c]]
+		num_nodes += len(p1[c]) // cykl ma tyle krawędzi co wierzchołków
+	}
+	if num_nodes > 0 {
+		overlap.NodeRatio = float64(overlap.SharedNodes) / float64(num_nodes)
+		overlap.EdgeRatio = float64(overlap.SharedEdges) / float64(num_nodes)
+	}
+	return aligned, overlap
+}
+
+// nodes[c1][c2], edges[c1][c2] - liczba wierzchołków i krawędzi cyklu c1 z p1 należących do cyklu c2 z p2
+func cycleOverlaps(p1 [][]int, p2 [][]int) ([][]int, [][]int) {
+	cycle_of := make(map[int]int) // cykl wierzchołka w p2
+	edge_cycle := make(map[edgeKey]int)
+	for c, cycle := range p2 {
+		for _, v := range cycle {
+			cycle_of[v] = c
+		}
+		for _, e := range solutionEdges([][]int{cycle}) {
+			edge_cycle[e] = c
+		}
+	}
+	nodes := make([][]int, len(p1))
+	edges := make([][]int, len(p1))
+	for c, cycle := range p1 {
+		nodes[c] = make([]int, len(p2))
+		edges[c] = make([]int, len(p2))
+		for _, v := range cycle {
+			if c2, ok := cycle_of[v]; ok {
+				nodes[c][c2]++
+			}
+		}
+		for _, e := range solutionEdges([][]int{cycle}) {
+			if c2, ok := edge_cycle[e]; ok {
+				edges[c][c2]++
+			}
+		}
+	}
+	return nodes, edges
+}
+
+// permutacja o największej sumie score(c, mapping[c]); pierwsza w porządku leksykograficznym przy remisie
+func bestPermutation(n int, score func(int, int) int) []int {
+	best, best_score := make([]int, n), -1
+	mapping := make([]int, n)
+	used := make([]bool, n)
+	var search func(c int, total int)
+	search = func(c int, total int) {
+		if c == n {
+			if total > best_score {
+				best_score = total
+				copy(best, mapping)
+			}
+			return
+		}
+		for c2 := 0; c2 < n; c2++ {
+			if used[c2] {
+				continue
+			}
+			used[c2] = true
+			mapping[c] = c2
+			search(c+1, total+score(c, c2))
+			used[c2] = false
+		}
+	}
+	search(0, 0)
+	return best
+}
+
+// dopasowanie zachłanne - kolejno pary o największym score spośród niedopasowanych
+func greedyMatching(n int, score func(int, int) int) []int {
+	mapping := make([]int, n)
+	used1, used2 := make([]bool, n), make([]bool, n)
+	for range n {
+		best1, best2 := -1, -1
+		for c1 := range n {
+			for c2 := range n {
+				if used1[c1] || used2[c2] {
+					continue
+				}
+				if best1 == -1 || score(c1, c2) > score(best1, best2) {
+					best1, best2 = c1, c2
+				}
+			}
+		}
+		mapping[best1] = best2
+		used1[best1], used2[best2] = true, true
+	}
+	return mapping
+}
+
+// średnia zgodność rodziców w krzyżowaniach jednego uruchomienia HAE (obserwator do cfg.Observer)
+type CrossoverStats struct {
+	Crossovers    int     `json:"crossovers"`
+	Relabelled    int     `json:"relabelled"`      // krzyżowania z zamienionymi etykietami cykli rodziców
+	MeanNodeRatio float64 `json:"mean node ratio"` // średni udział wierzchołków w dopasowanych cyklach obu rodziców
+	MeanEdgeRatio float64 `json:"mean edge ratio"` // średni udział krawędzi wspólnych dopasowanych cykli
+}
+
+func (s *CrossoverStats) OnCrossover(event CrossoverEvent) {
+	s.Crossovers++
+	if event.Overlap.Relabelled {
+		s.Relabelled++
+	}
+	s.MeanNodeRatio += (event.Overlap.NodeRatio - s.MeanNodeRatio) / float64(s.Crossovers)
+	s.MeanEdgeRatio += (event.Overlap.EdgeRatio - s.MeanEdgeRatio) / float64(s.Crossovers)
+}
+
+// dołączenie statystyk innego uruchomienia - średnie ważone liczbą krzyżowań
+func (s *CrossoverStats) Merge(other *CrossoverStats) {
+	total := s.Crossovers + other.Crossovers
+	if total == 0 {
+		return
+	}
+	s.MeanNodeRatio = (s.MeanNodeRatio*float64(s.Crossovers) + other.MeanNodeRatio*float64(other.Crossovers)) / float64(total)
+	s.MeanEdgeRatio = (s.MeanEdgeRatio*float64(s.Crossovers) + other.MeanEdgeRatio*float64(other.Crossovers)) / float64(total)
+	s.Crossovers = total
+	s.Relabelled += other.Relabelled
+}
+
+func (s *CrossoverStats) OnNewBest(event Event)                    {}
+func (s *CrossoverStats) OnIteration(event Event)                  {}
+func (s *CrossoverStats) OnPopulationChange(event PopulationEvent) {}
+func (s *CrossoverStats) OnRestart(event Event)                    {}
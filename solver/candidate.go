@@ -2,10 +2,28 @@ package solver
 
 import (
 	"IMO/utils"
+	"context"
+	"fmt"
 	"math"
+	"math/rand"
+	"slices"
 	"sort"
 )
 
+func init() {
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchFast), Description: "steepest descent with a list of improving moves"}, plainImprover(FastLocalSearch))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchFastOrOpt), Description: "fast local search + Or-opt"}, plainImprover(FastLocalSearchOrOpt))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchCandidate), Description: "candidate moves", Parameters: []string{"top_candidates"}}, candidateImprover(false, false))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchCandidateOrOpt), Description: "candidate moves + Or-opt", Parameters: []string{"top_candidates"}}, candidateImprover(true, false))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchCandidateThreeOpt), Description: "candidate moves + 3-opt", Parameters: []string{"top_candidates"}}, candidateImprover(false, true))
+}
+
+func candidateImprover(or_opt bool, three_opt bool) Improver {
+	return func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, cfg *Config, rng *rand.Rand) error {
+		return candidateSearch(ctx, distance_matrix, order, limits, cfg.TopCandidates, or_opt, three_opt)
+	}
+}
+
 type Applicability int
 
 const (
@@ -34,10 +52,12 @@ type MoveEdgeDetail struct {
 	Delta int // zmiana długości cyklu po zamianie krawędzi
 }
 
-// ruch - zamiana wierzchołków między cyklami
+// ruch - zamiana wierzchołków między cyklami C1 i C2
 type SwapMoveDetail struct {
-	N1    int // wierzchołek z cyklu 1
-	N2    int // wierzchołek z cyklu 2
+	C1    int // numer cyklu 1
+	C2    int // numer cyklu 2
+	N1    int // wierzchołek z cyklu C1
+	N2    int // wierzchołek z cyklu C2
 	SN1   int // następny wierzchołek po N1
 	SN2   int // następny wierzchołek po N2
 	PN1   int // poprzedni wierzchołek po N1
@@ -45,21 +65,29 @@ type SwapMoveDetail struct {
 	Delta int // zmiana długości cyklów po dodaniu krawędzi
 }
 
-func (m *MoveEdgeDetail) ExecuteMove(order [][]int) {
-	nodes := []int{m.N1, m.N2}                        // wierzchołki do zamiany
-	indexes := utils.IndexesOf(order[m.Cycle], nodes) // znajdź indeksy w cyklu
-	if len(indexes) != 2 {
-		panic("MoveEdgeDetail: indexes not found")
-	}
+// ruch - przeniesienie wierzchołka N z cyklu C1 w miejsce krawędzi P - S w cyklu C2
+type RelocateMoveDetail struct {
+	C1    int // numer cyklu, z którego zabieramy wierzchołek
+	C2    int // numer cyklu, do którego wstawiamy wierzchołek
+	N     int // przenoszony wierzchołek
+	PN    int // poprzedni wierzchołek przed N
+	SN    int // następny wierzchołek po N
+	P     int // początek krawędzi w cyklu C2, w którą wstawiamy
+	S     int // koniec krawędzi w cyklu C2, w którą wstawiamy
+	Delta int // zmiana długości cyklów po przeniesieniu
+}
 
-	n1_index, n2_index := indexes[0], indexes[1]
-	if n1_index > n2_index { // zamień indeksy
-		n1_index, n2_index = n2_index, n1_index
+func (m *MoveEdgeDetail) ExecuteMove(tour *Tour) error {
+	if !tour.Contains(m.Cycle, m.N1) || !tour.Contains(m.Cycle, m.N2) {
+		return fmt.Errorf("%w: edge exchange nodes %d, %d not found in cycle %d", ErrInfeasible, m.N1, m.N2, m.Cycle)
 	}
-
-	for i, j := n1_index+1, n2_index; i < j; i, j = i+1, j-1 { // jak w MoveEdge
-		order[m.Cycle][i], order[m.Cycle][j] = order[m.Cycle][j], order[m.Cycle][i] // zamiana krawędzi wewnątrz cyklu
+	// nowe krawędzie N1 - N2 i SN1 - SN2 - odwrócenie ścieżki między usuwanymi krawędziami
+	if tour.Succ(m.N1) == m.SN1 {
+		tour.Reverse(m.Cycle, tour.Position(m.SN1), tour.Position(m.N2))
+	} else { // cykl przechodzony w drugą stronę
+		tour.Reverse(m.Cycle, tour.Position(m.N1), tour.Position(m.SN2))
 	}
+	return nil
 }
 
 func (m *MoveEdgeDetail) GetDelta() int {
@@ -70,16 +98,12 @@ func (m *MoveEdgeDetail) SetDelta(delta int) {
 	m.Delta = delta
 }
 
-func (m *SwapMoveDetail) ExecuteMove(order [][]int) {
-	indexes := utils.IndexesOf(order[0], []int{m.N1})                    // znajdź indeksy w cyklu
-	indexes = append(indexes, utils.IndexesOf(order[1], []int{m.N2})...) // znajdź indeksy w cyklu
-
-	if len(indexes) != 2 {
-		panic("SwapMoveDetail: indexes not found")
+func (m *SwapMoveDetail) ExecuteMove(tour *Tour) error {
+	if !tour.Contains(m.C1, m.N1) || !tour.Contains(m.C2, m.N2) {
+		return fmt.Errorf("%w: swapped nodes %d, %d not found in cycles %d, %d", ErrInfeasible, m.N1, m.N2, m.C1, m.C2)
 	}
-	n1_index, n2_index := indexes[0], indexes[1]
-
-	order[0][n1_index], order[1][n2_index] = order[1][n2_index], order[0][n1_index] // zamiana wierzchołków między cyklami
+	tour.SwapNodes(m.N1, m.N2) // zamiana wierzchołków między cyklami
+	return nil
 }
 
 func (m *SwapMoveDetail) GetDelta() int {
@@ -90,9 +114,41 @@ func (m *SwapMoveDetail) SetDelta(delta int) {
 	m.Delta = delta
 }
 
-func FastLocalSearch(distance_matrix [][]int, order [][]int) error {
+func (m *RelocateMoveDetail) ExecuteMove(tour *Tour) error {
+	if !tour.Contains(m.C1, m.N) || !tour.Contains(m.C2, m.S) {
+		return fmt.Errorf("%w: relocated node %d or edge end %d not found in cycles %d, %d", ErrInfeasible, m.N, m.S, m.C1, m.C2)
+	}
+	tour.Remove(m.N)
+	j := tour.Position(m.S)
+	if tour.Pred(m.S) != m.P { // krawędź w drugą stronę - wstawiamy przed P
+		j = tour.Position(m.P)
+	}
+	return tour.Insert(m.C2, j, m.N)
+}
+
+func (m *RelocateMoveDetail) GetDelta() int {
+	return m.Delta // zmiana długości cyklów po przeniesieniu
+}
+
+func (m *RelocateMoveDetail) SetDelta(delta int) {
+	m.Delta = delta
+}
+
+func FastLocalSearch(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return fastLocalSearch(ctx, distance_matrix, order, limits, false)
+}
+
+// FastLocalSearch rozszerzony o ruchy Or-opt
+func FastLocalSearchOrOpt(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return fastLocalSearch(ctx, distance_matrix, order, limits, true)
+}
+
+func fastLocalSearch(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, or_opt bool) error {
 	// inicjacja tablicy z najlepszymi ruchami
-	var best_moves []Move // aktualnie najlepsze ruchy posortowane od najlepszego do najgorszego
+	var (
+		best_moves []Move                  // aktualnie najlepsze ruchy posortowane od najlepszego do najgorszego
+		tour       *Tour  = NewTour(order) // cykle z pozycjami wierzchołków
+	)
 
 	distance_before := DistancesBefore(distance_matrix, order)
 	swap_moves, err := BestMovesBetweenCycles(distance_matrix, order, distance_before) // wybieranie ruchów między cyklami poprawiających wynik
@@ -102,8 +158,18 @@ func FastLocalSearch(distance_matrix [][]int, order [][]int) error {
 	for m := range swap_moves {
 		best_moves = append(best_moves, &swap_moves[m])
 	}
+	relocate_moves := BestRelocateMoves(distance_matrix, tour, limits) // przeniesienia wierzchołków poprawiające wynik
+	for m := range relocate_moves {
+		best_moves = append(best_moves, &relocate_moves[m])
+	}
+	if or_opt {
+		or_opt_moves := BestOrOptMoves(distance_matrix, tour, limits) // przeniesienia segmentów poprawiające wynik
+		for m := range or_opt_moves {
+			best_moves = append(best_moves, &or_opt_moves[m])
+		}
+	}
 
-	for c := 0; c < NumCycles; c++ {
+	for c := range order {
 		moves_cycle := BestMovesEdgesCycle(distance_matrix, order[c], c) // wybieranie ruchów krawędzi poprawiających wynik
 		for m := range moves_cycle {
 			best_moves = append(best_moves, &moves_cycle[m])
@@ -113,21 +179,26 @@ func FastLocalSearch(distance_matrix [][]int, order [][]int) error {
 		return best_moves[i].GetDelta() < best_moves[j].GetDelta() // sortowanie po najwyższych deltach
 	})
 
-	for len(best_moves) > 0 {
+	for len(best_moves) > 0 && ctx.Err() == nil { // przerwanie przez ctx zostawia rozwiązanie po dotychczasowych ruchach
 		to_delete := []int{}  // indeksy do usunięcia
 		new_moves := []Move{} // nowe ruchy do dodania
 
 	Loop: // label loopa do breakowania
 		for i, move := range best_moves {
-			applicability := CheckApplicability(move, order)
+			applicability := CheckApplicability(move, tour, limits)
 			switch applicability {
 			case Applicable:
-				move.ExecuteMove(order)
+				if err := move.ExecuteMove(tour); err != nil {
+					return err
+				}
 
-				new_moves, err = FindNewMoves(distance_matrix, order, move) // znajdź nowe ruchy
+				new_moves, err = FindNewMoves(distance_matrix, tour, move, limits) // znajdź nowe ruchy
 				if err != nil {
 					return err
 				}
+				if or_opt {
+					new_moves = append(new_moves, FindNewOrOptMoves(distance_matrix, tour, move, limits)...)
+				}
 
 				to_delete = append(to_delete, i) // usuwamy po wykonaniu
 				break Loop
@@ -145,34 +216,41 @@ func FastLocalSearch(distance_matrix [][]int, order [][]int) error {
 
 	return nil
 }
-func BestMovesBetweenCycles(distance_matrix [][]int, order [][]int, distances_before [][]int) ([]SwapMoveDetail, error) {
+func BestMovesBetweenCycles(distance_matrix *utils.DistanceMatrix, order [][]int, distances_before [][]int) ([]SwapMoveDetail, error) {
 	var (
 		moves []SwapMoveDetail // aktualnie dostępne ruchy
 	)
-	for i := 0; i < len(order[0]); i++ {
-		for j := 0; j < len(order[1]); j++ {
-			// zamiana wierzchołka i z cyklu 1 z j z cyklu 2
-			curr_node1 := order[0][i]
-			curr_node2 := order[1][j]
-			bi := utils.ElemBefore(order[0], i) // wierzchołek przed i w cyklu 1
-			bj := utils.ElemBefore(order[1], j) // wierzchołek przed j w cyklu 2
-			ai := utils.ElemAfter(order[0], i)  // wierzchołek po i w cyklu 1
-			aj := utils.ElemAfter(order[1], j)  // wierzchołek po j w cyklu 2
-
-			delta := distance_matrix[bi][curr_node2] + distance_matrix[curr_node2][ai] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-				distance_matrix[bj][curr_node1] + distance_matrix[curr_node1][aj] -
-				distances_before[0][i] - distances_before[1][j]
-			if delta < 0 {
-				// dodaj ruch do listy
-				moves = append(moves, SwapMoveDetail{
-					N1:    curr_node1,
-					N2:    curr_node2,
-					SN1:   ai,
-					SN2:   aj,
-					PN1:   bi,
-					PN2:   bj,
-					Delta: delta,
-				})
+	// dla każdej pary cykli
+	for c1 := 0; c1 < len(order); c1++ {
+		for c2 := c1 + 1; c2 < len(order); c2++ {
+			for i := 0; i < len(order[c1]); i++ {
+				for j := 0; j < len(order[c2]); j++ {
+					// zamiana wierzchołka i z cyklu c1 z j z cyklu c2
+					curr_node1 := order[c1][i]
+					curr_node2 := order[c2][j]
+					bi := utils.ElemBefore(order[c1], i) // wierzchołek przed i w cyklu c1
+					bj := utils.ElemBefore(order[c2], j) // wierzchołek przed j w cyklu c2
+					ai := utils.ElemAfter(order[c1], i)  // wierzchołek po i w cyklu c1
+					aj := utils.ElemAfter(order[c2], j)  // wierzchołek po j w cyklu c2
+
+					delta := distance_matrix.At(bi, curr_node2) + distance_matrix.At(curr_node2, ai) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+						distance_matrix.At(bj, curr_node1) + distance_matrix.At(curr_node1, aj) -
+						distances_before[c1][i] - distances_before[c2][j]
+					if delta < 0 {
+						// dodaj ruch do listy
+						moves = append(moves, SwapMoveDetail{
+							C1:    c1,
+							C2:    c2,
+							N1:    curr_node1,
+							N2:    curr_node2,
+							SN1:   ai,
+							SN2:   aj,
+							PN1:   bi,
+							PN2:   bj,
+							Delta: delta,
+						})
+					}
+				}
 			}
 		}
 	}
@@ -180,7 +258,83 @@ func BestMovesBetweenCycles(distance_matrix [][]int, order [][]int, distances_be
 	return moves, nil
 }
 
-func BestMovesEdgesCycle(distance_matrix [][]int, order []int, cycle int) []MoveEdgeDetail {
+// przeniesienia wierzchołków między cyklami poprawiające wynik
+func BestRelocateMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits) []RelocateMoveDetail {
+	var moves []RelocateMoveDetail // aktualnie dostępne ruchy
+	for c := range tour.Order {
+		moves = append(moves, RelocateMovesOfNodes(distance_matrix, tour, limits, c, tour.Order[c])...)
+	}
+	return moves
+}
+
+// przeniesienia poprawiające wynik: wierzchołków nodes z cyklu cycle do innych cykli
+// oraz wierzchołków z innych cykli w miejsce krawędzi sąsiadujących z nodes
+func RelocateMovesAround(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, cycle int, nodes []int) []RelocateMoveDetail {
+	order := tour.Order
+	moves := RelocateMovesOfNodes(distance_matrix, tour, limits, cycle, nodes)
+	for _, n := range nodes {
+		if !tour.Contains(cycle, n) {
+			continue
+		}
+		edges := []Pair[int]{
+			{A: tour.Pred(n), B: n},
+			{A: n, B: tour.Succ(n)},
+		} // krawędzie wokół wierzchołka
+		for other_cycle := range order {
+			if !limits.CanRelocate(order, other_cycle, cycle) {
+				continue
+			}
+			for i, node := range order[other_cycle] {
+				bi := utils.ElemBefore(order[other_cycle], i)
+				ai := utils.ElemAfter(order[other_cycle], i)
+				for _, edge := range edges {
+					delta := RelocateDelta(distance_matrix, node, bi, ai, edge.A, edge.B)
+					if delta < 0 {
+						moves = append(moves, RelocateMoveDetail{C1: other_cycle, C2: cycle, N: node, PN: bi, SN: ai, P: edge.A, S: edge.B, Delta: delta})
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// przeniesienia poprawiające wynik wierzchołków nodes z cyklu cycle w dowolne miejsce innych cykli
+func RelocateMovesOfNodes(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, cycle int, nodes []int) []RelocateMoveDetail {
+	var (
+		moves []RelocateMoveDetail
+		order [][]int = tour.Order
+	)
+	for other_cycle := range order {
+		moves = append(moves, RelocateMovesBetween(distance_matrix, tour, limits, cycle, other_cycle, nodes)...)
+	}
+	return moves
+}
+
+// przeniesienia poprawiające wynik wierzchołków nodes z cyklu from w dowolne miejsce cyklu to
+func RelocateMovesBetween(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, from int, to int, nodes []int) []RelocateMoveDetail {
+	var moves []RelocateMoveDetail
+	if !limits.CanRelocate(tour.Order, from, to) {
+		return nil
+	}
+	for _, n := range nodes {
+		if !tour.Contains(from, n) {
+			continue
+		}
+		bi := tour.Pred(n)
+		ai := tour.Succ(n)
+		for j, s := range tour.Order[to] {
+			p := utils.ElemBefore(tour.Order[to], j)
+			delta := RelocateDelta(distance_matrix, n, bi, ai, p, s)
+			if delta < 0 {
+				moves = append(moves, RelocateMoveDetail{C1: from, C2: to, N: n, PN: bi, SN: ai, P: p, S: s, Delta: delta})
+			}
+		}
+	}
+	return moves
+}
+
+func BestMovesEdgesCycle(distance_matrix *utils.DistanceMatrix, order []int, cycle int) []MoveEdgeDetail {
 	var (
 		n1         int              // wierzchołek 1
 		n2         int              // wierzchołek 2
@@ -194,8 +348,8 @@ func BestMovesEdgesCycle(distance_matrix [][]int, order []int, cycle int) []Move
 			n1, n2 = order[i], order[j] // wierzchołki 1 i 2 - nr w cyklu
 			ai := utils.ElemAfter(order, i)
 			aj := utils.ElemAfter(order, j)
-			delta = distance_matrix[n1][n2] + distance_matrix[ai][aj] - // dystansy po zamianie krawędzi
-				distance_matrix[ai][n1] - distance_matrix[aj][n2] // dystansy przed zamianą krawędzi
+			delta = distance_matrix.At(n1, n2) + distance_matrix.At(ai, aj) - // dystansy po zamianie krawędzi
+				distance_matrix.At(ai, n1) - distance_matrix.At(aj, n2) // dystansy przed zamianą krawędzi
 			if delta < 0 {
 				moves_node = append(moves_node, MoveEdgeDetail{
 					N1:    n1,
@@ -220,40 +374,77 @@ func BestMovesEdgesCycle(distance_matrix [][]int, order []int, cycle int) []Move
 	return moves_node
 }
 
-func CheckApplicability(move Move, order [][]int) Applicability {
+func CheckApplicability(move Move, tour *Tour, limits *SizeLimits) Applicability {
+	order := tour.Order
 	switch m := move.(type) {
 	case *MoveEdgeDetail:
-		i := utils.IndexOf(order[m.Cycle], m.N1)
-		j := utils.IndexOf(order[m.Cycle], m.N2)
-		if i == -1 || j == -1 { // jeśli nie w tym samym cyklu
+		if !tour.Contains(m.Cycle, m.N1) || !tour.Contains(m.Cycle, m.N2) { // jeśli nie w tym samym cyklu
 			return NotApplicable
 		}
-		ai, aj := utils.ElemAfter(order[m.Cycle], i), utils.ElemAfter(order[m.Cycle], j)   // wierzchołki po i i j w cyklu
-		bi, bj := utils.ElemBefore(order[m.Cycle], i), utils.ElemBefore(order[m.Cycle], j) // wierzchołki przed i i j w cyklu
+		ai, aj := tour.Succ(m.N1), tour.Succ(m.N2) // wierzchołki po i i j w cyklu
+		bi, bj := tour.Pred(m.N1), tour.Pred(m.N2) // wierzchołki przed i i j w cyklu
 
 		if m.SN1 != ai && m.SN2 != aj && m.SN1 != bi && m.SN2 != bj { // jeśli różne następne wierzchołki niż wcześniej
 			return NotApplicable
 		}
-		if m.SN1 == ai && m.SN2 == aj { // jeśli wszystko takie jak wcześniej
+		if (m.SN1 == ai && m.SN2 == aj) || (m.SN1 == bi && m.SN2 == bj) { // obie krawędzie w tym samym kierunku - cykl mógł zostać odwrócony
 			return Applicable
 		}
-		if (m.SN1 == ai || m.SN2 == aj) && (m.SN1 == ai || m.SN1 == bi) && (m.SN2 == aj || m.SN2 == bj) { // jeśli różne sąsiedzi niż wcześniej
+		if (m.SN1 == ai || m.SN1 == bi) && (m.SN2 == aj || m.SN2 == bj) { // jeśli różne sąsiedzi niż wcześniej
 			return MayBeApplicable // jakaś krawędź w drugą stronę
 		} else {
 			return NotApplicable
 		}
 
 	case *SwapMoveDetail:
-		i := utils.IndexOf(order[0], m.N1)
-		j := utils.IndexOf(order[1], m.N2)
-		if i == -1 || j == -1 { // jeśli różne cykle niż wcześniej
+		if !tour.Contains(m.C1, m.N1) || !tour.Contains(m.C2, m.N2) { // jeśli różne cykle niż wcześniej
 			return NotApplicable
 		}
-		ai := utils.ElemAfter(order[0], i)                            // wierzchołek po i w cyklu 1
-		aj := utils.ElemAfter(order[1], j)                            // wierzchołek po j w cyklu 2
-		bi := utils.ElemBefore(order[0], i)                           // wierzchołek przed i w cyklu 1
-		bj := utils.ElemBefore(order[1], j)                           // wierzchołek przed j w cyklu 2
-		if m.PN1 != bi || m.PN2 != bj || m.SN1 != ai || m.SN2 != aj { // jeśli różni sąsiedzi niż wcześniej
+		ai, bi := tour.Succ(m.N1), tour.Pred(m.N1)
+		aj, bj := tour.Succ(m.N2), tour.Pred(m.N2)
+		// różni sąsiedzi niż wcześniej; kierunek nie ma znaczenia
+		if !(m.PN1 == bi && m.SN1 == ai) && !(m.PN1 == ai && m.SN1 == bi) || !(m.PN2 == bj && m.SN2 == aj) && !(m.PN2 == aj && m.SN2 == bj) {
+			return NotApplicable
+		}
+		return Applicable
+
+	case *RelocateMoveDetail:
+		if !limits.CanRelocate(order, m.C1, m.C2) { // rozmiary cykli nie pozwalają na przeniesienie
+			return NotApplicable
+		}
+		if !tour.Contains(m.C1, m.N) || !tour.Contains(m.C2, m.P) { // jeśli różne cykle niż wcześniej
+			return NotApplicable
+		}
+		ai, bi := tour.Succ(m.N), tour.Pred(m.N)
+		if !(m.PN == bi && m.SN == ai) && !(m.PN == ai && m.SN == bi) { // różni sąsiedzi niż wcześniej; kierunek nie ma znaczenia
+			return NotApplicable
+		}
+		if !tour.Adjacent(m.P, m.S) { // krawędź P - S nie istnieje
+			return NotApplicable
+		}
+		return Applicable
+
+	case *OrOptMoveDetail:
+		if !OrOptAllowed(order, limits, m.C1, m.C2, len(m.Segment)) {
+			return NotApplicable
+		}
+		positions, ok := tour.SegmentPositions(m.C1, m.Segment)
+		if !ok { // segment rozbity lub w innym cyklu
+			return NotApplicable
+		}
+		before := utils.ElemBefore(order[m.C1], positions[0])
+		after := utils.ElemAfter(order[m.C1], positions[len(positions)-1])
+		if order[m.C1][positions[0]] != m.Segment[0] || len(m.Segment) == 1 && before != m.PA {
+			before, after = after, before // segment zapisany w cyklu w drugą stronę
+		}
+		if before != m.PA || after != m.PB { // różni sąsiedzi niż wcześniej
+			return NotApplicable
+		}
+		if !tour.Contains(m.C2, m.P) || !tour.Adjacent(m.P, m.S) { // krawędź P - S nie istnieje
+			return NotApplicable
+		}
+		seg := segmentSpan{c: m.C1, i: positions[0], step: 1, length: len(positions)}
+		if m.C1 == m.C2 && (seg.holds(tour, m.P) || seg.holds(tour, m.S)) {
 			return NotApplicable
 		}
 		return Applicable
@@ -262,67 +453,76 @@ func CheckApplicability(move Move, order [][]int) Applicability {
 	return Applicable
 }
 
-func FindNewMoves(distance_matrix [][]int, order [][]int, move Move) ([]Move, error) {
+func FindNewMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, move Move, limits *SizeLimits) ([]Move, error) {
 	var (
-		delta     int               // zmiana długości cyklu po dodaniu krawędzi)
-		new_moves []Move = []Move{} // nowe ruchy do dodania
+		order         [][]int = tour.Order
+		delta         int                                 // zmiana długości cyklu po dodaniu krawędzi)
+		new_moves     []Move  = []Move{}                  // nowe ruchy do dodania
+		nodes_inner           = make([][]int, len(order)) // wierzchołki do rozważenia po zmianach krawędzi, bierzemy pod uwagę nowe krawędzie N1-N2, SN1-SN2
+		nodes_outer           = make([][]int, len(order)) // wierzchołki do rozważenia przy zamianach między cyklami
+		indexes_inner         = make([][]int, len(order))
+		indexes_outer         = make([][]int, len(order))
 	)
-	nodes_inner := [2][]int{{}, {}} // wierzchołki do rozważenia po zmianach krawędzi, bierzemy pod uwagę nowe krawędzie N1-N2, SN1-SN2
-	nodes_outer := [2][]int{{}, {}} // wierzchołki cyklu 0 do rozważenia po zmianach krawędzi
-	indexes_inner := [2][]int{{}, {}}
-	indexes_outer := [2][]int{{}, {}}
 
 	switch m := move.(type) {
 	case *SwapMoveDetail:
 		// na nowo obliczyć dla wszystkich wierzchołków
-		nodes_outer[0] = []int{m.N2, m.SN1, m.PN1}                                              // wierzchołki do rozważenia przy zamianie wierzchołków
-		nodes_outer[1] = []int{m.N1, m.SN2, m.PN2}                                              // drugi cykl
-		indexes_outer[0] = utils.IndexesOf(order[0], nodes_outer[0])                            // znajdź indeksy w cyklu
-		indexes_outer[1] = utils.IndexesOf(order[1], nodes_outer[1])                            // drugi cykl
-		nodes_inner[0] = []int{m.N2, utils.ElemBefore(order[0], utils.IndexOf(order[0], m.N2))} // wierzchołki do rozważenia przy zamianie wierzchołków
-		nodes_inner[1] = []int{m.N1, utils.ElemBefore(order[1], utils.IndexOf(order[1], m.N1))} // drugi cykl
-		indexes_inner[0] = utils.IndexesOf(order[0], nodes_inner[0])                            // znajdź indeksy w cyklu
-		indexes_inner[1] = utils.IndexesOf(order[1], nodes_inner[1])                            // drugi cykl
+		nodes_outer[m.C1] = []int{m.N2, m.SN1, m.PN1}    // wierzchołki do rozważenia przy zamianie wierzchołków
+		nodes_outer[m.C2] = []int{m.N1, m.SN2, m.PN2}    // drugi cykl
+		nodes_inner[m.C1] = []int{m.N2, tour.Pred(m.N2)} // wierzchołki do rozważenia przy zamianie wierzchołków
+		nodes_inner[m.C2] = []int{m.N1, tour.Pred(m.N1)} // drugi cykl
 
 	case *MoveEdgeDetail:
 		// nowe krawędzie: N1 - N2, SN1 - SN2, usunięcie krawędzi N1 - SN1, N2 - SN2
-		if m.Cycle == 0 {
-			nodes_inner[0] = []int{m.N1, m.SN1}
-			nodes_outer[0] = []int{m.N1, m.N2, m.SN1, m.SN2}
-		} else {
-			nodes_inner[1] = []int{m.N1, m.SN1}
-			nodes_outer[1] = []int{m.N1, m.N2, m.SN1, m.SN2}
-		}
-		indexes_inner[0] = utils.IndexesOf(order[m.Cycle], nodes_inner[0]) // znajdź indeksy w cyklu
-		indexes_inner[1] = utils.IndexesOf(order[m.Cycle], nodes_inner[1]) // znajdź indeksy w cyklu
-		indexes_outer[0] = utils.IndexesOf(order[m.Cycle], nodes_outer[0]) // znajdź indeksy w cyklu
-		indexes_outer[1] = utils.IndexesOf(order[m.Cycle], nodes_outer[1]) // znajdź indeksy w cyklu
+		nodes_inner[m.Cycle] = []int{m.N1, m.SN1}
+		nodes_outer[m.Cycle] = []int{m.N1, m.N2, m.SN1, m.SN2}
+
+	case *RelocateMoveDetail:
+		// nowe krawędzie: PN - SN w cyklu C1, P - N i N - S w cyklu C2
+		nodes_inner[m.C1] = []int{m.PN, m.SN}
+		nodes_outer[m.C1] = []int{m.PN, m.SN}
+		nodes_inner[m.C2] = []int{m.P, m.N}
+		nodes_outer[m.C2] = []int{m.P, m.N, m.S}
+
+	case *OrOptMoveDetail:
+		// nowe krawędzie: PA - PB w cyklu C1, P - początek i koniec segmentu - S w cyklu C2
+		nodes_inner[m.C1] = []int{m.PA, m.PB}
+		nodes_outer[m.C1] = []int{m.PA, m.PB}
+		nodes_inner[m.C2] = append(nodes_inner[m.C2], m.P, m.S, m.Segment[0], m.Segment[len(m.Segment)-1])
+		nodes_outer[m.C2] = append(nodes_outer[m.C2], m.P, m.S, m.Segment[0], m.Segment[len(m.Segment)-1])
+	}
+	for c := range order {
+		indexes_inner[c] = tour.Positions(nodes_inner[c]) // indeksy w cyklu
+		indexes_outer[c] = tour.Positions(nodes_outer[c]) // indeksy w cyklu
 	}
 
-	for c, no := range nodes_outer {
+	for cycle, no := range nodes_outer {
 		for z, n1 := range no {
-			i := indexes_outer[c][z] // indeks w cyklu
+			i := indexes_outer[cycle][z] // indeks w cyklu
 
-			cycle := c
-			other_cycle := 1 - c                    // drugi cykl
-			bi := utils.ElemBefore(order[cycle], i) // wierzchołek przed i w cyklu 1
-			ai := utils.ElemAfter(order[cycle], i)  // wierzchołek po i w cyklu 1
+			bi := utils.ElemBefore(order[cycle], i) // wierzchołek przed i w cyklu
+			ai := utils.ElemAfter(order[cycle], i)  // wierzchołek po i w cyklu
 			moves_node := []SwapMoveDetail{}        // aktualnie dostępne ruchy
 
-			for j := 0; j < len(order[other_cycle]); j++ {
-				n2 := order[other_cycle][j]
-
-				bj := utils.ElemBefore(order[other_cycle], j) // wierzchołek przed j w cyklu 2
-				aj := utils.ElemAfter(order[other_cycle], j)  // wierzchołek po j w cyklu 2
-
-				delta := distance_matrix[bi][n2] + distance_matrix[n2][ai] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-					distance_matrix[bj][n1] + distance_matrix[n1][aj] -
-					distance_matrix[bi][n1] - distance_matrix[bj][n2] - // dystansy przed zamianą krawędzi
-					distance_matrix[ai][n1] - distance_matrix[aj][n2] // dystansy po zamianie krawędzi
-				if delta < 0 {
-					// dodaj ruch do listy
-					if cycle == 0 {
+			for other_cycle := range order { // zamiany ze wszystkimi pozostałymi cyklami
+				if other_cycle == cycle {
+					continue
+				}
+				for j := 0; j < len(order[other_cycle]); j++ {
+					n2 := order[other_cycle][j]
+
+					bj := utils.ElemBefore(order[other_cycle], j) // wierzchołek przed j w drugim cyklu
+					aj := utils.ElemAfter(order[other_cycle], j)  // wierzchołek po j w drugim cyklu
+
+					delta := distance_matrix.At(bi, n2) + distance_matrix.At(n2, ai) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+						distance_matrix.At(bj, n1) + distance_matrix.At(n1, aj) -
+						distance_matrix.At(bi, n1) - distance_matrix.At(bj, n2) - // dystansy przed zamianą krawędzi
+						distance_matrix.At(ai, n1) - distance_matrix.At(aj, n2) // dystansy po zamianie krawędzi
+					if delta < 0 {
+						// dodaj ruch do listy
 						moves_node = append(moves_node, SwapMoveDetail{
+							C1:    cycle,
+							C2:    other_cycle,
 							N1:    n1,
 							N2:    n2,
 							SN1:   ai,
@@ -331,16 +531,6 @@ func FindNewMoves(distance_matrix [][]int, order [][]int, move Move) ([]Move, er
 							PN2:   bj,
 							Delta: delta,
 						})
-					} else {
-						moves_node = append(moves_node, SwapMoveDetail{
-							N1:    n2,
-							N2:    n1,
-							SN1:   aj,
-							SN2:   ai,
-							PN1:   bj,
-							PN2:   bi,
-							Delta: delta,
-						})
 					}
 				}
 			}
@@ -350,6 +540,23 @@ func FindNewMoves(distance_matrix [][]int, order [][]int, move Move) ([]Move, er
 		}
 	}
 
+	// przeniesienia wierzchołków przy zmienionych krawędziach
+	for c := range nodes_outer {
+		relocate_moves := RelocateMovesAround(distance_matrix, tour, limits, c, nodes_outer[c])
+		for m := range relocate_moves {
+			new_moves = append(new_moves, &relocate_moves[m])
+		}
+	}
+	// cykl przekroczył granicę rozmiaru - przeniesienia wcześniej niedozwolone (usunięte z listy) są teraz możliwe,
+	// ale tylko między odblokowaną parą cykli
+	from, to, moved := MovedNodes(move)
+	for _, pair := range limits.Unlocked(order, from, to, moved, 1) {
+		relocate_moves := RelocateMovesBetween(distance_matrix, tour, limits, pair.A, pair.B, order[pair.A])
+		for m := range relocate_moves {
+			new_moves = append(new_moves, &relocate_moves[m])
+		}
+	}
+
 	for c, ni := range nodes_inner {
 		for n := range ni {
 			n1 := ni[n]                        // wierzchołek 1
@@ -365,8 +572,8 @@ func FindNewMoves(distance_matrix [][]int, order [][]int, move Move) ([]Move, er
 				}
 				aj := utils.ElemAfter(order[c], j)
 
-				delta = distance_matrix[n1][n2] + distance_matrix[ai][aj] - // dystansy po zamianie krawędzi
-					distance_matrix[ai][n1] - distance_matrix[aj][n2] // dystansy przed zamianą krawędzi
+				delta = distance_matrix.At(n1, n2) + distance_matrix.At(ai, aj) - // dystansy po zamianie krawędzi
+					distance_matrix.At(ai, n1) - distance_matrix.At(aj, n2) // dystansy przed zamianą krawędzi
 				if delta < 0 {
 					moves_node = append(moves_node, MoveEdgeDetail{
 						N1:    n1,
@@ -396,6 +603,7 @@ func FindNewMoves(distance_matrix [][]int, order [][]int, move Move) ([]Move, er
 	return new_moves, nil
 }
 
+// dodanie new_moves do listy s posortowanej po delcie - w miejscu, s może zostać nadpisana
 func AddNewMoves(s []Move, new_moves []Move) []Move {
 	if len(new_moves) == 0 {
 		return s
@@ -406,36 +614,26 @@ func AddNewMoves(s []Move, new_moves []Move) []Move {
 		return new_moves[i].GetDelta() < new_moves[j].GetDelta()
 	})
 
-	result := make([]Move, 0, len(s)+len(new_moves))
-	i, j := 0, 0
-
-	for i < len(s) && j < len(new_moves) {
-		if s[i].GetDelta() < new_moves[j].GetDelta() { // stare lepsze
-			result = append(result, s[i])
-			i++
-		} else { // nowe lepsze
-			result = append(result, new_moves[j])
-			j++
-		}
+	// scalanie w miejscu od końca - miejsce każdego nowego ruchu wyszukiwane binarnie, stare ruchy przesuwane
+	// całymi fragmentami; nowy ruch przed starymi o tej samej delcie
+	i := len(s) // koniec starych ruchów jeszcze nie przesuniętych
+	s = slices.Grow(s, len(new_moves))[:len(s)+len(new_moves)]
+	for j := len(new_moves) - 1; j >= 0; j-- {
+		delta := new_moves[j].GetDelta()
+		k := sort.Search(i, func(k int) bool { return s[k].GetDelta() >= delta })
+		copy(s[k+j+1:], s[k:i])
+		s[k+j] = new_moves[j]
+		i = k
 	}
-
-	// pozostałr ruchu po wypełnieniu 1 z list
-	result = append(result, s[i:]...)
-	result = append(result, new_moves[j:]...)
-
-	return result
+	return s
 }
 
-func AddSorted(s []Move, move Move) []Move {
-	for i, v := range s {
-		if move.GetDelta() <= v.GetDelta() {
-			s = utils.Insert(s, i, move) // dodaj ruch w odpowiednie miejsce
-			return s
-		}
+func AddSorted(s []Move, move Move) ([]Move, error) {
+	i := 0 // miejsce ruchu - przed pierwszym ruchem o nie mniejszej delcie
+	for i < len(s) && move.GetDelta() > s[i].GetDelta() {
+		i++
 	}
-
-	s = append(s, move)
-	return s
+	return utils.Insert(s, i, move)
 }
 
 type Pair[T comparable] struct {
@@ -443,37 +641,30 @@ type Pair[T comparable] struct {
 	B T
 }
 
-func AllCandidateMoves(distance_matrix [][]int, order [][]int, candidates [][]int, which_cycle map[int]int) ([]Move, error) {
+func AllCandidateMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, candidates [][]int, limits *SizeLimits) ([]Move, error) {
 	var (
-		delta           int                                               // zmiana długości cyklu po dodaniu krawędzi
-		moves_edge      []MoveEdgeDetail                                  // ruchy zamiany krawędzi
-		moves_swap      []SwapMoveDetail                                  // ruchy zamiany wierzchołków między cyklami
-		candidate_moves []Move                                            // wyszystkie ruchy
-		num_nodes       int              = len(distance_matrix)           // liczba wierzchołków
-		pairs           []Pair[int]                                       // pary wierzchołków/początek krawędzi do zamiany
-		nodeToIndex     []map[int]int    = make([]map[int]int, num_nodes) // mapa wierzchołków do indeksów
+		order           [][]int              = tour.Order                // cykle
+		delta           int                                              // zmiana długości cyklu po dodaniu krawędzi
+		moves_edge      []MoveEdgeDetail                                 // ruchy zamiany krawędzi
+		moves_swap      []SwapMoveDetail                                 // ruchy zamiany wierzchołków między cyklami
+		moves_relocate  []RelocateMoveDetail                             // ruchy przeniesienia wierzchołka między cyklami
+		candidate_moves []Move                                           // wyszystkie ruchy
+		num_nodes       int                  = distance_matrix.Dimension // liczba wierzchołków
+		pairs           []Pair[int]                                      // pary wierzchołków/początek krawędzi do zamiany
 	)
-	for i := range order {
-		nodeToIndex[i] = make(map[int]int, len(order[i]))
-		for j, n := range order[i] {
-			nodeToIndex[i][n] = j // mapa wierzchołków do indeksów
-		}
-	}
 
 	for i := 0; i < num_nodes; i++ {
-		cycle := which_cycle[i]                       // w którym cyklu jest dany wierzchołek
-		index_i := nodeToIndex[cycle][i]              // indeks i w cyklu
-		ai := utils.ElemAfter(order[cycle], index_i)  // wierzchołek po i w cyklu
-		bi := utils.ElemBefore(order[cycle], index_i) // wierzchołek przed i w cyklu
+		cycle := tour.Cycle(i) // w którym cyklu jest dany wierzchołek
+		ai := tour.Succ(i)     // wierzchołek po i w cyklu
+		bi := tour.Pred(i)     // wierzchołek przed i w cyklu
 
 		for _, candidate := range candidates[i] {
 			if candidate == ai || candidate == bi { // jeśli już sąsiedzi nie rozważamy
 				continue
 			}
-			cycle_candidate := which_cycle[candidate]                       // w którym cyklu jest dany wierzchołek
-			index_candidate := nodeToIndex[cycle_candidate][candidate]      // indeks kandydata w cyklu
-			aj := utils.ElemAfter(order[cycle_candidate], index_candidate)  // wierzchołek po candidate w cyklu
-			bj := utils.ElemBefore(order[cycle_candidate], index_candidate) // wierzchołek przed candidate w cyklu
+			cycle_candidate := tour.Cycle(candidate) // w którym cyklu jest dany wierzchołek
+			aj := tour.Succ(candidate)               // wierzchołek po candidate w cyklu
+			bj := tour.Pred(candidate)               // wierzchołek przed candidate w cyklu
 
 			if cycle == cycle_candidate { // jeśli w tym samym cyklu -> zamiana krawędzi
 				pairs = []Pair[int]{
@@ -483,16 +674,15 @@ func AllCandidateMoves(distance_matrix [][]int, order [][]int, candidates [][]in
 
 				for _, pair := range pairs {
 					a, b := pair.A, pair.B
-					index_a, index_b := nodeToIndex[cycle_candidate][a], nodeToIndex[cycle_candidate][b] // indeksy w cyklu
-					aa := utils.ElemAfter(order[cycle], index_a)                                         // wierzchołek po a w cyklu
-					ab := utils.ElemAfter(order[cycle], index_b)                                         // wierzchołek po b w cyklu
+					aa := tour.Succ(a) // wierzchołek po a w cyklu
+					ab := tour.Succ(b) // wierzchołek po b w cyklu
 
-					delta = distance_matrix[a][b] + distance_matrix[aa][ab] - // dystansy po zamianie krawędzi
-						distance_matrix[a][aa] - distance_matrix[b][ab] // dystansy przed zamianą krawędzi
+					delta = distance_matrix.At(a, b) + distance_matrix.At(aa, ab) - // dystansy po zamianie krawędzi
+						distance_matrix.At(a, aa) - distance_matrix.At(b, ab) // dystansy przed zamianą krawędzi
 
 					moves_edge = append(moves_edge, MoveEdgeDetail{
-						N1:    order[cycle][index_a],
-						N2:    order[cycle][index_b],
+						N1:    a,
+						N2:    b,
 						SN1:   aa,
 						SN2:   ab,
 						Delta: delta,
@@ -500,36 +690,28 @@ func AllCandidateMoves(distance_matrix [][]int, order [][]int, candidates [][]in
 					})
 				}
 			} else { // jeśli w różnych cyklach -> zamiana wierzchołków
-				if cycle == 0 { // pierwszy z pary cykl = 0
-					pairs = []Pair[int]{
-						{A: i, B: bj},
-						{A: i, B: aj},
-						{A: bi, B: candidate},
-						{A: ai, B: candidate},
-					} // pary wierzchołków do zamiany - wierzchołki obok tego z którym chcemy mieć krawędź
-				} else {
-					pairs = []Pair[int]{
-						{A: bj, B: i},
-						{A: aj, B: i},
-						{A: candidate, B: bi},
-						{A: candidate, B: ai},
-					} // pary wierzchołków do zamiany - wierzchołki obok tego z którym chcemy mieć krawędź
-				}
+				pairs = []Pair[int]{
+					{A: i, B: bj},
+					{A: i, B: aj},
+					{A: bi, B: candidate},
+					{A: ai, B: candidate},
+				} // pary wierzchołków do zamiany (A z cyklu i, B z cyklu kandydata) - wierzchołki obok tego z którym chcemy mieć krawędź
 
 				for _, pair := range pairs {
 					a, b := pair.A, pair.B
-					index_a, index_b := nodeToIndex[0][a], nodeToIndex[1][b] // indeksy w cyklu
-					aa := utils.ElemAfter(order[0], index_a)                 // wierzchołek po a w cyklu
-					ab := utils.ElemAfter(order[1], index_b)                 // wierzchołek po b w cyklu
-					ba := utils.ElemBefore(order[0], index_a)                // wierzchołek przed a w cyklu
-					bb := utils.ElemBefore(order[1], index_b)                // wierzchołek przed b w cyklu
+					aa := tour.Succ(a) // wierzchołek po a w cyklu
+					ab := tour.Succ(b) // wierzchołek po b w cyklu
+					ba := tour.Pred(a) // wierzchołek przed a w cyklu
+					bb := tour.Pred(b) // wierzchołek przed b w cyklu
 
-					delta = distance_matrix[ba][b] + distance_matrix[b][aa] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-						distance_matrix[bb][a] + distance_matrix[a][ab] -
-						distance_matrix[ba][a] - distance_matrix[bb][b] - // dystansy przed zamianą krawędzi
-						distance_matrix[aa][a] - distance_matrix[ab][b] // dystansy po zamianie krawędzi
+					delta = distance_matrix.At(ba, b) + distance_matrix.At(b, aa) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+						distance_matrix.At(bb, a) + distance_matrix.At(a, ab) -
+						distance_matrix.At(ba, a) - distance_matrix.At(bb, b) - // dystansy przed zamianą krawędzi
+						distance_matrix.At(aa, a) - distance_matrix.At(ab, b) // dystansy po zamianie krawędzi
 
 					moves_swap = append(moves_swap, SwapMoveDetail{
+						C1:    cycle,
+						C2:    cycle_candidate,
 						N1:    a,
 						N2:    b,
 						SN1:   aa,
@@ -539,6 +721,20 @@ func AllCandidateMoves(distance_matrix [][]int, order [][]int, candidates [][]in
 						Delta: delta,
 					})
 				}
+
+				// przeniesienie i obok kandydata lub kandydata obok i - nowa krawędź i - candidate
+				if limits.CanRelocate(order, cycle, cycle_candidate) {
+					for _, edge := range []Pair[int]{{A: bj, B: candidate}, {A: candidate, B: aj}} {
+						delta = RelocateDelta(distance_matrix, i, bi, ai, edge.A, edge.B)
+						moves_relocate = append(moves_relocate, RelocateMoveDetail{C1: cycle, C2: cycle_candidate, N: i, PN: bi, SN: ai, P: edge.A, S: edge.B, Delta: delta})
+					}
+				}
+				if limits.CanRelocate(order, cycle_candidate, cycle) {
+					for _, edge := range []Pair[int]{{A: bi, B: i}, {A: i, B: ai}} {
+						delta = RelocateDelta(distance_matrix, candidate, bj, aj, edge.A, edge.B)
+						moves_relocate = append(moves_relocate, RelocateMoveDetail{C1: cycle_candidate, C2: cycle, N: candidate, PN: bj, SN: aj, P: edge.A, S: edge.B, Delta: delta})
+					}
+				}
 			}
 		}
 	}
@@ -549,42 +745,61 @@ func AllCandidateMoves(distance_matrix [][]int, order [][]int, candidates [][]in
 	for i := range moves_swap {
 		candidate_moves = append(candidate_moves, &moves_swap[i])
 	}
+	for i := range moves_relocate {
+		candidate_moves = append(candidate_moves, &moves_relocate[i])
+	}
 
 	return candidate_moves, nil
 }
 
-func CandidateSearch(distance_matrix [][]int, order [][]int) error {
+func CandidateSearch(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return candidateSearch(ctx, distance_matrix, order, limits, DefaultTopCandidates, false, false)
+}
+
+// CandidateSearch rozszerzony o ruchy Or-opt
+func CandidateSearchOrOpt(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return candidateSearch(ctx, distance_matrix, order, limits, DefaultTopCandidates, true, false)
+}
+
+// CandidateSearch rozszerzony o ruchy 3-opt ograniczone do list kandydatów
+func CandidateSearch3Opt(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return candidateSearch(ctx, distance_matrix, order, limits, DefaultTopCandidates, false, true)
+}
+
+// przeszukiwanie ruchami kandydackimi; top_candidates - rozmiar list kandydatów
+func candidateSearch(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, top_candidates int, or_opt bool, three_opt bool) error {
 	var (
-		candidate_moves []Move      // aktualnie dostępne ruchy
-		candidates      [][]int     // numery wierzchołków kandydackich dla każdego wierzchołka
-		top_candidates  int         = 10
-		which_cycle     map[int]int // w którym cyklu jest dany wierzchołek
+		candidate_moves []Move                   // aktualnie dostępne ruchy
+		candidates      [][]int                  // numery wierzchołków kandydackich dla każdego wierzchołka
+		tour            *Tour   = NewTour(order) // cykle z pozycjami wierzchołków
 	)
 
-	candidates = CalculateCandidates(distance_matrix, top_candidates) // obliczanie kandydatów
-	// inicjalizacja which_cycle
-	which_cycle = make(map[int]int)
-	for c := range order {
-		for i := range order[c] {
-			which_cycle[order[c][i]] = c // przypisanie cyklu do wierzchołka
-		}
+	candidates, err := CalculateCandidates(distance_matrix, top_candidates) // obliczanie kandydatów
+	if err != nil {
+		return err
 	}
 
 	var (
-		best_move       Move  = nil                                                // najlepszy ruch w iteracji
-		min_delta       int   = math.MaxInt                                        // minimalna zmiana długości cyklu
-		current_length1 int   = utils.CalculateCycleLen(order[0], distance_matrix) // akutalna długość cyklu 1
-		current_length2 int   = utils.CalculateCycleLen(order[1], distance_matrix) // akutalna długość cyklu 2
-		current_length  int   = current_length1 + current_length2
-		err             error = nil
+		best_move      Move = nil                                              // najlepszy ruch w iteracji
+		min_delta      int  = math.MaxInt                                      // minimalna zmiana długości cyklu
+		current_length int  = utils.CalculateCyclesLen(order, distance_matrix) // akutalna długość cykli
 	)
 
 	for {
+		if ctx.Err() != nil {
+			return nil // przerwane przez ctx - order zawiera rozwiązanie po dotychczasowych ruchach
+		}
 		// ruchy pomiędzy cyklami
-		candidate_moves, err = AllCandidateMoves(distance_matrix, order, candidates, which_cycle) // wszystkie ruchy między cyklami
+		candidate_moves, err = AllCandidateMoves(distance_matrix, tour, candidates, limits) // wszystkie ruchy między cyklami
 		if err != nil {
 			return err
 		}
+		if or_opt {
+			candidate_moves = append(candidate_moves, AllCandidateOrOptMoves(distance_matrix, tour, candidates, limits)...)
+		}
+		if three_opt {
+			candidate_moves = append(candidate_moves, CandidateThreeOptMoves(distance_matrix, tour, candidates)...)
+		}
 
 		best_move, min_delta = FindBestMove(candidate_moves) // najlepszy ruch i minimalna zmiana długości cyklu
 
@@ -594,14 +809,8 @@ func CandidateSearch(distance_matrix [][]int, order [][]int) error {
 			break
 		}
 		// jeśli znaleziono ruch, to wykonaj go
-		best_move.ExecuteMove(order) // wykonaj najlepszy ruch
-
-		// aktualizacja which_cycle
-		bm, ok := best_move.(*SwapMoveDetail)
-		if ok { // jeśli ruch to zamiana wierzchołków
-			// zamień cykle
-			which_cycle[bm.N1] = 1 - which_cycle[bm.N1] // zamień cykle
-			which_cycle[bm.N2] = 1 - which_cycle[bm.N2] // zamień cykle
+		if err := best_move.ExecuteMove(tour); err != nil { // wykonaj najlepszy ruch
+			return err
 		}
 
 		current_length = current_length + min_delta // aktualizuj długość cyklu
@@ -611,36 +820,33 @@ func CandidateSearch(distance_matrix [][]int, order [][]int) error {
 	return nil
 }
 
-func CalculateCandidates(distance_matrix [][]int, top_candidates int) (candidates [][]int) {
-	candidates = make([][]int, len(distance_matrix)) // numery wierzchołków kandydackich dla każdego wierzchołka
+func CalculateCandidates(distance_matrix *utils.DistanceMatrix, top_candidates int) ([][]int, error) {
+	candidates := make([][]int, distance_matrix.Dimension) // numery wierzchołków kandydackich dla każdego wierzchołka
 
-	for i := 0; i < len(distance_matrix); i++ {
-		for j := 0; j < len(distance_matrix); j++ {
-			var (
-				c     int  // aktualny kandydat
-				k     int  // nr kandydata
-				added bool = false
-			)
+	for i := 0; i < distance_matrix.Dimension; i++ {
+		for j := 0; j < distance_matrix.Dimension; j++ {
 			if i == j {
 				continue
 			}
 
-			dist := distance_matrix[i][j] // dystans między i - aktualny wierzchołek, a j - potencjalny kandydat
-			for k, c = range candidates[i] {
-				if dist < distance_matrix[i][c] { // jeśli dystans mniejszy niż aktualny kandydat
-					added = true
-					candidates[i] = utils.Insert(candidates[i], k, j) // dodaj kandydata w odpowiednie miejsce
-					if len(candidates[i]) > top_candidates {          // jeśli za dużo kandydatów
-						candidates[i] = candidates[i][:top_candidates] // ogranicz do top_candidates
-					}
-					break
-				}
+			dist := distance_matrix.At(i, j) // dystans między i - aktualny wierzchołek, a j - potencjalny kandydat
+			k := 0                           // miejsce kandydata - przed pierwszym dalszym
+			for k < len(candidates[i]) && dist >= distance_matrix.At(i, candidates[i][k]) {
+				k++
+			}
+			if k == top_candidates { // dalej niż wszyscy kandydaci
+				continue
+			}
+			var err error
+			candidates[i], err = utils.Insert(candidates[i], k, j) // dodaj kandydata w odpowiednie miejsce
+			if err != nil {
+				return nil, err
 			}
-			if !added && len(candidates[i]) < top_candidates { // jeśli nie dodano kandydata i nie ma wszystkich
-				candidates[i] = append(candidates[i], j) // dodaj kandydata na koniec
+			if len(candidates[i]) > top_candidates { // jeśli za dużo kandydatów
+				candidates[i] = candidates[i][:top_candidates] // ogranicz do top_candidates
 			}
 		}
 	}
 
-	return
+	return candidates, nil
 }
@@ -0,0 +1,86 @@
+package solver
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+)
+
+// limit kolejnych odrzuconych duplikatów przy tworzeniu populacji - po nim duplikat jest przyjmowany,
+// bo heurystyka z lokalnym przeszukiwaniem nie daje już wystarczająco różnych rozwiązań
+const maxDuplicateRejections = 100
+
+// postać kanoniczna rozwiązania: każdy cykl zaczyna się od najmniejszego wierzchołka i biegnie w stronę mniejszego
+// z jego sąsiadów, cykle posortowane leksykograficznie - równa dla rozwiązań różniących się tylko obrotem,
+// kierunkiem lub etykietami cykli
+func Canonical(order [][]int) [][]int {
+	canonical := make([][]int, len(order))
+	for c, cycle := range order {
+		canonical[c] = canonicalCycle(cycle)
+	}
+	slices.SortFunc(canonical, func(a, b []int) int { return slices.Compare(a, b) })
+	return canonical
+}
+
+func canonicalCycle(cycle []int) []int {
+	n := len(cycle)
+	canonical := make([]int, n)
+	if n == 0 {
+		return canonical
+	}
+	start := 0
+	for i := range cycle {
+		if cycle[i] < cycle[start] {
+			start = i
+		}
+	}
+	forward := cycle[(start+1)%n] <= cycle[(start-1+n)%n]
+	for k := range n {
+		if forward {
+			canonical[k] = cycle[(start+k)%n]
+		} else {
+			canonical[k] = cycle[(start-k+n)%n]
+		}
+	}
+	return canonical
+}
+
+// skrót postaci kanonicznej (FNV-1a) - równe rozwiązania mają równe skróty
+func SolutionHash(order [][]int) uint64 {
+	hash := fnv.New64a()
+	var buf []byte
+	for _, cycle := range Canonical(order) {
+		buf = binary.LittleEndian.AppendUint64(buf[:0], uint64(len(cycle))) // granica cyklu
+		for _, v := range cycle {
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+		}
+		hash.Write(buf)
+	}
+	return hash.Sum64()
+}
+
+// liczba krawędzi a nieobecnych w b; 0 - te same cykle niezależnie od obrotu, kierunku i etykiet
+func EdgeDistance(a [][]int, b [][]int) int {
+	diff := diffEdges(a, b)
+	distance := 0
+	for _, common := range diff.a_common {
+		if !common {
+			distance++
+		}
+	}
+	return distance
+}
+
+// czy order (o skrócie hash) już jest w populacji albo - przy distance > 0 - różni się od któregoś osobnika
+// o najwyżej distance krawędzi; hashes[i] - skrót population[i]
+func isDuplicate(population [][][]int, hashes []uint64, order [][]int, hash uint64, distance int) bool {
+	for i, member := range population {
+		if hashes[i] != hash && distance == 0 {
+			continue // różne skróty - różne rozwiązania
+		}
+		if EdgeDistance(member, order) <= distance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Canonical, SolutionHash i EdgeDistance nie zależą od obrotu, kierunku ani kolejności cykli,
+// a rozróżniają rozwiązania po jednym ruchu 2-opt
+func TestCanonicalInvariance(t *testing.T) {
+	rotate := func(order [][]int, rng *rand.Rand) [][]int {
+		for c, cycle := range order {
+			k := rng.Intn(len(cycle))
+			order[c] = append(cycle[k:], cycle[:k]...)
+		}
+		return order
+	}
+	reverse := func(order [][]int, rng *rand.Rand) [][]int {
+		for _, cycle := range order {
+			slices.Reverse(cycle)
+		}
+		return order
+	}
+	permute := func(order [][]int, rng *rand.Rand) [][]int {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		return order
+	}
+	for _, tc := range []struct {
+		name      string
+		transform func(order [][]int, rng *rand.Rand) [][]int
+	}{
+		{"rotation", rotate},
+		{"reversal", reverse},
+		{"cycle permutation", permute},
+		{"all", func(order [][]int, rng *rand.Rand) [][]int {
+			return permute(reverse(rotate(order, rng), rng), rng)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				order := randomOrder([]int{7, 6, 5}, rng)
+				transformed := tc.transform(cloneOrder(order), rng)
+				if !slices.EqualFunc(Canonical(order), Canonical(transformed), slices.Equal) {
+					t.Errorf("seed %d: canonical forms of %v and %v differ", seed, order, transformed)
+				}
+				if SolutionHash(order) != SolutionHash(transformed) {
+					t.Errorf("seed %d: hashes of %v and %v differ", seed, order, transformed)
+				}
+				if d := EdgeDistance(order, transformed); d != 0 {
+					t.Errorf("seed %d: edge distance of %v and %v is %d", seed, order, transformed, d)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalTwoOpt(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		order := randomOrder([]int{7, 6, 5}, rng)
+		c := rng.Intn(len(order))
+		n := len(order[c])
+		i := rng.Intn(n - 3)
+		j := i + 2 + rng.Intn(n-3-i) // i+1 < j < n-1 - krawędzie (i, i+1) i (j, j+1) nie sąsiadują
+		tour := NewTour(cloneOrder(order))
+		if err := (&MoveEdge{Cycle: c, N1: i, N2: j}).ExecuteMove(tour); err != nil {
+			t.Fatal(err)
+		}
+		if slices.EqualFunc(Canonical(order), Canonical(tour.Order), slices.Equal) {
+			t.Errorf("seed %d: canonical forms of %v and %v equal after 2-opt", seed, order, tour.Order)
+		}
+		if SolutionHash(order) == SolutionHash(tour.Order) {
+			t.Errorf("seed %d: hashes of %v and %v equal after 2-opt", seed, order, tour.Order)
+		}
+		if d := EdgeDistance(order, tour.Order); d != 2 {
+			t.Errorf("seed %d: edge distance of %v and %v is %d, expected 2", seed, order, tour.Order, d)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// nazwy wbudowanych algorytmów; pełna lista w rejestrze (Constructors, Improvers, Metaheuristics)
+
+// heurystyka konstrukcyjna - rozwiązanie startowe
+type Heuristic string
+
+const (
+	HeuristicNearestNeighbour Heuristic = "nn"      // najbliższy sąsiad
+	HeuristicGreedyCycle      Heuristic = "gc"      // rozbudowa cyklu
+	HeuristicRegret           Heuristic = "reg"     // żal
+	HeuristicWeightedRegret   Heuristic = "wreg"    // żal ważony
+	HeuristicRandom           Heuristic = "rand"    // rozwiązanie losowe
+	HeuristicInOrder          Heuristic = "inorder" // wierzchołki po kolei
+)
+
+// algorytm lokalnego przeszukiwania
+type LocalSearch string
+
+const (
+	LocalSearchSteepestNode      LocalSearch = "sn"   // steepest, zamiana wierzchołków
+	LocalSearchSteepestEdge      LocalSearch = "se"   // steepest, zamiana krawędzi
+	LocalSearchGreedyNode        LocalSearch = "gn"   // greedy, zamiana wierzchołków
+	LocalSearchGreedyEdge        LocalSearch = "ge"   // greedy, zamiana krawędzi
+	LocalSearchRandomWalk        LocalSearch = "rw"   // losowe błądzenie
+	LocalSearchFast              LocalSearch = "fls"  // lista ruchów przynoszących poprawę
+	LocalSearchCandidate         LocalSearch = "c"    // ruchy kandydackie
+	LocalSearchSteepestOrOpt     LocalSearch = "so"   // steepest edge + Or-opt
+	LocalSearchGreedyOrOpt       LocalSearch = "go"   // greedy edge + Or-opt
+	LocalSearchFastOrOpt         LocalSearch = "flso" // fast local search + Or-opt
+	LocalSearchCandidateOrOpt    LocalSearch = "co"   // ruchy kandydackie + Or-opt
+	LocalSearchCandidateThreeOpt LocalSearch = "c3"   // ruchy kandydackie + 3-opt
+	LocalSearchLinKernighan      LocalSearch = "lk"   // Lin-Kernighan
+)
+
+// metaheurystyka korzystająca z lokalnego przeszukiwania
+type Metaheuristic string
+
+const (
+	MetaheuristicMSLS         Metaheuristic = "msls"    // multiple start local search
+	MetaheuristicParallelMSLS Metaheuristic = "pmsls"   // multiple start local search na wielu gorutynach
+	MetaheuristicILS          Metaheuristic = "ils"     // iterated local search
+	MetaheuristicLNSWithLS    Metaheuristic = "lns-ls"  // large neighbourhood search z lokalnym przeszukiwaniem
+	MetaheuristicLNS          Metaheuristic = "lns"     // large neighbourhood search bez lokalnego przeszukiwania
+	MetaheuristicHAE          Metaheuristic = "hae"     // hybrydowy algorytm ewolucyjny
+	MetaheuristicHAEWithLS    Metaheuristic = "hae-ls"  // hybrydowy algorytm ewolucyjny z lokalnym przeszukiwaniem potomków
+	MetaheuristicIslandHAE    Metaheuristic = "ihae"    // HAE z populacjami na wyspach z migracją
+	MetaheuristicIslandHAELS  Metaheuristic = "ihae-ls" // HAE na wyspach z lokalnym przeszukiwaniem potomków
+)
+
+// operator krzyżowania HAE
+type Crossover string
+
+const (
+	CrossoverCommonEdges Crossover = "common-edges" // wspólne krawędzie rodziców połączone w łańcuchy
+	CrossoverEAX         Crossover = "eax"          // edge assembly crossover
+	CrossoverGPX         Crossover = "gpx"          // partition crossover
+	CrossoverOX          Crossover = "ox"           // order crossover cykli połączonych w jedną trasę
+	CrossoverCommonNodes Crossover = "common-nodes" // wspólne przypisanie wierzchołków do cykli
+)
+
+// topologia migracji między wyspami HAE
+type Topology string
+
+const (
+	TopologyRing Topology = "ring" // wyspa i wysyła do wyspy i+1
+	TopologyFull Topology = "full" // każda wyspa wysyła do wszystkich pozostałych
+)
+
+// strategia wymiany osobników populacji HAE przez potomka
+type Replacement string
+
+const (
+	ReplacementWorst         Replacement = "worst"          // potomek lepszy od najgorszego zastępuje najgorszego
+	ReplacementClosest       Replacement = "closest"        // potomek zastępuje najbliższego mu (w krawędziach) spośród gorszych od niego
+	ReplacementBiasedFitness Replacement = "biased-fitness" // usuwany osobnik o najgorszym rankingu kosztu i wkładu w różnorodność (HGS)
+)
+
+// wyspa HAE - własna populacja z własną heurystyką, lokalnym przeszukiwaniem i krzyżowaniem; puste pola - z konfiguracji głównej
+type Island struct {
+	Heuristic   Heuristic   `json:"heuristic,omitempty" yaml:"heuristic,omitempty"`
+	LocalSearch LocalSearch `json:"local_search,omitempty" yaml:"local_search,omitempty"`
+	Crossover   Crossover   `json:"crossover,omitempty" yaml:"crossover,omitempty"`
+}
+
+// domyślne wartości parametrów
+const (
+	DefaultPerturbationRatio float32       = 0.3
+	DefaultDestroyRatio      float32       = 0.3
+	DefaultTopCandidates     int           = 10
+	DefaultRegretWeight      int           = 1  // waga żalu w WeightedRegret
+	DefaultChangeWeight      int           = -4 // waga przyrostu długości w WeightedRegret
+	DefaultRandomWalkTime    time.Duration = 1538 * time.Millisecond
+	DefaultTimeLimit         time.Duration = 65550 * time.Millisecond // 65.55s - kroB średni czas MSLS
+	DefaultIterations        int           = 200
+	DefaultPopulationSize    int           = 20
+	DefaultNumIslands        int           = 4 // liczba wysp HAE przy pustym Islands
+	DefaultMigrationInterval int           = 100
+	DefaultMigrants          int           = 1
+	DefaultEliteSize         int           = 4 // HGS - liczba osobników chronionych przez ranking kosztu
+	DefaultNumClosest        int           = 5 // HGS - liczba najbliższych osobników w wkładzie w różnorodność
+)
+
+// czas w pliku konfiguracyjnym - tekst w formacie time.ParseDuration ("1.5s", "200ms") lub liczba milisekund
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		d.Duration = time.Duration(ms * float64(time.Millisecond))
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data) // liczba milisekund
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// konfiguracja algorytmów - wybór algorytmów i wszystkie parametry strojenia
+type Config struct {
+	Heuristic         Heuristic     `json:"heuristic" yaml:"heuristic"`                   // rozwiązanie startowe
+	LocalSearch       LocalSearch   `json:"local_search" yaml:"local_search"`             // lokalne przeszukiwanie (też wewnątrz metaheurystyk)
+	Metaheuristic     Metaheuristic `json:"metaheuristic" yaml:"metaheuristic"`           // metaheurystyka
+	PerturbationRatio float32       `json:"perturbation_ratio" yaml:"perturbation_ratio"` // ILS - część cyklu zaburzana w perturbacji
+	DestroyRatio      float32       `json:"destroy_ratio" yaml:"destroy_ratio"`           // LNS - część cyklu usuwana w Destroy
+	TopCandidates     int           `json:"top_candidates" yaml:"top_candidates"`         // rozmiar list kandydatów (c, co, c3, lk)
+	LKMaxDepth        int           `json:"lk_max_depth" yaml:"lk_max_depth"`             // maksymalna długość łańcucha ruchów Lin-Kernighana
+	RegretWeight      int           `json:"regret_weight" yaml:"regret_weight"`           // WeightedRegret - waga żalu
+	ChangeWeight      int           `json:"change_weight" yaml:"change_weight"`           // WeightedRegret - waga przyrostu długości
+	RandomWalkTime    Duration      `json:"random_walk_time" yaml:"random_walk_time"`     // czas losowego błądzenia
+	PopulationSize    int           `json:"population_size" yaml:"population_size"`       // HAE - rozmiar populacji elitarnej
+	Crossover         Crossover     `json:"crossover" yaml:"crossover"`                   // HAE - operator krzyżowania
+	AlignCycles       bool          `json:"align_cycles" yaml:"align_cycles"`             // HAE - dopasowanie etykiet cykli rodziców przed krzyżowaniem
+	DuplicateDistance int           `json:"duplicate_distance" yaml:"duplicate_distance"` // HAE - odrzucanie osobników różniących się od obecnych o najwyżej tyle krawędzi; 0 - tylko identycznych
+	Replacement       Replacement   `json:"replacement" yaml:"replacement"`               // HAE - który osobnik ustępuje potomkowi
+	EliteSize         int           `json:"elite_size" yaml:"elite_size"`                 // HAE biased-fitness - waga różnorodności 1 - elite_size/rozmiar populacji
+	NumClosest        int           `json:"num_closest" yaml:"num_closest"`               // HAE biased-fitness - liczba najbliższych osobników w wkładzie w różnorodność
+	TimeLimit         Duration      `json:"time_limit" yaml:"time_limit"`                 // limit czasu ILS, LNS i HAE
+	Iterations        int           `json:"iterations" yaml:"iterations"`                 // liczba iteracji MSLS (w pmsls obok limitu czasu)
+	MaxIterations     int           `json:"max_iterations" yaml:"max_iterations"`         // limit iteracji ILS, LNS i HAE obok limitu czasu; 0 - tylko czas
+	Workers           int           `json:"workers" yaml:"workers"`                       // liczba gorutyn jednego uruchomienia pmsls; 0 - liczba procesorów
+	Islands           []Island      `json:"islands,omitempty" yaml:"islands,omitempty"`   // wyspy ihae; puste - DefaultNumIslands wysp z heuristic i local_search
+	Topology          Topology      `json:"migration_topology" yaml:"migration_topology"` // ihae - dokąd wyspy wysyłają migrantów
+	MigrationInterval int           `json:"migration_interval" yaml:"migration_interval"` // ihae - liczba iteracji wyspy między migracjami
+	Migrants          int           `json:"migrants" yaml:"migrants"`                     // ihae - liczba najlepszych osobników wysyłanych w migracji
+	Seed              int64         `json:"seed" yaml:"seed"`                             // ziarno generatora liczb losowych; 0 - losowe
+	Observer          Observer      `json:"-" yaml:"-"`                                   // zdarzenia przebiegu metaheurystyk; nil - bez zgłaszania
+}
+
+// konfiguracja z domyślnymi wartościami - odpowiada dotychczasowym stałym w kodzie
+func DefaultConfig() *Config {
+	return &Config{
+		Heuristic:         HeuristicRandom,
+		LocalSearch:       LocalSearchSteepestEdge,
+		Metaheuristic:     MetaheuristicILS,
+		PerturbationRatio: DefaultPerturbationRatio,
+		DestroyRatio:      DefaultDestroyRatio,
+		TopCandidates:     DefaultTopCandidates,
+		LKMaxDepth:        LKMaxDepth,
+		RegretWeight:      DefaultRegretWeight,
+		ChangeWeight:      DefaultChangeWeight,
+		RandomWalkTime:    Duration{DefaultRandomWalkTime},
+		PopulationSize:    DefaultPopulationSize,
+		Crossover:         CrossoverCommonEdges,
+		AlignCycles:       true,
+		Replacement:       ReplacementWorst,
+		EliteSize:         DefaultEliteSize,
+		NumClosest:        DefaultNumClosest,
+		TimeLimit:         Duration{DefaultTimeLimit},
+		Iterations:        DefaultIterations,
+		Topology:          TopologyRing,
+		MigrationInterval: DefaultMigrationInterval,
+		Migrants:          DefaultMigrants,
+	}
+}
+
+// wczytanie konfiguracji z pliku JSON (.json) lub YAML (.yaml, .yml); pola nieobecne w pliku mają wartości domyślne
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+	default:
+		return nil, fmt.Errorf("%w: unknown config file extension %q (expected .json, .yaml or .yml)", ErrInvalidConfig, ext)
+	}
+	if err != nil && err != io.EOF { // io.EOF - pusty plik
+		return nil, fmt.Errorf("config %s: %w: %v", path, ErrInvalidConfig, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) Validate() error {
+	if _, err := constructors.lookup(string(cfg.Heuristic)); err != nil {
+		return err
+	}
+	if _, err := improvers.lookup(string(cfg.LocalSearch)); err != nil {
+		return err
+	}
+	if _, err := metaheuristics.lookup(string(cfg.Metaheuristic)); err != nil {
+		return err
+	}
+	if cfg.PerturbationRatio <= 0 || cfg.PerturbationRatio > 1 {
+		return fmt.Errorf("%w: perturbation ratio %v out of range (0, 1]", ErrInvalidConfig, cfg.PerturbationRatio)
+	}
+	if cfg.DestroyRatio <= 0 || cfg.DestroyRatio >= 1 {
+		return fmt.Errorf("%w: destroy ratio %v out of range (0, 1)", ErrInvalidConfig, cfg.DestroyRatio)
+	}
+	if cfg.TopCandidates < 1 {
+		return fmt.Errorf("%w: number of candidates must be positive, got %d", ErrInvalidConfig, cfg.TopCandidates)
+	}
+	if cfg.LKMaxDepth < 1 {
+		return fmt.Errorf("%w: Lin-Kernighan depth must be positive, got %d", ErrInvalidConfig, cfg.LKMaxDepth)
+	}
+	if cfg.RandomWalkTime.Duration < 0 || cfg.TimeLimit.Duration < 0 {
+		return fmt.Errorf("%w: time limits cannot be negative", ErrInvalidConfig)
+	}
+	if cfg.PopulationSize < 2 {
+		return fmt.Errorf("%w: population size must be at least 2, got %d", ErrInvalidConfig, cfg.PopulationSize)
+	}
+	if cfg.Iterations < 1 {
+		return fmt.Errorf("%w: number of iterations must be positive, got %d", ErrInvalidConfig, cfg.Iterations)
+	}
+	if _, err := crossovers.lookup(string(cfg.Crossover)); err != nil {
+		return err
+	}
+	for i, island := range cfg.Islands {
+		if _, err := constructors.lookup(string(island.Heuristic)); island.Heuristic != "" && err != nil {
+			return fmt.Errorf("island %d: %w", i, err)
+		}
+		if _, err := improvers.lookup(string(island.LocalSearch)); island.LocalSearch != "" && err != nil {
+			return fmt.Errorf("island %d: %w", i, err)
+		}
+		if _, err := crossovers.lookup(string(island.Crossover)); island.Crossover != "" && err != nil {
+			return fmt.Errorf("island %d: %w", i, err)
+		}
+	}
+	if cfg.Topology != TopologyRing && cfg.Topology != TopologyFull {
+		return fmt.Errorf("%w: unknown migration topology %q (available: %s, %s)", ErrInvalidConfig, cfg.Topology, TopologyRing, TopologyFull)
+	}
+	if cfg.MigrationInterval < 1 {
+		return fmt.Errorf("%w: migration interval must be positive, got %d", ErrInvalidConfig, cfg.MigrationInterval)
+	}
+	if cfg.Migrants < 1 || cfg.Migrants >= cfg.PopulationSize {
+		return fmt.Errorf("%w: number of migrants %d out of range [1, %d)", ErrInvalidConfig, cfg.Migrants, cfg.PopulationSize)
+	}
+	if cfg.DuplicateDistance < 0 {
+		return fmt.Errorf("%w: duplicate distance cannot be negative, got %d", ErrInvalidConfig, cfg.DuplicateDistance)
+	}
+	switch cfg.Replacement {
+	case ReplacementWorst, ReplacementClosest, ReplacementBiasedFitness:
+	default:
+		return fmt.Errorf("%w: unknown replacement %q (available: %s, %s, %s)", ErrInvalidConfig, cfg.Replacement, ReplacementWorst, ReplacementClosest, ReplacementBiasedFitness)
+	}
+	if cfg.EliteSize < 0 || cfg.EliteSize > cfg.PopulationSize {
+		return fmt.Errorf("%w: elite size %d out of range [0, %d]", ErrInvalidConfig, cfg.EliteSize, cfg.PopulationSize)
+	}
+	if cfg.NumClosest < 1 {
+		return fmt.Errorf("%w: number of closest individuals must be positive, got %d", ErrInvalidConfig, cfg.NumClosest)
+	}
+	if cfg.Workers < 0 {
+		return fmt.Errorf("%w: number of workers cannot be negative, got %d", ErrInvalidConfig, cfg.Workers)
+	}
+	if cfg.MaxIterations < 0 {
+		return fmt.Errorf("%w: iteration limit cannot be negative, got %d", ErrInvalidConfig, cfg.MaxIterations)
+	}
+	return nil
+}
+
+// nowe losowe ziarno (różne od 0) - do zapisania w wynikach, żeby można było powtórzyć uruchomienie
+func NewSeed() int64 {
+	for {
+		if seed := rand.Int63(); seed != 0 {
+			return seed
+		}
+	}
+}
+
+// generator liczb losowych z ziarna cfg.Seed; przy Seed == 0 z losowego ziarna.
+// Generator nie jest bezpieczny dla wielu gorutyn - każde uruchomienie dostaje własny
+func (cfg *Config) Rand() *rand.Rand {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = NewSeed()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// kopia konfiguracji dla i-tego powtórzenia eksperymentu - kolejne powtórzenia z kolejnych ziaren cfg.Seed + i
+func (cfg *Config) Repetition(i int) *Config {
+	rep := *cfg
+	rep.Seed = cfg.Seed + int64(i)
+	return &rep
+}
+
+// kontekst metaheurystyki ograniczonej czasem - kończy się po cfg.TimeLimit albo wcześniej razem z ctx
+func (cfg *Config) withTimeLimit(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, cfg.TimeLimit.Duration)
+}
+
+// czy metaheurystyka może wykonać kolejną iterację - ctx (limit czasu, anulowanie) i opcjonalny limit iteracji
+func (cfg *Config) withinBudget(ctx context.Context, iter int) bool {
+	if cfg.MaxIterations > 0 && iter >= cfg.MaxIterations {
+		return false
+	}
+	return ctx.Err() == nil
+}
+
+// konfiguracja do użycia w punktach wejścia: nil - domyślna, w przeciwnym razie sprawdzona podana
+func resolveConfig(cfg *Config) (*Config, error) {
+	if cfg == nil {
+		return DefaultConfig(), nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
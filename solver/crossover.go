@@ -0,0 +1,408 @@
+package solver
+
+import (
+	"IMO/reader"
+	"IMO/utils"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+func init() {
+	RegisterCrossover(AlgorithmInfo{Name: string(CrossoverCommonEdges), Description: "common edges of both parents joined into chains, rest by greedy cycle"}, plainCrossover(CrossOver))
+	RegisterCrossover(AlgorithmInfo{Name: string(CrossoverEAX), Description: "edge assembly: random AB-cycle applied to the first parent, subtours merged"}, plainCrossover(EAX))
+	RegisterCrossover(AlgorithmInfo{Name: string(CrossoverGPX), Description: "partition crossover: shorter parent edges in each component of differing edges"}, plainCrossover(GPX))
+	RegisterCrossover(AlgorithmInfo{Name: string(CrossoverOX), Description: "order crossover of concatenated cycles, split by first parent cycle sizes"}, plainCrossover(OX))
+	RegisterCrossover(AlgorithmInfo{Name: string(CrossoverCommonNodes), Description: "nodes in the same cycle of both parents kept in first parent order, rest by greedy cycle"}, plainCrossover(CommonNodes))
+}
+
+// krawędź nieskierowana - mniejszy wierzchołek pierwszy
+type edgeKey [2]int
+
+func newEdgeKey(u, v int) edgeKey {
+	if u > v {
+		u, v = v, u
+	}
+	return edgeKey{u, v}
+}
+
+// drugi koniec krawędzi
+func (e edgeKey) other(v int) int {
+	if e[0] == v {
+		return e[1]
+	}
+	return e[0]
+}
+
+// krawędzie wszystkich cykli rozwiązania; cykl z 1 wierzchołkiem daje pętlę, z 2 - tę samą krawędź dwa razy
+func solutionEdges(order [][]int) []edgeKey {
+	var edges []edgeKey
+	for _, cycle := range order {
+		for j := range cycle {
+			edges = append(edges, newEdgeKey(cycle[j], utils.ElemAfter(cycle, j)))
+		}
+	}
+	return edges
+}
+
+// krawędzie obu rodziców z zaznaczonymi wspólnymi (z uwzględnieniem krotności)
+type edgeDiff struct {
+	a, b               []edgeKey // krawędzie rodzica 1 i 2
+	a_common, b_common []bool    // czy krawędź występuje też u drugiego rodzica
+}
+
+func diffEdges(p1 [][]int, p2 [][]int) *edgeDiff {
+	diff := &edgeDiff{a: solutionEdges(p1), b: solutionEdges(p2)}
+	diff.a_common = make([]bool, len(diff.a))
+	diff.b_common = make([]bool, len(diff.b))
+	b_index := make(map[edgeKey][]int, len(diff.b)) // niesparowane krawędzie rodzica 2
+	for i, e := range diff.b {
+		b_index[e] = append(b_index[e], i)
+	}
+	for i, e := range diff.a {
+		if ids := b_index[e]; len(ids) > 0 {
+			diff.a_common[i] = true
+			diff.b_common[ids[len(ids)-1]] = true
+			b_index[e] = ids[:len(ids)-1]
+		}
+	}
+	return diff
+}
+
+// listy incydencji krawędzi niewspólnych: adj[v] - indeksy krawędzi z v (pętla dwa razy)
+func differingIncidence(edges []edgeKey, common []bool, dimension int) [][]int {
+	adj := make([][]int, dimension)
+	for i, e := range edges {
+		if common[i] {
+			continue
+		}
+		adj[e[0]] = append(adj[e[0]], i)
+		adj[e[1]] = append(adj[e[1]], i)
+	}
+	return adj
+}
+
+// usunięcie jednego wystąpienia krawędzi id z listy incydencji wierzchołka v
+func removeIncidence(adj [][]int, v int, id int) {
+	for k, e := range adj[v] {
+		if e == id {
+			adj[v] = append(adj[v][:k], adj[v][k+1:]...)
+			return
+		}
+	}
+}
+
+// podcykle grafu, w którym każdy wierzchołek z krawędzią ma dokładnie 2 krawędzie; kolejność po najmniejszym wierzchołku
+func subtours(edges []edgeKey, dimension int) [][]int {
+	adj := make([][]int, dimension)
+	for i, e := range edges {
+		adj[e[0]] = append(adj[e[0]], i)
+		adj[e[1]] = append(adj[e[1]], i)
+	}
+	used := make([]bool, len(edges))
+	visited := make([]bool, dimension)
+	var tours [][]int
+	for start := range adj {
+		if visited[start] || len(adj[start]) == 0 {
+			continue
+		}
+		var tour []int
+		for v := start; ; {
+			visited[v] = true
+			tour = append(tour, v)
+			next := -1
+			for _, id := range adj[v] {
+				if !used[id] {
+					next = id
+					break
+				}
+			}
+			if next == -1 {
+				break
+			}
+			used[next] = true
+			if v = edges[next].other(v); v == start {
+				break
+			}
+		}
+		tours = append(tours, tour)
+	}
+	return tours
+}
+
+// najtańsze połączenie podcyklu tour z cyklem cycle przez wymianę krawędzi (cycle[i], cycle[i+1]) i (tour[j], tour[j+1]);
+// reversed - tour wstawiany od tour[j] wstecz
+func bestMerge(cycle []int, tour []int, distance_matrix *utils.DistanceMatrix) (int, int, bool, int) {
+	best_i, best_j, best_reversed, best_delta := -1, -1, false, math.MaxInt
+	for i := range cycle {
+		c1, c2 := cycle[i], utils.ElemAfter(cycle, i)
+		removed_c := distance_matrix.At(c1, c2)
+		for j := range tour {
+			t1, t2 := tour[j], utils.ElemAfter(tour, j)
+			removed := removed_c + distance_matrix.At(t1, t2)
+			if delta := distance_matrix.At(c1, t2) + distance_matrix.At(t1, c2) - removed; delta < best_delta {
+				best_i, best_j, best_reversed, best_delta = i, j, false, delta
+			}
+			if delta := distance_matrix.At(c1, t1) + distance_matrix.At(t2, c2) - removed; delta < best_delta {
+				best_i, best_j, best_reversed, best_delta = i, j, true, delta
+			}
+		}
+	}
+	return best_i, best_j, best_reversed, best_delta
+}
+
+// cykl po połączeniu z bestMerge
+func mergeTour(cycle []int, tour []int, i int, j int, reversed bool) []int {
+	merged := make([]int, 0, len(cycle)+len(tour))
+	merged = append(merged, cycle[:i+1]...)
+	for k := range tour {
+		if reversed {
+			merged = append(merged, tour[(j-k+len(tour))%len(tour)])
+		} else {
+			merged = append(merged, tour[(j+1+k)%len(tour)])
+		}
+	}
+	return append(merged, cycle[i+1:]...)
+}
+
+// skrócenie częściowych cykli tak, by Repair mógł je uzupełnić: najwyżej limits.Max, a gdy zabrakłoby wierzchołków
+// do minimalnych rozmiarów - najwyżej limits.Target (docelowe rozmiary sumują się do liczby wierzchołków)
+func fitToLimits(order [][]int, limits *SizeLimits) {
+	needed, available := 0, 0
+	for c := range order {
+		if len(order[c]) > limits.Max[c] {
+			order[c] = order[c][:limits.Max[c]]
+		}
+		needed += max(len(order[c]), limits.Min[c])
+		available += limits.Target[c]
+	}
+	if needed <= available {
+		return
+	}
+	for c := range order {
+		if len(order[c]) > limits.Target[c] {
+			order[c] = order[c][:limits.Target[c]]
+		}
+	}
+}
+
+// potomek z podcykli: największe podcykle stają się cyklami (cykl rodzica p1 z największą liczbą ich wierzchołków),
+// pozostałe są dołączane do cyklu o najmniejszym koszcie połączenia mieszczącego się w limits.Max,
+// a wierzchołki niemieszczących się wstawia Repair
+func assembleSubtours(tours [][]int, p1 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error) {
+	sort.SliceStable(tours, func(i, j int) bool { return len(tours[i]) > len(tours[j]) })
+	cycle_of := make([]int, distance_matrix.Dimension) // cykl wierzchołka w p1
+	for c, cycle := range p1 {
+		for _, v := range cycle {
+			cycle_of[v] = c
+		}
+	}
+	child := make([][]int, len(p1))
+	rest := tours
+	for assigned := 0; assigned < len(child) && len(rest) > 0; assigned++ {
+		overlap := make([]int, len(child))
+		for _, v := range rest[0] {
+			overlap[cycle_of[v]]++
+		}
+		best := -1
+		for c := range child {
+			if child[c] == nil && (best == -1 || overlap[c] > overlap[best]) {
+				best = c
+			}
+		}
+		child[best], rest = rest[0], rest[1:]
+	}
+	for _, tour := range rest {
+		best_c, best_i, best_j, best_reversed, best_delta := -1, -1, -1, false, math.MaxInt
+		for c := range child {
+			if len(child[c]) == 0 || len(child[c])+len(tour) > limits.Max[c] {
+				continue
+			}
+			if i, j, reversed, delta := bestMerge(child[c], tour, distance_matrix); delta < best_delta {
+				best_c, best_i, best_j, best_reversed, best_delta = c, i, j, reversed, delta
+			}
+		}
+		if best_c != -1 {
+			child[best_c] = mergeTour(child[best_c], tour, best_i, best_j, best_reversed)
+		}
+	}
+	fitToLimits(child, limits)
+	if err := Repair(child, distance_matrix, nodes, limits, rng); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// edge assembly crossover (EAX-1AB): losowy AB-cykl - naprzemienne krawędzie p1 i p2 spoza części wspólnej -
+// zastępuje w p1 swoje krawędzie p1 krawędziami p2; powstałe podcykle łączy assembleSubtours
+func EAX(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error) {
+	diff := diffEdges(p1, p2)
+	a_adj := differingIncidence(diff.a, diff.a_common, distance_matrix.Dimension)
+	b_adj := differingIncidence(diff.b, diff.b_common, distance_matrix.Dimension)
+	var starts []int // wierzchołki z krawędziami różniącymi rodziców
+	for v := range a_adj {
+		if len(a_adj[v]) > 0 {
+			starts = append(starts, v)
+		}
+	}
+	if len(starts) == 0 { // te same krawędzie - potomek równy p1
+		child := make([][]int, len(p1))
+		err := utils.CopyCycles(child, p1)
+		return child, err
+	}
+
+	// AB-cykl; w każdym wierzchołku liczba krawędzi p1 i p2 spoza części wspólnej jest równa, więc marsz się domyka
+	removed := make([]bool, len(diff.a)) // krawędzie p1 z AB-cyklu
+	var added []edgeKey                  // krawędzie p2 z AB-cyklu
+	start := starts[rng.Intn(len(starts))]
+	for v, from_p1 := start, true; ; from_p1 = !from_p1 {
+		adj, edges := b_adj, diff.b
+		if from_p1 {
+			adj, edges = a_adj, diff.a
+		}
+		if len(adj[v]) == 0 {
+			return nil, fmt.Errorf("%w: AB-cycle broken at node %d", ErrInfeasible, v)
+		}
+		id := adj[v][rng.Intn(len(adj[v]))]
+		w := edges[id].other(v)
+		removeIncidence(adj, v, id)
+		removeIncidence(adj, w, id)
+		if from_p1 {
+			removed[id] = true
+		} else {
+			added = append(added, edges[id])
+		}
+		if v = w; !from_p1 && v == start {
+			break
+		}
+	}
+
+	child_edges := added
+	for i, e := range diff.a {
+		if !removed[i] {
+			child_edges = append(child_edges, e)
+		}
+	}
+	return assembleSubtours(subtours(child_edges, distance_matrix.Dimension), p1, distance_matrix, nodes, limits, rng)
+}
+
+// partition crossover (GPX): w każdej spójnej składowej grafu krawędzi różniących rodziców wybierane są krawędzie
+// rodzica o mniejszej sumie długości (remis - p1); z krawędziami wspólnymi dają podcykle łączone przez assembleSubtours
+func GPX(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error) {
+	diff := diffEdges(p1, p2)
+	component := make([]int, distance_matrix.Dimension) // union-find składowych
+	for v := range component {
+		component[v] = v
+	}
+	var find func(v int) int
+	find = func(v int) int {
+		if component[v] != v {
+			component[v] = find(component[v])
+		}
+		return component[v]
+	}
+	union := func(edges []edgeKey, common []bool) {
+		for i, e := range edges {
+			if !common[i] {
+				component[find(e[0])] = find(e[1])
+			}
+		}
+	}
+	union(diff.a, diff.a_common)
+	union(diff.b, diff.b_common)
+
+	length := func(edges []edgeKey, common []bool) []int { // długość krawędzi rodzica w składowych
+		lengths := make([]int, distance_matrix.Dimension)
+		for i, e := range edges {
+			if !common[i] {
+				lengths[find(e[0])] += distance_matrix.At(e[0], e[1])
+			}
+		}
+		return lengths
+	}
+	a_len, b_len := length(diff.a, diff.a_common), length(diff.b, diff.b_common)
+
+	var child_edges []edgeKey
+	for i, e := range diff.a {
+		if diff.a_common[i] || a_len[find(e[0])] <= b_len[find(e[0])] {
+			child_edges = append(child_edges, e)
+		}
+	}
+	for i, e := range diff.b {
+		if !diff.b_common[i] && b_len[find(e[0])] < a_len[find(e[0])] {
+			child_edges = append(child_edges, e)
+		}
+	}
+	return assembleSubtours(subtours(child_edges, distance_matrix.Dimension), p1, distance_matrix, nodes, limits, rng)
+}
+
+// order crossover (OX) cykli połączonych w jedną trasę: losowy fragment trasy p1 zostaje na swoich pozycjach,
+// pozostałe pozycje (od końca fragmentu) wypełniają wierzchołki w kolejności z trasy p2; podział na cykle o rozmiarach z p1
+func OX(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error) {
+	var tour1, tour2 []int
+	for c := range p1 {
+		tour1 = append(tour1, p1[c]...)
+	}
+	for c := range p2 {
+		tour2 = append(tour2, p2[c]...)
+	}
+	if len(tour1) != len(tour2) {
+		return nil, fmt.Errorf("%w: parents with %d and %d nodes", ErrInfeasible, len(tour1), len(tour2))
+	}
+	i, j, err := utils.Pick2RandomValues(len(tour1), rng)
+	if err != nil {
+		return nil, err
+	}
+	i, j = min(i, j), max(i, j)
+
+	tour := make([]int, len(tour1))
+	in_tour := make([]bool, distance_matrix.Dimension)
+	copy(tour[i:j+1], tour1[i:j+1])
+	for _, v := range tour1[i : j+1] {
+		in_tour[v] = true
+	}
+	position := (j + 1) % len(tour)
+	for k := range tour2 {
+		v := tour2[(j+1+k)%len(tour2)]
+		if in_tour[v] {
+			continue
+		}
+		tour[position] = v
+		in_tour[v] = true
+		position = (position + 1) % len(tour)
+	}
+
+	child := make([][]int, len(p1))
+	offset := 0
+	for c := range p1 {
+		child[c] = tour[offset : offset+len(p1[c]) : offset+len(p1[c])]
+		offset += len(p1[c])
+	}
+	return child, nil
+}
+
+// wierzchołki przypisane w obu rodzicach do tego samego cyklu zostają w nim w kolejności z p1, pozostałe wstawia Repair
+func CommonNodes(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error) {
+	cycle_of := make([]int, distance_matrix.Dimension) // cykl wierzchołka w p2
+	for v := range cycle_of {
+		cycle_of[v] = -1
+	}
+	for c, cycle := range p2 {
+		for _, v := range cycle {
+			cycle_of[v] = c
+		}
+	}
+	child := make([][]int, len(p1))
+	for c, cycle := range p1 {
+		for _, v := range cycle {
+			if cycle_of[v] == c {
+				child[c] = append(child[c], v)
+			}
+		}
+	}
+	if err := Repair(child, distance_matrix, nodes, limits, rng); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
@@ -0,0 +1,190 @@
+package solver
+
+import (
+	"IMO/reader"
+	"IMO/utils"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+type crossoverFunc func(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error)
+
+// potomek musi być podziałem wszystkich wierzchołków na cykle w granicach limits
+func checkPartition(t *testing.T, child [][]int, num_nodes int, limits *SizeLimits) {
+	t.Helper()
+	if err := limits.Check(child); err != nil {
+		t.Fatalf("child %v: %v", child, err)
+	}
+	seen := make([]bool, num_nodes)
+	for c, cycle := range child {
+		for _, v := range cycle {
+			if v < 0 || v >= num_nodes || seen[v] {
+				t.Fatalf("child %v: node %d in cycle %d out of range or repeated", child, v, c)
+			}
+			seen[v] = true
+		}
+	}
+	if i := slices.Index(seen, false); i != -1 {
+		t.Fatalf("child %v: node %d missing", child, i)
+	}
+}
+
+func TestCrossoverPartition(t *testing.T) {
+	limits, err := NewSizeLimits(30, []int{10, 10, 10}, 3, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		crossover crossoverFunc
+	}{
+		{"EAX", EAX},
+		{"GPX", GPX},
+		{"OX", OX},
+		{"CommonNodes", CommonNodes},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				nodes, distance_matrix := testInstance(t, 30, rng)
+				p1 := randomOrder([]int{12, 9, 9}, rng)
+				p2 := randomOrder([]int{8, 11, 11}, rng)
+				child, err := tc.crossover(p1, p2, distance_matrix, nodes, limits, rng)
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				checkPartition(t, child, 30, limits)
+			}
+		})
+	}
+}
+
+// krzyżowanie identycznych rodziców daje tego samego rodzica (z dokładnością do obrotu i kierunku cykli)
+func TestCrossoverIdenticalParents(t *testing.T) {
+	limits, err := NewSizeLimits(20, []int{10, 10}, 2, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		crossover crossoverFunc
+	}{
+		{"EAX", EAX},
+		{"GPX", GPX},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 10; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				nodes, distance_matrix := testInstance(t, 20, rng)
+				p1 := randomOrder([]int{11, 9}, rng)
+				child, err := tc.crossover(p1, cloneOrder(p1), distance_matrix, nodes, limits, rng)
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				if len(child) != len(p1) {
+					t.Fatalf("seed %d: child has %d cycles, expected %d", seed, len(child), len(p1))
+				}
+				for c := range p1 {
+					if !slices.Equal(canonicalCycle(child[c]), canonicalCycle(p1[c])) {
+						t.Errorf("seed %d: cycle %d is %v, expected %v", seed, c, child[c], p1[c])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSubtours(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		edges     []edgeKey
+		dimension int
+		expected  [][]int
+	}{
+		{
+			name:      "single cycle",
+			edges:     []edgeKey{{0, 2}, {1, 2}, {1, 3}, {0, 3}},
+			dimension: 4,
+			expected:  [][]int{{0, 2, 1, 3}},
+		},
+		{
+			name:      "two cycles and isolated node",
+			edges:     []edgeKey{{4, 5}, {0, 1}, {5, 6}, {1, 2}, {3, 6}, {0, 2}, {3, 4}},
+			dimension: 8,
+			expected:  [][]int{{0, 1, 2}, {3, 4, 5, 6}},
+		},
+		{
+			name:      "two-node cycle",
+			edges:     []edgeKey{{1, 3}, {1, 3}, {0, 2}, {2, 4}, {0, 4}},
+			dimension: 5,
+			expected:  [][]int{{0, 2, 4}, {1, 3}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tours := subtours(tc.edges, tc.dimension)
+			if len(tours) != len(tc.expected) {
+				t.Fatalf("got %v, expected %v", tours, tc.expected)
+			}
+			for i := range tours {
+				if !slices.Equal(canonicalCycle(tours[i]), tc.expected[i]) {
+					t.Errorf("subtour %d: got %v, expected %v", i, tours[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeTour(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cycle    []int
+		tour     []int
+		i, j     int
+		reversed bool
+		expected []int
+	}{
+		{"forward", []int{0, 1, 2, 3}, []int{4, 5, 6}, 1, 0, false, []int{0, 1, 5, 6, 4, 2, 3}},
+		{"reversed", []int{0, 1, 2, 3}, []int{4, 5, 6}, 1, 0, true, []int{0, 1, 4, 6, 5, 2, 3}},
+		{"after last", []int{0, 1, 2}, []int{3, 4}, 2, 1, false, []int{0, 1, 2, 3, 4}},
+		{"wrapping tour", []int{0, 1, 2}, []int{3, 4, 5}, 0, 2, true, []int{0, 5, 4, 3, 1, 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeTour(tc.cycle, tc.tour, tc.i, tc.j, tc.reversed)
+			if !slices.Equal(merged, tc.expected) {
+				t.Errorf("got %v, expected %v", merged, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAssembleSubtours(t *testing.T) {
+	limits, err := NewSizeLimits(12, []int{6, 6}, 2, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1 := [][]int{{6, 7, 8, 9, 10, 11}, {0, 1, 2, 3, 4, 5}}
+	for _, tc := range []struct {
+		name  string
+		tours [][]int
+	}{
+		{"cycles of parent", [][]int{{0, 1, 2, 3, 4, 5}, {6, 7, 8, 9, 10, 11}}},
+		{"subtours to merge", [][]int{{0, 1, 2, 3}, {6, 7, 8}, {4, 5, 9}, {10, 11}}},
+		{"too large subtour", [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {10, 11}}},
+		{"missing nodes", [][]int{{0, 1, 2}, {6, 7, 8}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			nodes, distance_matrix := testInstance(t, 12, rng)
+			child, err := assembleSubtours(cloneOrder(tc.tours), p1, distance_matrix, nodes, limits, rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPartition(t, child, 12, limits)
+			// największy podcykl trafia do cyklu p1, z którym ma najwięcej wierzchołków
+			if !slices.Contains(child[1], 0) {
+				t.Errorf("child %v: subtour with node 0 not in cycle 1", child)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package solver
+
+import (
+	"IMO/utils"
+	"math/rand"
+	"testing"
+)
+
+// delta każdego ruchu musi być równa różnicy długości cykli przeliczonych po jego wykonaniu
+func checkDeltas(t *testing.T, name string, distance_matrix *utils.DistanceMatrix, order [][]int, moves []Move) {
+	t.Helper()
+	before := utils.CalculateCyclesLen(order, distance_matrix)
+	for _, move := range moves {
+		tour := NewTour(cloneOrder(order))
+		if err := move.ExecuteMove(tour); err != nil {
+			t.Fatalf("%s: %T%+v: %v", name, move, move, err)
+		}
+		after := utils.CalculateCyclesLen(tour.Order, distance_matrix)
+		if after-before != move.GetDelta() {
+			t.Errorf("%s: %T%+v: delta %d, recomputed %d", name, move, move, move.GetDelta(), after-before)
+		}
+	}
+}
+
+// ruchy z wycinka wartości jako lista ruchów
+func asMoves[T any, P interface {
+	*T
+	Move
+}](moves []T) []Move {
+	result := make([]Move, len(moves))
+	for m := range moves {
+		result[m] = P(&moves[m])
+	}
+	return result
+}
+
+func TestMoveDeltas(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		sizes     []int
+		tolerance int
+	}{
+		{"minimal cycles", []int{3, 4, 5}, 2},
+		{"two cycles", []int{6, 6}, 3},
+		{"fixed sizes", []int{5, 5, 4}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			num_nodes := 0
+			for _, s := range tc.sizes {
+				num_nodes += s
+			}
+			limits, err := NewSizeLimits(num_nodes, tc.sizes, tc.tolerance, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for seed := int64(1); seed <= 5; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				_, distance_matrix := testInstance(t, num_nodes, rng)
+				order := randomOrder(tc.sizes, rng)
+				tour := NewTour(cloneOrder(order))
+				candidates, err := CalculateCandidates(distance_matrix, 4)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// ruchy bez dystansów z deltą liczoną przez CalculateDelta
+				moves, err := AllMovesNoDistance(order, limits, true, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				node_moves, err := AllMovesNoDistance(order, limits, false, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, move := range append(moves, node_moves...) {
+					CalculateDelta(move, distance_matrix, order)
+				}
+				checkDeltas(t, "no distance", distance_matrix, order, moves)
+				checkDeltas(t, "no distance nodes", distance_matrix, order, node_moves)
+
+				// ruchy przeszukiwania stromego
+				distances_before := DistancesBefore(distance_matrix, order)
+				swaps, err := AllMovesBetweenCycles(distance_matrix, order, distances_before)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkDeltas(t, "swap", distance_matrix, order, asMoves(swaps))
+				checkDeltas(t, "relocate", distance_matrix, order, asMoves(AllRelocateMoves(distance_matrix, order, limits, distances_before)))
+				for c := range order {
+					checkDeltas(t, "nodes", distance_matrix, order, asMoves(AllMovesNodesCycle(distance_matrix, order[c], c, distances_before[c])))
+					checkDeltas(t, "edges", distance_matrix, order, asMoves(AllMovesEdgesCycle(distance_matrix, order[c], c)))
+				}
+
+				// ruchy z pamięcią wierzchołków
+				swap_details, err := BestMovesBetweenCycles(distance_matrix, order, distances_before)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkDeltas(t, "swap detail", distance_matrix, order, asMoves(swap_details))
+				checkDeltas(t, "relocate detail", distance_matrix, order, asMoves(BestRelocateMoves(distance_matrix, tour, limits)))
+				for c := range order {
+					checkDeltas(t, "edges detail", distance_matrix, order, asMoves(BestMovesEdgesCycle(distance_matrix, order[c], c)))
+				}
+				checkDeltas(t, "or-opt detail", distance_matrix, order, asMoves(BestOrOptMoves(distance_matrix, tour, limits)))
+
+				// ruchy kandydackie
+				candidate_moves, err := AllCandidateMoves(distance_matrix, tour, candidates, limits)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkDeltas(t, "candidate", distance_matrix, order, candidate_moves)
+				checkDeltas(t, "candidate or-opt", distance_matrix, order, AllCandidateOrOptMoves(distance_matrix, tour, candidates, limits))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package solver
+
+import (
+	"math"
+	"slices"
+)
+
+// różnorodność populacji HAE - średnie po parach osobników; 1 we wszystkich miarach zgodności - populacja
+// jednego rozwiązania
+type Diversity struct {
+	SharedEdgeRatio float64 `json:"shared edge ratio"` // średni udział krawędzi wspólnych pary osobników
+	NodeAgreement   float64 `json:"node agreement"`    // średni udział wierzchołków w tych samych cyklach pary po dopasowaniu etykiet
+	EdgeEntropy     float64 `json:"edge entropy"`      // entropia częstości krawędzi w populacji: 0 - same kopie, 1 - osobniki bez wspólnych krawędzi
+}
+
+// miary różnorodności populacji; mniej niż 2 osobniki - zerowa entropia i pełna zgodność
+func PopulationDiversity(population [][][]int) Diversity {
+	diversity := Diversity{SharedEdgeRatio: 1, NodeAgreement: 1}
+	if len(population) < 2 {
+		return diversity
+	}
+	pairs := 0
+	diversity.SharedEdgeRatio, diversity.NodeAgreement = 0, 0
+	for i := range population {
+		for j := i + 1; j < len(population); j++ {
+			_, overlap := alignParents(population[i], population[j], true)
+			diversity.SharedEdgeRatio += edgeSimilarity(population[i], population[j])
+			diversity.NodeAgreement += overlap.NodeRatio
+			pairs++
+		}
+	}
+	diversity.SharedEdgeRatio /= float64(pairs)
+	diversity.NodeAgreement /= float64(pairs)
+	diversity.EdgeEntropy = edgeEntropy(population)
+	return diversity
+}
+
+// udział krawędzi a obecnych też w b
+func edgeSimilarity(a [][]int, b [][]int) float64 {
+	num_edges := 0
+	for _, cycle := range a {
+		num_edges += len(cycle)
+	}
+	if num_edges == 0 {
+		return 1
+	}
+	return 1 - float64(EdgeDistance(a, b))/float64(num_edges)
+}
+
+// entropia rozkładu krawędzi wszystkich osobników, przeskalowana z [ln n, ln(P*n)] do [0, 1]
+// (n - krawędzie osobnika, P - rozmiar populacji)
+func edgeEntropy(population [][][]int) float64 {
+	counts := make(map[edgeKey]int)
+	total := 0
+	for _, member := range population {
+		for _, e := range solutionEdges(member) {
+			counts[e]++
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		q := float64(count) / float64(total)
+		entropy -= q * math.Log(q)
+	}
+	per_member := float64(total) / float64(len(population))
+	return max(0, (entropy-math.Log(per_member))/math.Log(float64(len(population))))
+}
+
+// osobnik usuwany przy wymianie biased fitness (HGS, Vidal i in. 2012) spośród costs (koszty populacji
+// i potomka na końcu) o odległościach distances[i][j]: BF = ranga kosztu + (1 - elite/n) * ranga wkładu
+// w różnorodność (średnia odległość od num_closest najbliższych). Najlepszy osobnik nie jest usuwany;
+// przy remisie BF usuwany droższy
+func biasedFitnessVictim(costs []int, distances [][]int, elite int, num_closest int) int {
+	n := len(costs)
+	contribution := make([]float64, n)
+	for i := range n {
+		others := make([]int, 0, n-1)
+		for j := range n {
+			if j != i {
+				others = append(others, distances[i][j])
+			}
+		}
+		slices.Sort(others)
+		closest := others[:min(num_closest, len(others))]
+		for _, d := range closest {
+			contribution[i] += float64(d)
+		}
+		contribution[i] /= float64(len(closest))
+	}
+	cost_rank := ranks(n, func(a, b int) bool { return costs[a] < costs[b] })
+	diversity_rank := ranks(n, func(a, b int) bool { return contribution[a] > contribution[b] })
+	weight := 1 - float64(elite)/float64(n)
+	victim, victim_fitness := -1, 0.0
+	for i := range n {
+		if cost_rank[i] == 0 {
+			continue // najlepszy zostaje
+		}
+		fitness := float64(cost_rank[i]) + weight*float64(diversity_rank[i])
+		if victim == -1 || fitness > victim_fitness || (fitness == victim_fitness && cost_rank[i] > cost_rank[victim]) {
+			victim, victim_fitness = i, fitness
+		}
+	}
+	return victim
+}
+
+// ranga każdego z n elementów w porządku less; remisy według indeksu
+func ranks(n int, less func(a, b int) bool) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		}
+		return 0
+	})
+	rank := make([]int, n)
+	for r, i := range order {
+		rank[i] = r
+	}
+	return rank
+}
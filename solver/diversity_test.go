@@ -0,0 +1,91 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// cykl Hamiltona i, i+step, i+2*step, ... na n wierzchołkach (n pierwsze - cykle o różnych step <= n/2 są rozłączne krawędziowo)
+func stepCycle(n int, step int) []int {
+	cycle := make([]int, n)
+	for i := range cycle {
+		cycle[i] = i * step % n
+	}
+	return cycle
+}
+
+func TestEdgeEntropy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	order := randomOrder([]int{6, 5}, rng)
+	for _, tc := range []struct {
+		name       string
+		population [][][]int
+		expected   float64
+	}{
+		{"identical", [][][]int{order, cloneOrder(order), cloneOrder(order), cloneOrder(order)}, 0},
+		{"identical up to rotation and labels", [][][]int{order, {slices.Concat(order[1][2:], order[1][:2]), order[0]}}, 0},
+		{"disjoint", [][][]int{{stepCycle(7, 1)}, {stepCycle(7, 2)}, {stepCycle(7, 3)}}, 1},
+		{"disjoint pair", [][][]int{{stepCycle(11, 1)}, {stepCycle(11, 4)}}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := edgeEntropy(tc.population); math.Abs(got-tc.expected) > 1e-9 {
+				t.Errorf("got %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		population := make([][][]int, 2+rng.Intn(5))
+		for i := range population {
+			population[i] = randomOrder([]int{6, 5}, rng)
+		}
+		if got := edgeEntropy(population); got < 0 || got > 1+1e-9 {
+			t.Errorf("seed %d: entropy %v outside [0, 1]", seed, got)
+		}
+	}
+}
+
+func TestBiasedFitnessVictim(t *testing.T) {
+	// najlepszy osobnik (0) jest najbliżej pozostałych - najgorszy wkład w różnorodność
+	costs := []int{10, 20, 30, 40}
+	distances := [][]int{
+		{0, 1, 1, 1},
+		{1, 0, 9, 9},
+		{1, 9, 0, 9},
+		{1, 9, 9, 0},
+	}
+	for elite := 0; elite <= len(costs); elite++ {
+		if victim := biasedFitnessVictim(costs, distances, elite, 2); victim == 0 {
+			t.Errorf("elite %d: best individual removed", elite)
+		}
+	}
+
+	for seed := int64(1); seed <= 100; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		n := 2 + rng.Intn(8)
+		costs := make([]int, n)
+		distances := make([][]int, n)
+		for i := range n {
+			costs[i] = rng.Intn(5) // remisy kosztów
+			distances[i] = make([]int, n)
+		}
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				distances[i][j] = rng.Intn(10)
+				distances[j][i] = distances[i][j]
+			}
+		}
+		best := 0
+		for i := range costs {
+			if costs[i] < costs[best] {
+				best = i
+			}
+		}
+		victim := biasedFitnessVictim(costs, distances, rng.Intn(n+1), 1+rng.Intn(n))
+		if victim < 0 || victim >= n || victim == best {
+			t.Errorf("seed %d: victim %d of costs %v (best %d)", seed, victim, costs, best)
+		}
+	}
+}
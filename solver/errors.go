@@ -0,0 +1,14 @@
+package solver
+
+import (
+	"IMO/utils"
+	"errors"
+)
+
+// rodzaje błędów pakietu - sprawdzane przez errors.Is; zwracane błędy opakowują je (%w) z kontekstem
+var (
+	ErrInvalidInstance  = utils.ErrInvalidInstance            // ten sam błąd co w utils - instancja lub macierz odległości
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")     // brak zarejestrowanej heurystyki, przeszukiwania lub metaheurystyki
+	ErrInvalidConfig    = errors.New("invalid configuration") // niepoprawne parametry Config lub ograniczeń rozmiarów
+	ErrInfeasible       = errors.New("infeasible")            // nie da się zbudować lub poprawić rozwiązania spełniającego ograniczenia
+)
@@ -3,11 +3,20 @@ package solver
 import (
 	"IMO/reader"
 	"IMO/utils"
+	"context"
 	"fmt"
 	"math"
-	"time"
+	"math/rand"
+	"slices"
 )
 
+func init() {
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicHAE), Description: "hybrid evolutionary algorithm", Parameters: []string{"heuristic", "local_search", "population_size", "crossover", "align_cycles", "duplicate_distance", "replacement", "elite_size", "num_closest", "time_limit"}, TimeBounded: true}, HAEWithoutLS)
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicHAEWithLS), Description: "hybrid evolutionary algorithm with local search of offspring", Parameters: []string{"heuristic", "local_search", "population_size", "crossover", "align_cycles", "duplicate_distance", "replacement", "elite_size", "num_closest", "time_limit"}, TimeBounded: true}, HAEWithLS)
+}
+
+// identyczne cykle element po elemencie - zależne od obrotu, kierunku i etykiet cykli; te same rozwiązania
+// niezależnie od zapisu rozpoznaje EdgeDistance == 0
 func SameSolution[T comparable](s1 [][]T, s2 [][]T) bool {
 	for i := 0; i < len(s1); i++ {
 		for j := 0; j < len(s1[i]); j++ {
@@ -19,60 +28,74 @@ func SameSolution[T comparable](s1 [][]T, s2 [][]T) bool {
 	return true
 }
 
-func CreateStartPopulation(distance_matrix [][]int, nodes []reader.Node, population_size int, heuristic_algorithm string, local_search_algorithm string) ([][][]int, []int) {
+func CreateStartPopulation(ctx context.Context, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) ([][][]int, []int, error) {
+	return createStartPopulation(ctx, distance_matrix, nodes, cfg, limits, rng, newProgress(cfg))
+}
+
+// populacja startowa posortowana rosnąco po koszcie; nowe rozwiązania i osobniki zgłaszane przez progress
+func createStartPopulation(ctx context.Context, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand, progress *progress) ([][][]int, []int, error) {
+	construct, local_search, err := metaheuristicFuncs(cfg, rng)
+	if err != nil {
+		return nil, nil, err
+	}
 	var (
 		population            [][][]int // eltarna
 		population_cycles_len []int     // długości cykli
+		population_hashes     []uint64  // skróty postaci kanonicznych
+		rejected              int       // kolejne odrzucone duplikaty
 	)
 
 	// 1. Stworzenie populacji elitarnej
-	for i := 0; i < population_size; i++ {
-		start_order, err := Solve(nodes, heuristic_algorithm, distance_matrix) // domyślnie Random
+	for i := 0; i < cfg.PopulationSize; i++ {
+		if i > 0 && ctx.Err() != nil {
+			break // przerwane przez ctx - populacja niepełna, ale niepusta
+		}
+		ls_order := NewOrder(limits.Target)
+		err := construct(distance_matrix, ls_order, nodes) // domyślnie Random
 		if err != nil {
-			panic("Error")
+			return nil, nil, fmt.Errorf("population member %d: %w", i, err)
 		}
-		ls_order, err := Local_search(start_order, local_search_algorithm, distance_matrix) // lokalne wyszukiwanie; domyślnie SteepestEdge
+		progress.restart(0, utils.CalculateCyclesLen(ls_order, distance_matrix), ls_order)
+		err = local_search(ctx, distance_matrix, ls_order, limits) // lokalne wyszukiwanie; domyślnie SteepestEdge
 		if err != nil {
-			panic("Error")
+			return nil, nil, fmt.Errorf("population member %d: local search: %w", i, err)
 		}
-		cycle_len := utils.CalculateCycleLen(ls_order[0], distance_matrix) + utils.CalculateCycleLen(ls_order[1], distance_matrix)
+		cycle_len := utils.CalculateCyclesLen(ls_order, distance_matrix)
 		index_better := utils.IndexBetterInSortedArray(population_cycles_len[:i], cycle_len)
 		if index_better == -1 {
 			index_better = i
 		}
-
-		// // sprawdzenie czy jest rozwiązanie - ta sama suma dł. cyklów, oraz te same wartości
-		// for prev_index:=index_better-1; prev_index >= 0 && cycle_len == population_cycles_len[prev_index];{
-		// 	if SameSolution(ls_order, population[prev_index]) == true {
-		// 		// to samo rozwiązanie już jest
-
-		// 	}
-		// }
-		if prev_index := index_better - 1; prev_index >= 0 && cycle_len == population_cycles_len[prev_index] {
-			// ta sama suma cykli - najpewniej to samo rozwiązanie, ciężko o taką samą dł. cykli przy różnych rozwiązaniach
-			// wygeneruj nowe
+		hash := SolutionHash(ls_order)
+		if isDuplicate(population, population_hashes, ls_order, hash, cfg.DuplicateDistance) && rejected < maxDuplicateRejections {
+			// to samo lub zbyt podobne rozwiązanie już jest - wygeneruj nowe
+			rejected++
 			i--
 			continue
 		}
+		rejected = 0
 
-		if index_better == i {
-			population = append(population, ls_order)                        // dodanie do populacji
-			population_cycles_len = append(population_cycles_len, cycle_len) // dodanie do długości cykli
-		} else {
-			population_cycles_len = utils.Insert(population_cycles_len, index_better, cycle_len)
-			population = utils.Insert(population, index_better, ls_order)
+		// dodanie do populacji i długości cykli; index_better == i - na końcu
+		if population, err = utils.Insert(population, index_better, ls_order); err != nil {
+			return nil, nil, err
+		}
+		if population_cycles_len, err = utils.Insert(population_cycles_len, index_better, cycle_len); err != nil {
+			return nil, nil, err
 		}
+		if population_hashes, err = utils.Insert(population_hashes, index_better, hash); err != nil {
+			return nil, nil, err
+		}
+		progress.population(0, index_better, population_cycles_len, population)
 	}
 
-	return population, population_cycles_len
+	return population, population_cycles_len, nil
 }
 
-func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.Node) ([][]int, error) {
+func CrossOver(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) ([][]int, error) {
 	var (
 		crossed_order     [][]int    = make([][]int, len(p1))
 		adjacency_matrix1 [][]bool                               // macierze sąsiedztwa dla p1
 		adjacency_matrix2 [][]bool                               // macierze sąsiedztwa dla p2
-		adjacency_crossed [][][]bool = make([][][]bool, len(p1)) // macierze sąsiedztwa połączone (2 cykle)
+		adjacency_crossed [][][]bool = make([][][]bool, len(p1)) // macierze sąsiedztwa połączone (dla każdego cyklu)
 	)
 	// return crossed_order, nil
 
@@ -90,25 +113,29 @@ func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.N
 	// ostatecznie z pojedynczego łańcuch tworzymy cykl - teraz to do GreedyCycle razem z wierzchołkami z 0
 
 	for i := 0; i < len(p1); i++ { // iteracja po cyklach
-		adjacency_matrix1 = make([][]bool, len(p1[i])+len(p2[i]))
-		adjacency_matrix2 = make([][]bool, len(p1[i])+len(p2[i]))
+		adjacency_matrix1 = make([][]bool, distance_matrix.Dimension)
+		adjacency_matrix2 = make([][]bool, distance_matrix.Dimension)
 		for j := 0; j < len(adjacency_matrix1); j++ { // iteracja po wierzchołkach
 			adjacency_matrix1[j] = make([]bool, len(adjacency_matrix1))
 			adjacency_matrix2[j] = make([]bool, len(adjacency_matrix1))
 		}
-		for j := 0; j < len(p1[i]); j++ {
-			n1 := p1[i][j] // rodzic 1
+		for j := 0; j < len(p1[i]); j++ { // rodzic 1
+			n1 := p1[i][j]
 			n2 := utils.ElemAfter(p1[i], j)
 			adjacency_matrix1[n1][n2] = true
 			adjacency_matrix1[n2][n1] = true
-
-			n1 = p2[i][j] // rodzic 2
-			n2 = utils.ElemAfter(p2[i], j)
+		}
+		for j := 0; j < len(p2[i]); j++ { // rodzic 2
+			n1 := p2[i][j]
+			n2 := utils.ElemAfter(p2[i], j)
 			adjacency_matrix2[n1][n2] = true
 			adjacency_matrix2[n2][n1] = true
 		}
 		// połączenie macierzy sąsiedztwa rodziców
-		adjacency_AND := utils.MatrixLogicAND(adjacency_matrix1, adjacency_matrix2) // macierz sąsiedztwa połączona
+		adjacency_AND, err := utils.MatrixLogicAND(adjacency_matrix1, adjacency_matrix2) // macierz sąsiedztwa połączona
+		if err != nil {
+			return nil, err
+		}
 		adjacency_crossed[i] = adjacency_AND
 	}
 
@@ -140,9 +167,11 @@ func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.N
 			nr_neighbors_count[nr_neighbors_node[u]]++
 		}
 
-		if nr_neighbors_count[1] == 0 { // ten sam cykl w obu rodzicach
-			copy(crossed_order[i], p1[i])
-			continue
+		if nr_neighbors_count[1] == 0 {
+			if nr_neighbors_count[2] > 0 { // ten sam cykl w obu rodzicach
+				crossed_order[i] = append(crossed_order[i], p1[i]...)
+			}
+			continue // brak wspólnych krawędzi - cykl zbudowany od nowa w Repair
 		}
 
 		// łączenie łańcuchów
@@ -187,10 +216,10 @@ func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.N
 			join_end := false
 			for j := 1; j < len(chains); j++ {
 				// sprawdzenie odległości między początkiem/końcem chain 0 a początkiem/końcem chain j
-				distance_start_start := distance_matrix[start][chains[j][0]]
-				distance_end_start := distance_matrix[end][chains[j][0]]
-				distance_start_end := distance_matrix[start][chains[j][len(chains[j])-1]]
-				distance_end_end := distance_matrix[end][chains[j][len(chains[j])-1]]
+				distance_start_start := distance_matrix.At(start, chains[j][0])
+				distance_end_start := distance_matrix.At(end, chains[j][0])
+				distance_start_end := distance_matrix.At(start, chains[j][len(chains[j])-1])
+				distance_end_end := distance_matrix.At(end, chains[j][len(chains[j])-1])
 
 				// wszystkie możliwości po kolei O(n) a nie O(log(n)) jakby można zrobić ale tylko 4 przypadki więc spoko
 				update := func(d, idx int, je, ze bool) {
@@ -208,7 +237,7 @@ func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.N
 			}
 
 			if min_index == -1 {
-				return nil, fmt.Errorf("błąd: nie znaleziono indeksu")
+				return nil, fmt.Errorf("%w: no chain to join in cycle %d", ErrInfeasible, i)
 			}
 			if !zero_end {
 				// odwróć chain 0
@@ -232,7 +261,7 @@ func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.N
 	}
 
 	// naprawa cyklu
-	err := Repair(crossed_order, distance_matrix, nodes)
+	err := Repair(crossed_order, distance_matrix, nodes, limits, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -240,147 +269,225 @@ func CrossOver(p1 [][]int, p2 [][]int, distance_matrix [][]int, nodes []reader.N
 	return crossed_order, nil
 }
 
-func HAEWithoutLS(distance_matrix [][]int, order [][]int, nodes []reader.Node, time_limit int, heuristic_algorithm string, local_search_algorithm string, population_size int) (int, error) {
-	var (
-		iter                  int                        // wykonane iteracje
-		population            [][][]int                  // eltarna
-		population_cycles_len []int                      // długości cykli
-		start_time            time.Time     = time.Now() // czas rozpoczęcia algorytmu
-		elapsed               time.Duration              // czas od rozpoczęnia algorytmu
-		time_limit_reached    bool
-	)
+func HAEWithoutLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
+	return runHAE(ctx, distance_matrix, order, nodes, cfg, limits, rng, false)
+}
+
+func HAEWithLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
+	return runHAE(ctx, distance_matrix, order, nodes, cfg, limits, rng, true)
+}
+
+// HAE z jedną populacją; with_ls - lokalne przeszukiwanie potomków
+func runHAE(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand, with_ls bool) (int, error) {
+	iter := 0                             // wykonane iteracje
+	ctx, cancel := cfg.withTimeLimit(ctx) // limit czasu obejmuje tworzenie populacji
+	defer cancel()
+	progress := newProgress(cfg) // zdarzenia dla cfg.Observer
 
 	// 1. Stworzenie populacji elitarnej
-	population, population_cycles_len = CreateStartPopulation(distance_matrix, nodes, population_size, heuristic_algorithm, local_search_algorithm)
-	var (
-		p1, p2           [][]int                                               // rodzice
-		used_parents     map[string]utils.Empty = make(map[string]utils.Empty) // Mapa przechowująca użyte kombinacje rodziców
-		num_used_parents int                    = 0
-		max_combinations int                    = population_size * (population_size - 1) / 2 // maksymalna liczba kombinacji rodziców
-	)
-	if len(population) != len(population_cycles_len) && len(population) != population_size {
-		return iter, fmt.Errorf("błąd: populacja nie jest tej samej długości co długości cykli")
+	hae, err := newHAEState(ctx, distance_matrix, nodes, cfg, limits, rng, with_ls, progress)
+	if err != nil {
+		return iter, err
 	}
 
-	// główna pętla algorytmu
-	for time_limit_reached = false; !time_limit_reached && max_combinations != num_used_parents; {
-		// 2 losowi rodzice z populacji
-		i1, i2, _ := utils.Pick2RandomValues(population_size)
-		p1, p2 = population[i1], population[i2]
-		// jak rodzice byli sprawdzani to ich nie sprawdzaj ponownie
-		key := fmt.Sprintf("%d-%d", min(i1, i2), max(i1, i2))
-		if _, exists := used_parents[key]; exists {
-			continue
-		}
-		used_parents[key] = utils.Empty{}
-		num_used_parents++
-
-		// krzyżowanie rodziców
-		new_order, err := CrossOver(p1, p2, distance_matrix, nodes)
+	// główna pętla algorytmu; populacja niepełna tylko po zakończeniu ctx w trakcie jej tworzenia
+	for running := hae.full(); running && !hae.converged(); {
+		new_order, len_new_order, ok, err := hae.step(ctx, iter)
 		if err != nil {
 			return iter, err
 		}
-		len_new_order := utils.CalculateCycleLen(new_order[0], distance_matrix) + utils.CalculateCycleLen(new_order[1], distance_matrix)
-
-		if len_new_order < population_cycles_len[len(population_cycles_len)-1] {
-			exists := false
-			// jeśli nowy cykl jest krótszy od najdłuższego cyklu w populacji to dodaj go do populacji
-			index_better := utils.IndexBetterInSortedArray(population_cycles_len, len_new_order)
-			if prev_index := index_better - 1; prev_index >= 0 && len_new_order == population_cycles_len[prev_index] {
-				exists = true
-			}
-			if !exists {
-				utils.InsertRetainSize(population_cycles_len, len_new_order, index_better)
-				utils.InsertRetainSize(population, new_order, index_better)
-
-				used_parents = make(map[string]utils.Empty) // reset mapy użytych rodziców
-				num_used_parents = 0
-			}
-		}
-
-		// sprawdzenie czy koniec czasu
-		elapsed = time.Since(start_time)
-		if elapsed.Milliseconds() > int64(time_limit) {
-			time_limit_reached = true
+		if !ok {
+			continue // para rodziców już sprawdzona
 		}
 		iter++
+		progress.iteration(iter, len_new_order, new_order)
+		// sprawdzenie czy koniec czasu lub limitu iteracji
+		running = cfg.withinBudget(ctx, iter)
 	}
 
-	utils.CopyCycles(order, population[0]) // kopiowanie najlepszego rozwiązania do order
-	return iter, nil
+	err = utils.CopyCycles(order, hae.population[0]) // kopiowanie najlepszego rozwiązania do order
+	return iter, err
 }
 
-func HAEWithLS(distance_matrix [][]int, order [][]int, nodes []reader.Node, time_limit int, heuristic_algorithm string, local_search_algorithm string, population_size int) (int, error) {
-	var (
-		iter                  int                        // wykonane iteracje
-		population            [][][]int                  // eltarna
-		population_cycles_len []int                      // długości cykli
-		start_time            time.Time     = time.Now() // czas rozpoczęcia algorytmu
-		elapsed               time.Duration              // czas od rozpoczęnia algorytmu
-		time_limit_reached    bool
-	)
+// populacja elitarna HAE (posortowana rosnąco po koszcie) ze stanem doboru rodziców
+type haeState struct {
+	distance_matrix       *utils.DistanceMatrix
+	nodes                 []reader.Node
+	cfg                   *Config
+	limits                *SizeLimits
+	rng                   *rand.Rand
+	crossover             CrossoverFunc                                                            // cfg.Crossover
+	local_search          func(context.Context, *utils.DistanceMatrix, [][]int, *SizeLimits) error // nil - potomkowie bez lokalnego przeszukiwania
+	progress              *progress
+	population            [][][]int              // eltarna
+	population_cycles_len []int                  // długości cykli
+	population_hashes     []uint64               // skróty postaci kanonicznych osobników
+	distances             [][]int                // EdgeDistance między osobnikami - tylko przy ReplacementBiasedFitness
+	used_parents          map[string]utils.Empty // Mapa przechowująca użyte kombinacje rodziców
+	num_used_parents      int
+	max_combinations      int // maksymalna liczba kombinacji rodziców
+}
 
-	// 1. Stworzenie populacji elitarnej
-	population, population_cycles_len = CreateStartPopulation(distance_matrix, nodes, population_size, heuristic_algorithm, local_search_algorithm)
-	var (
-		p1, p2           [][]int                                               // rodzice
-		used_parents     map[string]utils.Empty = make(map[string]utils.Empty) // Mapa przechowująca użyte kombinacje rodziców
-		num_used_parents int                    = 0
-		max_combinations int                    = population_size * (population_size - 1) / 2 // maksymalna liczba kombinacji rodziców
-	)
-	if len(population) != len(population_cycles_len) && len(population) != population_size {
-		return iter, fmt.Errorf("błąd: populacja nie jest tej samej długości co długości cykli")
+// stan HAE z populacją startową z cfg.Heuristic i cfg.LocalSearch
+func newHAEState(ctx context.Context, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand, with_ls bool, progress *progress) (*haeState, error) {
+	crossover, err := crossovers.lookup(string(cfg.Crossover))
+	if err != nil {
+		return nil, err
 	}
-
-	// główna pętla algorytmu
-	for time_limit_reached = false; !time_limit_reached && max_combinations != num_used_parents; {
-		// 2 losowi rodzice z populacji
-		i1, i2, _ := utils.Pick2RandomValues(population_size)
-		p1, p2 = population[i1], population[i2]
-		// jak rodzice byli sprawdzani to ich nie sprawdzaj ponownie
-		key := fmt.Sprintf("%d-%d", min(i1, i2), max(i1, i2))
-		if _, exists := used_parents[key]; exists {
-			continue
+	population, population_cycles_len, err := createStartPopulation(ctx, distance_matrix, nodes, cfg, limits, rng, progress)
+	if err != nil {
+		return nil, err
+	}
+	hae := &haeState{
+		distance_matrix:       distance_matrix,
+		nodes:                 nodes,
+		cfg:                   cfg,
+		limits:                limits,
+		rng:                   rng,
+		crossover:             crossover,
+		progress:              progress,
+		population:            population,
+		population_cycles_len: population_cycles_len,
+		used_parents:          make(map[string]utils.Empty),
+		max_combinations:      cfg.PopulationSize * (cfg.PopulationSize - 1) / 2,
+	}
+	for _, member := range population {
+		hae.population_hashes = append(hae.population_hashes, SolutionHash(member))
+	}
+	if cfg.Replacement == ReplacementBiasedFitness {
+		hae.distances = make([][]int, len(population))
+		for i, member := range population {
+			hae.distances[i] = hae.memberDistances(member)
 		}
-		used_parents[key] = utils.Empty{}
-		num_used_parents++
-
-		// krzyżowanie rodziców
-		new_order, err := CrossOver(p1, p2, distance_matrix, nodes)
+	}
+	if with_ls {
+		_, hae.local_search, err = metaheuristicFuncs(cfg, rng) // lokalne przeszukiwanie potomków
 		if err != nil {
-			return iter, err
+			return nil, err
 		}
-		// local search
-		new_order, err = Local_search(new_order, local_search_algorithm, distance_matrix)
+	}
+	return hae, nil
+}
+
+// czy populacja ma pełny rozmiar - niepełna tylko po zakończeniu ctx w trakcie tworzenia
+func (hae *haeState) full() bool {
+	return len(hae.population) == hae.cfg.PopulationSize
+}
+
+// czy wszystkie pary rodziców sprawdzone bez zmiany populacji
+func (hae *haeState) converged() bool {
+	return hae.num_used_parents == hae.max_combinations
+}
+
+// jedna iteracja: krzyżowanie losowych rodziców, opcjonalne lokalne przeszukiwanie i wstawienie potomka.
+// Zwraca potomka i jego koszt; false - losowa para rodziców była już sprawdzona, iteracja się nie odbyła
+func (hae *haeState) step(ctx context.Context, iter int) ([][]int, int, bool, error) {
+	// 2 losowi rodzice z populacji
+	i1, i2, err := utils.Pick2RandomValues(hae.cfg.PopulationSize, hae.rng)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	// jak rodzice byli sprawdzani to ich nie sprawdzaj ponownie
+	key := fmt.Sprintf("%d-%d", min(i1, i2), max(i1, i2))
+	if _, exists := hae.used_parents[key]; exists {
+		return nil, 0, false, nil
+	}
+	hae.used_parents[key] = utils.Empty{}
+	hae.num_used_parents++
+	// cykl c z p2 odpowiada cyklowi c z p1 - etykiety cykli są dowolne
+	p1, p2 := hae.population[i1], hae.population[i2]
+	p2, overlap := alignParents(p1, p2, hae.cfg.AlignCycles)
+
+	// krzyżowanie rodziców
+	new_order, err := hae.crossover(p1, p2, hae.distance_matrix, hae.nodes, hae.limits, hae.cfg, hae.rng)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("crossover: %w", err)
+	}
+	if hae.local_search != nil {
+		err = hae.local_search(ctx, hae.distance_matrix, new_order, hae.limits)
 		if err != nil {
-			return iter, err
+			return nil, 0, false, fmt.Errorf("local search: %w", err)
 		}
-		len_new_order := utils.CalculateCycleLen(new_order[0], distance_matrix) + utils.CalculateCycleLen(new_order[1], distance_matrix)
-
-		if len_new_order < population_cycles_len[len(population_cycles_len)-1] {
-			exists := false
-			// jeśli nowy cykl jest krótszy od najdłuższego cyklu w populacji to dodaj go do populacji
-			index_better := utils.IndexBetterInSortedArray(population_cycles_len, len_new_order)
-			if prev_index := index_better - 1; prev_index >= 0 && len_new_order == population_cycles_len[prev_index] {
-				exists = true
-			}
-			if !exists {
-				utils.InsertRetainSize(population_cycles_len, len_new_order, index_better)
-				utils.InsertRetainSize(population, new_order, index_better)
+	}
+	len_new_order := utils.CalculateCyclesLen(new_order, hae.distance_matrix)
+	hae.progress.crossover(iter, overlap, len_new_order, new_order)
+	if _, err := hae.insert(iter, new_order, len_new_order); err != nil {
+		return nil, 0, false, err
+	}
+	return new_order, len_new_order, true, nil
+}
 
-				used_parents = make(map[string]utils.Empty) // reset mapy użytych rodziców
-				num_used_parents = 0
+// wstawienie rozwiązania order o koszcie cost w miejsce osobnika wybranego według cfg.Replacement, jeśli order
+// nie jest duplikatem osobnika populacji (cfg.DuplicateDistance); false - populacja bez zmian
+func (hae *haeState) insert(iter int, order [][]int, cost int) (bool, error) {
+	worst := len(hae.population) - 1
+	if hae.cfg.Replacement != ReplacementBiasedFitness && cost >= hae.population_cycles_len[worst] {
+		return false, nil // nie ma gorszego osobnika do zastąpienia
+	}
+	hash := SolutionHash(order)
+	if isDuplicate(hae.population, hae.population_hashes, order, hash, hae.cfg.DuplicateDistance) {
+		return false, nil
+	}
+	victim := worst
+	var distances []int // odległości potomka od osobników
+	switch hae.cfg.Replacement {
+	case ReplacementClosest:
+		// najbliższy spośród gorszych - potomek zastępuje osobnika ze swojego basenu
+		distances = hae.memberDistances(order)
+		for i := range hae.population {
+			if hae.population_cycles_len[i] > cost && distances[i] < distances[victim] {
+				victim = i
 			}
 		}
+	case ReplacementBiasedFitness:
+		distances = hae.memberDistances(order)
+		costs := append(slices.Clone(hae.population_cycles_len), cost)
+		all_distances := make([][]int, len(costs))
+		for i := range hae.population {
+			all_distances[i] = append(slices.Clone(hae.distances[i]), distances[i])
+		}
+		all_distances[len(costs)-1] = append(slices.Clone(distances), 0)
+		victim = biasedFitnessVictim(costs, all_distances, hae.cfg.EliteSize, hae.cfg.NumClosest)
+		if victim == len(hae.population) {
+			return false, nil // potomek najgorszy w rankingu
+		}
+	}
+	index_better := hae.replace(victim, order, cost, hash, distances)
+	hae.progress.population(iter, index_better, hae.population_cycles_len, hae.population)
+
+	hae.used_parents = make(map[string]utils.Empty) // reset mapy użytych rodziców
+	hae.num_used_parents = 0
+	return true, nil
+}
 
-		// sprawdzenie czy koniec czasu
-		elapsed = time.Since(start_time)
-		if elapsed.Milliseconds() > int64(time_limit) {
-			time_limit_reached = true
+// usunięcie osobnika victim i wstawienie order w miejsce wynikające z kosztu; zwraca pozycję order
+func (hae *haeState) replace(victim int, order [][]int, cost int, hash uint64, distances []int) int {
+	hae.population = slices.Delete(hae.population, victim, victim+1)
+	hae.population_cycles_len = slices.Delete(hae.population_cycles_len, victim, victim+1)
+	hae.population_hashes = slices.Delete(hae.population_hashes, victim, victim+1)
+	index_better := utils.IndexBetterInSortedArray(hae.population_cycles_len, cost)
+	if index_better == -1 {
+		index_better = len(hae.population)
+	}
+	hae.population = slices.Insert(hae.population, index_better, order)
+	hae.population_cycles_len = slices.Insert(hae.population_cycles_len, index_better, cost)
+	hae.population_hashes = slices.Insert(hae.population_hashes, index_better, hash)
+	if hae.distances != nil {
+		distances = slices.Delete(slices.Clone(distances), victim, victim+1)
+		hae.distances = slices.Delete(hae.distances, victim, victim+1)
+		for i := range hae.distances {
+			hae.distances[i] = slices.Insert(slices.Delete(hae.distances[i], victim, victim+1), index_better, distances[i])
 		}
-		iter++
+		hae.distances = slices.Insert(hae.distances, index_better, slices.Insert(distances, index_better, 0))
 	}
+	return index_better
+}
 
-	utils.CopyCycles(order, population[0]) // kopiowanie najlepszego rozwiązania do order
-	return iter, nil
+// odległości (EdgeDistance) order od kolejnych osobników populacji
+func (hae *haeState) memberDistances(order [][]int) []int {
+	distances := make([]int, len(hae.population))
+	for i, member := range hae.population {
+		distances[i] = EdgeDistance(order, member)
+	}
+	return distances
 }
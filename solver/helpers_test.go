@@ -0,0 +1,46 @@
+package solver
+
+import (
+	"IMO/reader"
+	"IMO/utils"
+	"math/rand"
+	"testing"
+)
+
+// losowa instancja n wierzchołków na płaszczyźnie z macierzą odległości euklidesowych
+func testInstance(t *testing.T, n int, rng *rand.Rand) ([]reader.Node, *utils.DistanceMatrix) {
+	t.Helper()
+	nodes := make([]reader.Node, n)
+	for i := range nodes {
+		nodes[i] = reader.Node{X: float64(rng.Intn(1000)), Y: float64(rng.Intn(1000))}
+	}
+	instance := &reader.Instance{Name: "test", Dimension: n, EdgeWeightType: "EUC_2D", Nodes: nodes, HasCoordinates: true}
+	distance_matrix, err := utils.NewDistanceMatrix(instance, utils.RoundedEuclidean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nodes, distance_matrix
+}
+
+// losowy podział wierzchołków 0..n-1 na cykle o rozmiarach sizes
+func randomOrder(sizes []int, rng *rand.Rand) [][]int {
+	n := 0
+	for _, s := range sizes {
+		n += s
+	}
+	perm := rng.Perm(n)
+	order := make([][]int, len(sizes))
+	for c, s := range sizes {
+		order[c], perm = append([]int(nil), perm[:s]...), perm[s:]
+	}
+	return order
+}
+
+// głęboka kopia cykli
+func cloneOrder(order [][]int) [][]int {
+	clone := make([][]int, len(order))
+	for c := range order {
+		clone[c] = append([]int(nil), order[c]...)
+	}
+	return clone
+}
@@ -0,0 +1,171 @@
+package solver
+
+import (
+	"IMO/reader"
+	"IMO/utils"
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+func init() {
+	params := []string{"heuristic", "local_search", "population_size", "crossover", "align_cycles", "duplicate_distance", "replacement", "elite_size", "num_closest", "time_limit", "islands", "migration_topology", "migration_interval", "migrants"}
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicIslandHAE), Description: "hybrid evolutionary algorithm on concurrent islands with migration", Parameters: params, TimeBounded: true}, IslandHAEWithoutLS)
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicIslandHAELS), Description: "island hybrid evolutionary algorithm with local search of offspring", Parameters: params, TimeBounded: true}, IslandHAEWithLS)
+}
+
+// wyspa modelu wyspowego - populacja HAE ewoluująca we własnej gorutynie
+type island struct {
+	cfg        *Config        // konfiguracja z heurystyką, lokalnym przeszukiwaniem i krzyżowaniem wyspy
+	rng        *rand.Rand     // własny generator wyspy
+	hae        *haeState      // populacja; nil - niezainicjalizowana
+	inbox      chan [][]int   // migranci od sąsiadów
+	neighbours []chan [][]int // skrzynki wysp, do których wysyłani są migranci
+}
+
+func IslandHAEWithoutLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
+	return runIslandHAE(ctx, distance_matrix, order, nodes, cfg, limits, rng, false)
+}
+
+func IslandHAEWithLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
+	return runIslandHAE(ctx, distance_matrix, order, nodes, cfg, limits, rng, true)
+}
+
+// HAE na wyspach cfg.Islands: każda wyspa co cfg.MigrationInterval swoich iteracji wysyła cfg.Migrants najlepszych
+// osobników do sąsiadów (cfg.Topology) i przyjmuje oczekujących migrantów jak potomków. Migracja jest asynchroniczna -
+// pełna skrzynka sąsiada pomija migranta, a wyspa bez poprawy (wszystkie pary rodziców sprawdzone) kończy pracę.
+// Limit czasu i cfg.MaxIterations (suma iteracji wysp) obejmują wszystkie wyspy; wynik - najlepszy osobnik wysp
+func runIslandHAE(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand, with_ls bool) (int, error) {
+	ctx, cancel := cfg.withTimeLimit(ctx) // limit czasu obejmuje tworzenie populacji
+	defer cancel()
+	islands := newIslands(cfg, rng)
+	progress := newProgress(cfg) // zdarzenia wszystkich wysp dla cfg.Observer
+	var (
+		iter     atomic.Int64 // suma wykonanych iteracji wysp
+		wg       sync.WaitGroup
+		err_once sync.Once
+		err      error // pierwszy błąd wyspy
+	)
+	for i := range islands {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if island_err := islands[i].run(ctx, distance_matrix, nodes, limits, with_ls, progress, &iter); island_err != nil {
+				err_once.Do(func() { err = island_err })
+				cancel() // przerwanie pozostałych wysp
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return int(iter.Load()), err
+	}
+
+	best := islands[0]
+	for _, isl := range islands[1:] {
+		if isl.hae.population_cycles_len[0] < best.hae.population_cycles_len[0] {
+			best = isl
+		}
+	}
+	err = utils.CopyCycles(order, best.hae.population[0]) // kopiowanie najlepszego rozwiązania do order
+	return int(iter.Load()), err
+}
+
+// wyspy z cfg.Islands (puste - DefaultNumIslands wysp z konfiguracji głównej) połączone według cfg.Topology;
+// generatory wysp z kolejnych ziaren z rng
+func newIslands(cfg *Config, rng *rand.Rand) []*island {
+	island_cfgs := cfg.Islands
+	if len(island_cfgs) == 0 {
+		island_cfgs = make([]Island, DefaultNumIslands)
+	}
+	islands := make([]*island, len(island_cfgs))
+	for i, island_cfg := range island_cfgs {
+		isl_cfg := *cfg
+		if island_cfg.Heuristic != "" {
+			isl_cfg.Heuristic = island_cfg.Heuristic
+		}
+		if island_cfg.LocalSearch != "" {
+			isl_cfg.LocalSearch = island_cfg.LocalSearch
+		}
+		if island_cfg.Crossover != "" {
+			isl_cfg.Crossover = island_cfg.Crossover
+		}
+		islands[i] = &island{
+			cfg:   &isl_cfg,
+			rng:   rand.New(rand.NewSource(rng.Int63())),
+			inbox: make(chan [][]int, cfg.Migrants*(len(island_cfgs)-1)+1),
+		}
+	}
+	for i, isl := range islands {
+		switch cfg.Topology {
+		case TopologyRing:
+			if next := (i + 1) % len(islands); next != i {
+				isl.neighbours = append(isl.neighbours, islands[next].inbox)
+			}
+		case TopologyFull:
+			for j := range islands {
+				if j != i {
+					isl.neighbours = append(isl.neighbours, islands[j].inbox)
+				}
+			}
+		}
+	}
+	return islands
+}
+
+// ewolucja populacji wyspy aż do końca czasu, limitu iteracji albo braku poprawy
+func (isl *island) run(ctx context.Context, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, with_ls bool, progress *progress, iter *atomic.Int64) error {
+	hae, err := newHAEState(ctx, distance_matrix, nodes, isl.cfg, limits, isl.rng, with_ls, progress)
+	if err != nil {
+		return err
+	}
+	isl.hae = hae
+	island_iter := 0 // iteracje tej wyspy - wyznaczają migracje
+	for running := hae.full(); running && !hae.converged(); {
+		new_order, len_new_order, ok, err := hae.step(ctx, int(iter.Load()))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue // para rodziców już sprawdzona
+		}
+		island_iter++
+		total := int(iter.Add(1))
+		progress.iteration(total, len_new_order, new_order)
+		if island_iter%isl.cfg.MigrationInterval == 0 {
+			if err := isl.migrate(total); err != nil {
+				return err
+			}
+		}
+		running = isl.cfg.withinBudget(ctx, total)
+	}
+	return nil
+}
+
+// wysłanie kopii najlepszych osobników do sąsiadów i przyjęcie oczekujących migrantów
+func (isl *island) migrate(iter int) error {
+	hae := isl.hae
+	for _, inbox := range isl.neighbours {
+		for m := 0; m < isl.cfg.Migrants && m < len(hae.population); m++ {
+			migrant := make([][]int, len(hae.population[m]))
+			if err := utils.CopyCycles(migrant, hae.population[m]); err != nil {
+				return err
+			}
+			select {
+			case inbox <- migrant:
+			default: // pełna skrzynka - sąsiad nie nadąża albo już skończył
+			}
+		}
+	}
+	for {
+		select {
+		case migrant := <-isl.inbox:
+			if _, err := hae.insert(iter, migrant, utils.CalculateCyclesLen(migrant, hae.distance_matrix)); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package solver
+
+import (
+	"IMO/utils"
+	"context"
+	"math/rand"
+)
+
+const (
+	LKMaxDepth      = 50 // maksymalna liczba kroków w jednym łańcuchu ruchów
+	LKTopCandidates = 10 // rozmiar list kandydatów
+)
+
+func init() {
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchLinKernighan), Description: "Lin-Kernighan variable-depth 2-opt chains + candidate moves", Parameters: []string{"top_candidates", "lk_max_depth"}},
+		func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, cfg *Config, rng *rand.Rand) error {
+			return linKernighan(ctx, distance_matrix, order, limits, cfg.TopCandidates, cfg.LKMaxDepth)
+		})
+}
+
+// stan przeszukiwania Lin-Kernighana dla jednego cyklu zapisanego jako lista krawędzi
+type lkCycle struct {
+	distance_matrix *utils.DistanceMatrix
+	candidates      [][]int
+	max_depth       int           // maksymalna liczba kroków w łańcuchu
+	out             []*utils.Edge // krawędź wychodząca z wierzchołka
+	in_cycle        []bool        // czy wierzchołek należy do cyklu
+}
+
+// przebudowa listy krawędzi dla cyklu
+func (lk *lkCycle) load(cycle []int) {
+	for i := range lk.in_cycle {
+		lk.in_cycle[i] = false
+	}
+	edges := make([]*utils.Edge, len(cycle))
+	for i := range cycle {
+		edges[i] = utils.NewEdge(cycle[i], utils.ElemAfter(cycle, i), lk.distance_matrix, nil, nil)
+		edges[i].Nr = i
+		lk.out[cycle[i]] = edges[i]
+		lk.in_cycle[cycle[i]] = true
+	}
+	for i := range edges {
+		edges[i].Prev = edges[(i-1+len(edges))%len(edges)]
+		edges[i].Next = edges[(i+1)%len(edges)]
+	}
+}
+
+func (lk *lkCycle) succ(node int) int {
+	return lk.out[node].To
+}
+
+func (lk *lkCycle) pred(node int) int {
+	return lk.out[node].Prev.From
+}
+
+// ruch 2-opt: usunięcie krawędzi (t1, t2) i (t4, t3), dodanie (t2, t3) i (t1, t4); t2 = succ(t1), t4 = pred(t3)
+// ścieżka t2 -> ... -> t4 zostaje odwrócona; wymaga t2 != t4
+func (lk *lkCycle) flip(t1 int, t2 int, t3 int) {
+	var (
+		e1    *utils.Edge = lk.out[t1]      // t1 -> t2
+		e2    *utils.Edge = lk.out[t3].Prev // t4 -> t3
+		t4    int         = e2.From
+		first *utils.Edge = e1.Next // pierwsza krawędź ścieżki t2 -> t4
+		last  *utils.Edge = e2.Prev // ostatnia krawędź ścieżki t2 -> t4
+	)
+	for e := first; ; e = e.Prev { // odwrócenie krawędzi ścieżki; po zamianie Prev wskazuje na kolejną krawędź
+		e.From, e.To = e.To, e.From
+		e.Prev, e.Next = e.Next, e.Prev
+		lk.out[e.From] = e
+		if e == last {
+			break
+		}
+	}
+	// po odwróceniu ścieżka zaczyna się od last, a kończy na first
+	e1.To, e1.Next, last.Prev = t4, last, e1
+	e2.From, e2.Prev, first.Next = t2, first, e2
+	e1.Length = lk.distance_matrix.At(e1.From, e1.To)
+	e2.Length = lk.distance_matrix.At(e2.From, e2.To)
+	lk.out[t1], lk.out[t2] = e1, e2
+}
+
+// łańcuch ruchów rozpoczęty od krawędzi (t1, succ(t1)); zwraca zysk - o ile skrócił się cykl
+func (lk *lkCycle) improveFrom(t1 int) int {
+	type step struct {
+		t2, t3 int
+	}
+	var (
+		d         = lk.distance_matrix.At
+		steps     []step
+		used      = make(map[int]bool) // wierzchołki t3 użyte w łańcuchu
+		gain      int                  // aktualny zysk po zamknięciu cyklu
+		best_gain int                  // najlepszy zysk w łańcuchu
+		best_step int                  // liczba kroków dla najlepszego zysku
+	)
+	for len(steps) < lk.max_depth {
+		var (
+			t2        int = lk.succ(t1)
+			g         int = gain + d(t1, t2) // zysk bez krawędzi zamykającej
+			best_t3   int = -1
+			best_open int // g - d(t2, t3) + d(t4, t3) najlepszego kandydata
+		)
+		for _, t3 := range lk.candidates[t2] {
+			g1 := g - d(t2, t3)
+			if g1 <= 0 { // kandydaci posortowani rosnąco po odległości
+				break
+			}
+			if !lk.in_cycle[t3] || used[t3] || t3 == t1 || t3 == lk.succ(t2) {
+				continue
+			}
+			if open := g1 + d(lk.pred(t3), t3); best_t3 == -1 || open > best_open {
+				best_t3, best_open = t3, open
+			}
+		}
+		if best_t3 == -1 {
+			break
+		}
+		t4 := lk.pred(best_t3)
+		lk.flip(t1, t2, best_t3)
+		steps = append(steps, step{t2, best_t3})
+		used[best_t3] = true
+		gain = best_open - d(t1, t4)
+		if gain > best_gain {
+			best_gain, best_step = gain, len(steps)
+		}
+	}
+	// cofnięcie kroków po najlepszym
+	for i := len(steps) - 1; i >= best_step; i-- {
+		lk.flip(t1, lk.succ(t1), steps[i].t3)
+	}
+	return best_gain
+}
+
+// poprawa jednego cyklu do optimum lokalnego lub przerwania przez ctx; zwraca zysk
+func (lk *lkCycle) improve(ctx context.Context, cycle []int) int {
+	if len(cycle) < 5 {
+		return 0
+	}
+	lk.load(cycle)
+	var (
+		total  int
+		queue  []int = append([]int(nil), cycle...) // wierzchołki do sprawdzenia
+		active       = make(map[int]bool, len(cycle))
+	)
+	for _, n := range cycle {
+		active[n] = true
+	}
+	for len(queue) > 0 && ctx.Err() == nil {
+		t1 := queue[0]
+		queue = queue[1:]
+		active[t1] = false
+		gain := lk.improveFrom(t1)
+		if gain <= 0 {
+			continue
+		}
+		total += gain
+		// ponowne sprawdzenie otoczenia zmienionych krawędzi
+		for _, n := range []int{t1, lk.succ(t1), lk.pred(t1)} {
+			if !active[n] {
+				active[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	copy(cycle, utils.EdgeToNodeCycle(lk.out[cycle[0]]))
+	return total
+}
+
+// przeszukiwanie Lin-Kernighana (wersja z ruchami 2-opt o zmiennej głębokości) dla każdego cyklu,
+// przeplatane ruchami kandydackimi pomiędzy cyklami (CandidateSearch)
+func LinKernighan(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return linKernighan(ctx, distance_matrix, order, limits, LKTopCandidates, LKMaxDepth)
+}
+
+func linKernighan(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, top_candidates int, max_depth int) error {
+	candidates, err := CalculateCandidates(distance_matrix, top_candidates)
+	if err != nil {
+		return err
+	}
+	lk := &lkCycle{
+		distance_matrix: distance_matrix,
+		candidates:      candidates,
+		max_depth:       max_depth,
+		out:             make([]*utils.Edge, distance_matrix.Dimension),
+		in_cycle:        make([]bool, distance_matrix.Dimension),
+	}
+	length := utils.CalculateCyclesLen(order, distance_matrix)
+	for {
+		if ctx.Err() != nil {
+			return nil // przerwane przez ctx - order zawiera rozwiązanie po dotychczasowych ruchach
+		}
+		if len(order) > 1 { // ruchy pomiędzy cyklami - najpierw podział wierzchołków, potem poprawa cykli
+			err := candidateSearch(ctx, distance_matrix, order, limits, top_candidates, false, false)
+			if err != nil {
+				return err
+			}
+		}
+		for c := range order {
+			lk.improve(ctx, order[c])
+		}
+		new_length := utils.CalculateCyclesLen(order, distance_matrix)
+		if new_length >= length {
+			return nil
+		}
+		length = new_length
+	}
+}
@@ -2,11 +2,26 @@ package solver
 
 import (
 	"IMO/utils"
+	"context"
 	"math"
 	"math/rand"
+	"slices"
 	"time"
 )
 
+func init() {
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchSteepestNode), Description: "steepest descent, node exchange"}, plainImprover(SteepestNode))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchSteepestEdge), Description: "steepest descent, 2-opt edge exchange"}, plainImprover(SteepestEdge))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchGreedyNode), Description: "greedy descent, node exchange"}, randomizedImprover(GreedyNode))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchGreedyEdge), Description: "greedy descent, 2-opt edge exchange"}, randomizedImprover(GreedyEdge))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchSteepestOrOpt), Description: "steepest edge exchange + Or-opt"}, plainImprover(SteepestOrOpt))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchGreedyOrOpt), Description: "greedy edge exchange + Or-opt"}, randomizedImprover(GreedyOrOpt))
+	RegisterImprover(AlgorithmInfo{Name: string(LocalSearchRandomWalk), Description: "random walk keeping the best solution", Parameters: []string{"random_walk_time"}, TimeBounded: true},
+		func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, cfg *Config, rng *rand.Rand) error {
+			return randomWalk(ctx, distance_matrix, order, limits, cfg.RandomWalkTime.Duration, rng)
+		})
+}
+
 // ruch - zamiana wierzchołka N1 z N2 w cyklu Cycle
 type MoveNode struct {
 	Cycle int // numer cyklu
@@ -15,10 +30,12 @@ type MoveNode struct {
 	Delta int // zmiana długości cyklu po dodaniu krawędzi
 }
 
-// ruch - zamiana wierzchołków między cyklami
+// ruch - zamiana wierzchołków między cyklami C1 i C2
 type SwapMove struct {
-	N1    int // wierzchołek z cyklu 1
-	N2    int // wierzchołek z cyklu 2
+	C1    int // numer cyklu 1
+	C2    int // numer cyklu 2
+	N1    int // wierzchołek z cyklu C1 - nr w cyklu
+	N2    int // wierzchołek z cyklu C2 - nr w cyklu
 	Delta int // zmiana długości cyklów po dodaniu krawędzi
 }
 
@@ -30,14 +47,24 @@ type MoveEdge struct {
 	Delta int // zmiana długości cyklu po zamianie krawędzi
 }
 
+// ruch - przeniesienie wierzchołka N1 z cyklu C1 do cyklu C2 przed wierzchołek N2
+type RelocateMove struct {
+	C1    int // numer cyklu, z którego zabieramy wierzchołek
+	C2    int // numer cyklu, do którego wstawiamy wierzchołek
+	N1    int // przenoszony wierzchołek - nr w cyklu C1
+	N2    int // wierzchołek z cyklu C2, przed który wstawiamy - nr w cyklu
+	Delta int // zmiana długości cyklów po przeniesieniu
+}
+
 type Move interface {
-	ExecuteMove(order [][]int) // wykonanie ruchu
-	GetDelta() int             // zmiana długości cyklu po dodaniu krawędzi
-	SetDelta(delta int)        // ustawienie zmiany długości cyklu po dodaniu krawędzi
+	ExecuteMove(tour *Tour) error // wykonanie ruchu; błąd (ErrInfeasible) - ruch nie pasuje do cykli, np. nieaktualny
+	GetDelta() int                // zmiana długości cyklu po dodaniu krawędzi
+	SetDelta(delta int)           // ustawienie zmiany długości cyklu po dodaniu krawędzi
 }
 
-func (m *SwapMove) ExecuteMove(order [][]int) {
-	order[0][m.N1], order[1][m.N2] = order[1][m.N2], order[0][m.N1] // zamiana wierzchołków między cyklami
+func (m *SwapMove) ExecuteMove(tour *Tour) error {
+	tour.SwapAt(m.C1, m.N1, m.C2, m.N2) // zamiana wierzchołków między cyklami
+	return nil
 }
 
 func (m *SwapMove) GetDelta() int {
@@ -48,8 +75,23 @@ func (m *SwapMove) SetDelta(delta int) {
 	m.Delta = delta
 }
 
-func (m *MoveNode) ExecuteMove(order [][]int) {
-	order[m.Cycle][m.N1], order[m.Cycle][m.N2] = order[m.Cycle][m.N2], order[m.Cycle][m.N1] // zamiana wierzchołków wewnątrz cyklu
+func (m *RelocateMove) ExecuteMove(tour *Tour) error {
+	node := tour.Order[m.C1][m.N1]
+	tour.Remove(node)                    // usunięcie wierzchołka z cyklu C1
+	return tour.Insert(m.C2, m.N2, node) // wstawienie przed N2 w cyklu C2
+}
+
+func (m *RelocateMove) GetDelta() int {
+	return m.Delta // zmiana długości cyklów po przeniesieniu
+}
+
+func (m *RelocateMove) SetDelta(delta int) {
+	m.Delta = delta
+}
+
+func (m *MoveNode) ExecuteMove(tour *Tour) error {
+	tour.SwapAt(m.Cycle, m.N1, m.Cycle, m.N2) // zamiana wierzchołków wewnątrz cyklu
+	return nil
 }
 
 func (m *MoveNode) GetDelta() int {
@@ -60,10 +102,11 @@ func (m *MoveNode) SetDelta(delta int) {
 	m.Delta = delta
 }
 
-func (m *MoveEdge) ExecuteMove(order [][]int) {
-	for i, j := m.N1+1, m.N2; i < j; i, j = i+1, j-1 {
-		order[m.Cycle][i], order[m.Cycle][j] = order[m.Cycle][j], order[m.Cycle][i] // zamiana krawędzi wewnątrz cyklu
+func (m *MoveEdge) ExecuteMove(tour *Tour) error {
+	if m.N1+1 < m.N2 {
+		tour.Reverse(m.Cycle, m.N1+1, m.N2) // zamiana krawędzi wewnątrz cyklu - odwrócenie fragmentu między nimi
 	}
+	return nil
 }
 
 func (m *MoveEdge) GetDelta() int {
@@ -74,17 +117,19 @@ func (m *MoveEdge) SetDelta(delta int) {
 	m.Delta = delta
 }
 
-func SteepestNode(distance_matrix [][]int, order [][]int) error {
+func SteepestNode(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
 	var (
-		best_move       Move   = nil                                                // najlepszy ruch w iteracji
-		min_delta       int    = math.MaxInt                                        // minimalna zmiana długości cyklu
-		current_length1 int    = utils.CalculateCycleLen(order[0], distance_matrix) // akutalna długość cyklu 1
-		current_length2 int    = utils.CalculateCycleLen(order[1], distance_matrix) // akutalna długość cyklu 2
-		current_length  int    = current_length1 + current_length2
-		all_moves       []Move // aktualnie dostępne ruchy
+		best_move      Move   = nil                                              // najlepszy ruch w iteracji
+		min_delta      int    = math.MaxInt                                      // minimalna zmiana długości cyklu
+		current_length int    = utils.CalculateCyclesLen(order, distance_matrix) // akutalna długość cykli
+		all_moves      []Move                                                    // aktualnie dostępne ruchy
+		tour           *Tour  = NewTour(order)                                   // cykle z pozycjami wierzchołków
 	)
 
 	for {
+		if ctx.Err() != nil {
+			return nil // przerwane przez ctx - order zawiera rozwiązanie po dotychczasowych ruchach
+		}
 		// ruchy pomiędzy cyklami
 		distances_before := DistancesBefore(distance_matrix, order)                        // dystans do wierzchołków przed i po aktualnym w cyklu
 		swap_moves, err := AllMovesBetweenCycles(distance_matrix, order, distances_before) // wszystkie ruchy między cyklami
@@ -95,8 +140,13 @@ func SteepestNode(distance_matrix [][]int, order [][]int) error {
 		for i := range swap_moves {               // dla każdego ruchu
 			all_moves[i] = &swap_moves[i] // dodaj ruch do listy
 		}
+		// przeniesienia wierzchołków między cyklami - tylko gdy pozwalają na to rozmiary cykli
+		relocate_moves := AllRelocateMoves(distance_matrix, order, limits, distances_before)
+		for i := range relocate_moves {
+			all_moves = append(all_moves, &relocate_moves[i])
+		}
 		// ruchy w obrębie cyklu - zamiana wierzchołków w cyklu
-		for c := 0; c < NumCycles; c++ { // dla każdego cyklu
+		for c := range order { // dla każdego cyklu
 			moves_cycle := AllMovesNodesCycle(distance_matrix, order[c], c, distances_before[c]) // wszystkie ruchy w cyklu zamiany wierzchołków
 
 			for m := range moves_cycle { // dla każdego ruchu
@@ -111,45 +161,64 @@ func SteepestNode(distance_matrix [][]int, order [][]int) error {
 			break
 		}
 		// jeśli znaleziono ruch, to wykonaj go
-		best_move.ExecuteMove(order)                // wykonaj najlepszy ruch
+		if err := best_move.ExecuteMove(tour); err != nil { // wykonaj najlepszy ruch
+			return err
+		}
 		current_length = current_length + min_delta // aktualizuj długość cyklu
 		best_move, min_delta = nil, math.MaxInt     // ustaw najlepszy ruch na nil i delta MaxInt
 	}
 
 	return nil
 }
-func RandomWalk(distance_matrix [][]int, order [][]int) error {
+func RandomWalk(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, rng *rand.Rand) error {
+	return randomWalk(ctx, distance_matrix, order, limits, DefaultRandomWalkTime, rng)
+}
+
+// losowe ruchy przez czas duration; zapamiętywane najlepsze rozwiązanie
+func randomWalk(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, duration time.Duration, rng *rand.Rand) error {
 	var (
 		move           Move
-		current_length int     = utils.CalculateCycleLen(order[0], distance_matrix) + utils.CalculateCycleLen(order[1], distance_matrix)
-		save_order     [][]int = make([][]int, NumCycles) // kolejność odwiedzania wierzchołków dla obydwu cykli
+		current_length int     = utils.CalculateCyclesLen(order, distance_matrix)
+		save_order     [][]int = make([][]int, len(order)) // kolejność odwiedzania wierzchołków dla wszystkich cykli
+		move_types     int     = 3                         // zamiana wierzchołków, krawędzi, wierzchołków między cyklami
+		tour           *Tour   = NewTour(order)            // cykle z pozycjami wierzchołków
 	)
 	// kopiowanie tablic (kopie elementów) a nie całej macierzy (kopie tablic - wskaźniki) bo referencja
 	for so := range save_order {
 		save_order[so] = make([]int, len(order[so]))
 		copy(save_order[so], order[so])
 	}
-	start := time.Now()
-	for elapsed := time.Since(start); elapsed < 1538*time.Millisecond; elapsed = time.Since(start) {
-		move_type := rand.Intn(3)
+	if len(order) < 2 {
+		move_types = 2 // jeden cykl - brak zamian między cyklami
+	}
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	for ctx.Err() == nil {
+		move_type := rng.Intn(move_types)
 		switch move_type {
 		case 0: // zamiana wierzchołków wewnątrz cyklu
-			cycle := rand.Intn(2)
-			n1 := rand.Intn(len(order[cycle]))
-			n2 := rand.Intn(len(order[cycle]))
+			cycle := rng.Intn(len(order))
+			n1 := rng.Intn(len(order[cycle]))
+			n2 := rng.Intn(len(order[cycle]))
 			move = &MoveNode{Cycle: cycle, N1: n1, N2: n2, Delta: 0}
 		case 1: // zamiana krawędzi wewnątrz cyklu
-			cycle := rand.Intn(2)
-			n1 := rand.Intn(len(order[cycle]))
-			n2 := rand.Intn(len(order[cycle]))
+			cycle := rng.Intn(len(order))
+			n1 := rng.Intn(len(order[cycle]))
+			n2 := rng.Intn(len(order[cycle]))
 			move = &MoveEdge{Cycle: cycle, N1: n1, N2: n2, Delta: 0}
 		case 2: // zamiana wierzchołków między cyklami
-			n1 := rand.Intn(len(order[0]))
-			n2 := rand.Intn(len(order[1]))
-			move = &SwapMove{N1: n1, N2: n2, Delta: 0}
+			c1, c2, err := utils.Pick2RandomValues(len(order), rng)
+			if err != nil {
+				return err
+			}
+			n1 := rng.Intn(len(order[c1]))
+			n2 := rng.Intn(len(order[c2]))
+			move = &SwapMove{C1: c1, C2: c2, N1: n1, N2: n2, Delta: 0}
 		}
-		move.ExecuteMove(order)
-		new_current_length := utils.CalculateCycleLen(order[0], distance_matrix) + utils.CalculateCycleLen(order[1], distance_matrix) // aktualizuj długość cyklu
+		if err := move.ExecuteMove(tour); err != nil {
+			return err
+		}
+		new_current_length := utils.CalculateCyclesLen(order, distance_matrix) // aktualizuj długość cyklu
 		if new_current_length < current_length {
 			for so := range save_order {
 				copy(save_order[so], order[so])
@@ -160,36 +229,24 @@ func RandomWalk(distance_matrix [][]int, order [][]int) error {
 	copy(order, save_order)
 	return nil
 }
-func GreedyNode(distance_matrix [][]int, order [][]int) error {
+func GreedyNode(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, rng *rand.Rand) error {
 	var (
-		best_move       Move   = nil                                                // najlepszy ruch w iteracji
-		min_delta       int    = math.MaxInt                                        // minimalna zmiana długości cyklu
-		current_length1 int    = utils.CalculateCycleLen(order[0], distance_matrix) // akutalna długość cyklu 1
-		current_length2 int    = utils.CalculateCycleLen(order[1], distance_matrix) // akutalna długość cyklu 2
-		current_length  int    = current_length1 + current_length2
-		all_moves       []Move // aktualnie dostępne ruchy
+		best_move      Move   = nil                                              // najlepszy ruch w iteracji
+		min_delta      int    = math.MaxInt                                      // minimalna zmiana długości cyklu
+		current_length int    = utils.CalculateCyclesLen(order, distance_matrix) // akutalna długość cykli
+		all_moves      []Move                                                    // aktualnie dostępne ruchy
+		tour           *Tour  = NewTour(order)                                   // cykle z pozycjami wierzchołków
 	)
 
-	// ruchy pomiędzy cyklami
-	swap_moves, err := AllMovesBetweenCyclesNoDistance(order) // wszystkie ruchy między cyklami
+	all_moves, err := AllMovesNoDistance(order, limits, false, false)
 	if err != nil {
 		return err
 	}
-	all_moves = make([]Move, len(swap_moves)) // lista ruchów
-	for i := range swap_moves {               // dla każdego ruchu
-		all_moves[i] = &swap_moves[i] // dodaj ruch do listy
-	}
-
-	// ruchy w obrębie cyklu - zamiana wierzchołków w cyklu
-	for c := 0; c < NumCycles; c++ { // dla każdego cyklu
-		moves_cycle := AllMovesNodesCycleNoDistance(order[c], c) // wszystkie ruchy w cyklu zamiany wierzchołków
-
-		for m := range moves_cycle { // dla każdego ruchu
-			all_moves = append(all_moves, &moves_cycle[m]) // dodaj ruch do listy
-		}
-	}
 	for {
-		best_move, min_delta = FindBestMoveGreedy(all_moves, distance_matrix, order) // najlepszy ruch i minimalna zmiana długości cyklu
+		if ctx.Err() != nil {
+			return nil // przerwane przez ctx - order zawiera rozwiązanie po dotychczasowych ruchach
+		}
+		best_move, min_delta = FindBestMoveGreedy(all_moves, distance_matrix, order, rng) // najlepszy ruch i minimalna zmiana długości cyklu
 
 		// koniec iteracji
 		if min_delta >= 0 { // jeśli nie znaleziono ruchu, który zmniejsza długość cyklu skończ przeszukiwanie
@@ -197,8 +254,12 @@ func GreedyNode(distance_matrix [][]int, order [][]int) error {
 			break
 		}
 		// jeśli znaleziono ruch, to wykonaj go
-		best_move.ExecuteMove(order)                // wykonaj najlepszy ruch
+		if err := best_move.ExecuteMove(tour); err != nil { // wykonaj najlepszy ruch
+			return err
+		}
 		current_length = current_length + min_delta // aktualizuj długość cyklu
+		// zmiana długości cykli - ruchy z nieaktualnymi indeksami wymieniane na nowe
+		all_moves = UpdateMovesNoDistance(all_moves, order, limits, best_move, false, false)
 
 		best_move, min_delta = nil, math.MaxInt // ustaw najlepszy ruch na nil i delta MaxInt
 	}
@@ -206,17 +267,28 @@ func GreedyNode(distance_matrix [][]int, order [][]int) error {
 	return nil
 }
 
-func SteepestEdge(distance_matrix [][]int, order [][]int) error {
+func SteepestEdge(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return steepestEdge(ctx, distance_matrix, order, limits, false)
+}
+
+// SteepestEdge rozszerzony o ruchy Or-opt
+func SteepestOrOpt(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+	return steepestEdge(ctx, distance_matrix, order, limits, true)
+}
+
+func steepestEdge(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, or_opt bool) error {
 	var (
-		best_move       Move   = nil                                                // najlepszy ruch w iteracji
-		min_delta       int    = math.MaxInt                                        // minimalna zmiana długości cyklu
-		current_length1 int    = utils.CalculateCycleLen(order[0], distance_matrix) // akutalna długość cyklu 1
-		current_length2 int    = utils.CalculateCycleLen(order[1], distance_matrix) // akutalna długość cyklu 2
-		current_length  int    = current_length1 + current_length2
-		all_moves       []Move // aktualnie dostępne ruchy
+		best_move      Move   = nil                                              // najlepszy ruch w iteracji
+		min_delta      int    = math.MaxInt                                      // minimalna zmiana długości cyklu
+		current_length int    = utils.CalculateCyclesLen(order, distance_matrix) // akutalna długość cykli
+		all_moves      []Move                                                    // aktualnie dostępne ruchy
+		tour           *Tour  = NewTour(order)                                   // cykle z pozycjami wierzchołków
 	)
 
 	for {
+		if ctx.Err() != nil {
+			return nil // przerwane przez ctx - order zawiera rozwiązanie po dotychczasowych ruchach
+		}
 		// ruchy pomiędzy cyklami
 		distances_before := DistancesBefore(distance_matrix, order)                        // dystans do wierzchołków przed i po aktualnym w cyklu
 		swap_moves, err := AllMovesBetweenCycles(distance_matrix, order, distances_before) // wszystkie ruchy między cyklami
@@ -227,15 +299,25 @@ func SteepestEdge(distance_matrix [][]int, order [][]int) error {
 		for i := range swap_moves {               // dla każdego ruchu
 			all_moves[i] = &swap_moves[i] // dodaj ruch do listy
 		}
+		// przeniesienia wierzchołków między cyklami - tylko gdy pozwalają na to rozmiary cykli
+		relocate_moves := AllRelocateMoves(distance_matrix, order, limits, distances_before)
+		for i := range relocate_moves {
+			all_moves = append(all_moves, &relocate_moves[i])
+		}
 
 		// ruchy w obrębie cyklu - zamiana wierzchołków w cyklu
-		for c := 0; c < NumCycles; c++ { // dla każdego cyklu
+		for c := range order { // dla każdego cyklu
 			moves_cycle := AllMovesEdgesCycle(distance_matrix, order[c], c) // wszystkie ruchy w cyklu zamiany wierzchołków
 
 			for m := range moves_cycle { // dla każdego ruchu
 				all_moves = append(all_moves, &moves_cycle[m]) // dodaj ruch do listy
 			}
 		}
+		if or_opt { // przeniesienia segmentów w obrębie cyklu i między cyklami
+			if or_opt_move := BestOrOptMove(distance_matrix, order, limits); or_opt_move != nil {
+				all_moves = append(all_moves, or_opt_move) // tylko najlepszy - FindBestMove i tak wybiera jeden ruch
+			}
+		}
 		best_move, min_delta = FindBestMove(all_moves) // najlepszy ruch i minimalna zmiana długości cyklu
 
 		// koniec iteracji
@@ -244,7 +326,9 @@ func SteepestEdge(distance_matrix [][]int, order [][]int) error {
 			break
 		}
 		// jeśli znaleziono ruch, to wykonaj go
-		best_move.ExecuteMove(order)                // wykonaj najlepszy ruch
+		if err := best_move.ExecuteMove(tour); err != nil { // wykonaj najlepszy ruch
+			return err
+		}
 		current_length = current_length + min_delta // aktualizuj długość cyklu
 		best_move, min_delta = nil, math.MaxInt     // ustaw najlepszy ruch na nil i delta MaxInt
 	}
@@ -252,36 +336,33 @@ func SteepestEdge(distance_matrix [][]int, order [][]int) error {
 	return nil
 }
 
-func GreedyEdge(distance_matrix [][]int, order [][]int) error {
+func GreedyEdge(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, rng *rand.Rand) error {
+	return greedyEdge(ctx, distance_matrix, order, limits, false, rng)
+}
+
+// GreedyEdge rozszerzony o ruchy Or-opt
+func GreedyOrOpt(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, rng *rand.Rand) error {
+	return greedyEdge(ctx, distance_matrix, order, limits, true, rng)
+}
+
+func greedyEdge(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, or_opt bool, rng *rand.Rand) error {
 	var (
-		best_move       Move   = nil                                                // najlepszy ruch w iteracji
-		min_delta       int    = math.MaxInt                                        // minimalna zmiana długości cyklu
-		current_length1 int    = utils.CalculateCycleLen(order[0], distance_matrix) // akutalna długość cyklu 1
-		current_length2 int    = utils.CalculateCycleLen(order[1], distance_matrix) // akutalna długość cyklu 2
-		current_length  int    = current_length1 + current_length2
-		all_moves       []Move // aktualnie dostępne ruchy
+		best_move      Move   = nil                                              // najlepszy ruch w iteracji
+		min_delta      int    = math.MaxInt                                      // minimalna zmiana długości cyklu
+		current_length int    = utils.CalculateCyclesLen(order, distance_matrix) // akutalna długość cykli
+		all_moves      []Move                                                    // aktualnie dostępne ruchy
+		tour           *Tour  = NewTour(order)                                   // cykle z pozycjami wierzchołków
 	)
 
-	// ruchy pomiędzy cyklami
-	swap_moves, err := AllMovesBetweenCyclesNoDistance(order) // wszystkie ruchy między cyklami
+	all_moves, err := AllMovesNoDistance(order, limits, true, or_opt)
 	if err != nil {
 		return err
 	}
-	all_moves = make([]Move, len(swap_moves)) // lista ruchów
-	for i := range swap_moves {               // dla każdego ruchu
-		all_moves[i] = &swap_moves[i] // dodaj ruch do listy
-	}
-
-	// ruchy w obrębie cyklu - zamiana wierzchołków w cyklu
-	for c := 0; c < NumCycles; c++ { // dla każdego cyklu
-		moves_cycle := AllMovesEdgesCycleNoDistance(order[c], c) // wszystkie ruchy w cyklu zamiany wierzchołków
-
-		for m := range moves_cycle { // dla każdego ruchu
-			all_moves = append(all_moves, &moves_cycle[m]) // dodaj ruch do listy
-		}
-	}
 	for {
-		best_move, min_delta = FindBestMoveGreedy(all_moves, distance_matrix, order) // najlepszy ruch i minimalna zmiana długości cyklu
+		if ctx.Err() != nil {
+			return nil // przerwane przez ctx - order zawiera rozwiązanie po dotychczasowych ruchach
+		}
+		best_move, min_delta = FindBestMoveGreedy(all_moves, distance_matrix, order, rng) // najlepszy ruch i minimalna zmiana długości cyklu
 
 		// koniec iteracji
 		if min_delta >= 0 { // jeśli nie znaleziono ruchu, który zmniejsza długość cyklu skończ przeszukiwanie
@@ -289,8 +370,12 @@ func GreedyEdge(distance_matrix [][]int, order [][]int) error {
 			break
 		}
 		// jeśli znaleziono ruch, to wykonaj go
-		best_move.ExecuteMove(order)                // wykonaj najlepszy ruch
+		if err := best_move.ExecuteMove(tour); err != nil { // wykonaj najlepszy ruch
+			return err
+		}
 		current_length = current_length + min_delta // aktualizuj długość cyklu
+		// zmiana długości cykli - ruchy z nieaktualnymi indeksami wymieniane na nowe
+		all_moves = UpdateMovesNoDistance(all_moves, order, limits, best_move, true, or_opt)
 
 		best_move, min_delta = nil, math.MaxInt // ustaw najlepszy ruch na nil i delta MaxInt
 	}
@@ -298,7 +383,183 @@ func GreedyEdge(distance_matrix [][]int, order [][]int) error {
 	return nil
 }
 
-func CalculateDelta(move Move, distance_matrix [][]int, order [][]int) int {
+// wszystkie ruchy bez obliczonych delt (przeszukiwanie zachłanne); edges - zamiany krawędzi zamiast wierzchołków w cyklu,
+// or_opt - dodatkowo przeniesienia segmentów
+func AllMovesNoDistance(order [][]int, limits *SizeLimits, edges bool, or_opt bool) ([]Move, error) {
+	var all_moves []Move // aktualnie dostępne ruchy
+
+	// ruchy pomiędzy cyklami
+	swap_moves, err := AllMovesBetweenCyclesNoDistance(order) // wszystkie ruchy między cyklami
+	if err != nil {
+		return nil, err
+	}
+	all_moves = make([]Move, len(swap_moves)) // lista ruchów
+	for i := range swap_moves {               // dla każdego ruchu
+		all_moves[i] = &swap_moves[i] // dodaj ruch do listy
+	}
+	relocate_moves := AllRelocateMovesNoDistance(order, limits) // przeniesienia wierzchołków między cyklami
+	for i := range relocate_moves {
+		all_moves = append(all_moves, &relocate_moves[i])
+	}
+
+	// ruchy w obrębie cyklu - zamiana wierzchołków lub krawędzi w cyklu
+	for c := range order { // dla każdego cyklu
+		if edges {
+			moves_cycle := AllMovesEdgesCycleNoDistance(order[c], c)
+			for m := range moves_cycle {
+				all_moves = append(all_moves, &moves_cycle[m])
+			}
+		} else {
+			moves_cycle := AllMovesNodesCycleNoDistance(order[c], c)
+			for m := range moves_cycle {
+				all_moves = append(all_moves, &moves_cycle[m])
+			}
+		}
+	}
+	if or_opt {
+		or_opt_moves := AllOrOptMovesNoDistance(order, limits)
+		for m := range or_opt_moves {
+			all_moves = append(all_moves, &or_opt_moves[m])
+		}
+	}
+	return all_moves, nil
+}
+
+// cykle po ruchu, który przeniósł wierzchołki między cyklami - które indeksy w ruchach bez delt zachowują znaczenie
+type resizedCycles struct {
+	before   []int      // rozmiary cykli przed ruchem
+	changed  []bool     // czy rozmiar cyklu się zmienił
+	bound    []int      // liczba początkowych indeksów wspólnych dla rozmiarów przed i po ruchu
+	relocate [][]bool   // czy przeniesienie wierzchołka z cyklu c1 do c2 jest nadal dozwolone
+	or_opt   [][][]bool // [length-1][c1][c2] - czy przeniesienie segmentu length wierzchołków jest nadal dozwolone
+}
+
+func newResizedCycles(order [][]int, limits *SizeLimits, from int, to int, moved int) *resizedCycles {
+	r := &resizedCycles{
+		before:   make([]int, len(order)),
+		changed:  make([]bool, len(order)),
+		bound:    make([]int, len(order)),
+		relocate: make([][]bool, len(order)),
+		or_opt:   make([][][]bool, MaxOrOptSegment),
+	}
+	for c := range order {
+		r.before[c] = len(order[c])
+	}
+	r.before[from] += moved
+	r.before[to] -= moved
+	for c := range order {
+		r.changed[c] = r.before[c] != len(order[c])
+		r.bound[c] = min(r.before[c], len(order[c]))
+		r.relocate[c] = make([]bool, len(order))
+		for c2 := range order {
+			r.relocate[c][c2] = limits.CanRelocate(order, c, c2)
+		}
+	}
+	for length := 1; length <= MaxOrOptSegment; length++ {
+		r.or_opt[length-1] = make([][]bool, len(order))
+		for c1 := range order {
+			r.or_opt[length-1][c1] = make([]bool, len(order))
+			for c2 := range order {
+				r.or_opt[length-1][c1][c2] = OrOptAllowed(order, limits, c1, c2, length)
+			}
+		}
+	}
+	return r
+}
+
+// aktualizacja ruchów bez delt po ruchu move, który przeniósł wierzchołki między cyklami (order - cykle po ruchu):
+// usunięcie ruchów z indeksami, których znaczenie zależy od rozmiaru zmienionych cykli, lub między cyklami, które
+// straciły możliwość przeniesienia, i dodanie ruchów z takimi indeksami oraz między parami cykli z limits.Unlocked
+func UpdateMovesNoDistance(all_moves []Move, order [][]int, limits *SizeLimits, move Move, edges bool, or_opt bool) []Move {
+	from, to, moved := MovedNodes(move)
+	if moved == 0 {
+		return all_moves // rozmiary cykli bez zmian - indeksy aktualne
+	}
+	r := newResizedCycles(order, limits, from, to, moved)
+
+	kept := all_moves[:0]
+	for _, m := range all_moves {
+		if r.stable(m) {
+			kept = append(kept, m)
+		}
+	}
+	all_moves = kept
+
+	// ruchy z indeksami poza wspólną częścią cykli przed i po ruchu
+	for c1 := range order {
+		for c2 := c1 + 1; c2 < len(order); c2++ {
+			tailPairs(len(order[c1]), r.bound[c1], len(order[c2]), r.bound[c2], func(i int, j int) {
+				all_moves = append(all_moves, &SwapMove{C1: c1, C2: c2, N1: i, N2: j, Delta: math.MaxInt})
+			})
+		}
+	}
+	unlocked := limits.Unlocked(order, from, to, moved, 1)
+	for c1 := range order {
+		for c2 := range order {
+			if !r.relocate[c1][c2] {
+				continue
+			}
+			s1, s2 := r.bound[c1], r.bound[c2]
+			if slices.Contains(unlocked, Pair[int]{A: c1, B: c2}) {
+				s1, s2 = 0, 0 // przeniesienia wcześniej niedozwolone - wszystkie ruchy nowe
+			}
+			tailPairs(len(order[c1]), s1, len(order[c2]), s2, func(i int, j int) {
+				all_moves = append(all_moves, &RelocateMove{C1: c1, C2: c2, N1: i, N2: j, Delta: math.MaxInt})
+			})
+		}
+	}
+	for _, c := range []int{from, to} {
+		for j := r.bound[c]; j < len(order[c]); j++ {
+			for i := range j {
+				if edges {
+					all_moves = append(all_moves, &MoveEdge{Cycle: c, N1: i, N2: j, Delta: math.MaxInt})
+				} else {
+					all_moves = append(all_moves, &MoveNode{Cycle: c, N1: i, N2: j, Delta: math.MaxInt})
+				}
+			}
+		}
+	}
+	if or_opt {
+		or_opt_moves := orOptMovesNoDistanceAfter(order, limits, r, from, to, moved)
+		for m := range or_opt_moves {
+			all_moves = append(all_moves, &or_opt_moves[m])
+		}
+	}
+	return all_moves
+}
+
+// czy ruch bez delty zachowuje znaczenie po zmianie rozmiarów cykli
+func (r *resizedCycles) stable(move Move) bool {
+	switch m := move.(type) {
+	case *SwapMove:
+		return m.N1 < r.bound[m.C1] && m.N2 < r.bound[m.C2]
+	case *RelocateMove:
+		return r.relocate[m.C1][m.C2] && m.N1 < r.bound[m.C1] && m.N2 < r.bound[m.C2]
+	case *MoveNode:
+		return m.N2 < r.bound[m.Cycle] // N1 < N2
+	case *MoveEdge:
+		return m.N2 < r.bound[m.Cycle]
+	case *OrOptMove:
+		return r.orOptStable(m)
+	}
+	return false
+}
+
+// pary indeksów (i, j) z [0, n1) x [0, n2), w których i >= s1 lub j >= s2
+func tailPairs(n1 int, s1 int, n2 int, s2 int, fn func(i int, j int)) {
+	for i := s1; i < n1; i++ {
+		for j := range n2 {
+			fn(i, j)
+		}
+	}
+	for i := range min(s1, n1) {
+		for j := s2; j < n2; j++ {
+			fn(i, j)
+		}
+	}
+}
+
+func CalculateDelta(move Move, distance_matrix *utils.DistanceMatrix, order [][]int) int {
 	var (
 		delta      int = 0 // zmiana długości cyklu po dodaniu krawędzi
 		n1         int     // wierzchołek 1 - nr w cyklu
@@ -320,13 +581,19 @@ func CalculateDelta(move Move, distance_matrix [][]int, order [][]int) int {
 		ai = utils.ElemAfter(order[m.Cycle], n1)  // wierzchołek po i w cyklu 1
 		aj = utils.ElemAfter(order[m.Cycle], n2)  // wierzchołek po j w cyklu 2
 	case *SwapMove:
-		n1, n2 = m.N1, m.N2                 // wierzchołki 1 i 2 - nr w cyklu
-		curr_node1 = order[0][m.N1]         // wierzchołek aktualny w cyklu 1
-		curr_node2 = order[1][m.N2]         // wierzchołek aktualny w cyklu 2
-		bi = utils.ElemBefore(order[0], n1) // wierzchołek przed i w cyklu 1
-		bj = utils.ElemBefore(order[1], n2) // wierzchołek przed j w cyklu 2
-		ai = utils.ElemAfter(order[0], n1)  // wierzchołek po i w cyklu 1
-		aj = utils.ElemAfter(order[1], n2)  // wierzchołek po j w cyklu 2
+		n1, n2 = m.N1, m.N2                    // wierzchołki 1 i 2 - nr w cyklu
+		curr_node1 = order[m.C1][m.N1]         // wierzchołek aktualny w cyklu 1
+		curr_node2 = order[m.C2][m.N2]         // wierzchołek aktualny w cyklu 2
+		bi = utils.ElemBefore(order[m.C1], n1) // wierzchołek przed i w cyklu 1
+		bj = utils.ElemBefore(order[m.C2], n2) // wierzchołek przed j w cyklu 2
+		ai = utils.ElemAfter(order[m.C1], n1)  // wierzchołek po i w cyklu 1
+		aj = utils.ElemAfter(order[m.C2], n2)  // wierzchołek po j w cyklu 2
+	case *RelocateMove:
+		curr_node1 = order[m.C1][m.N1]           // przenoszony wierzchołek
+		bi = utils.ElemBefore(order[m.C1], m.N1) // wierzchołek przed i w cyklu 1
+		ai = utils.ElemAfter(order[m.C1], m.N1)  // wierzchołek po i w cyklu 1
+		bj = utils.ElemBefore(order[m.C2], m.N2) // wierzchołek przed miejscem wstawienia w cyklu 2
+		curr_node2 = order[m.C2][m.N2]           // wierzchołek po miejscu wstawienia w cyklu 2
 	case *MoveEdge:
 		n1, n2 = m.N1, m.N2                      // wierzchołki 1 i 2 - nr w cyklu
 		curr_node1 = order[m.Cycle][m.N1]        // wierzchołek aktualny w cyklu 1
@@ -337,44 +604,70 @@ func CalculateDelta(move Move, distance_matrix [][]int, order [][]int) int {
 
 	switch m := move.(type) {
 	case *SwapMove:
-		delta = distance_matrix[bi][curr_node2] + distance_matrix[curr_node2][ai] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-			distance_matrix[bj][curr_node1] + distance_matrix[curr_node1][aj] -
-			distance_matrix[bi][curr_node1] - distance_matrix[curr_node1][ai] - // dystansy od wierzchołków przed i po aktualnych przed zamianą
-			distance_matrix[bj][curr_node2] - distance_matrix[curr_node2][aj] // dystansy od wierzchołków przed i po aktualnych przed zamianą
+		delta = distance_matrix.At(bi, curr_node2) + distance_matrix.At(curr_node2, ai) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+			distance_matrix.At(bj, curr_node1) + distance_matrix.At(curr_node1, aj) -
+			distance_matrix.At(bi, curr_node1) - distance_matrix.At(curr_node1, ai) - // dystansy od wierzchołków przed i po aktualnych przed zamianą
+			distance_matrix.At(bj, curr_node2) - distance_matrix.At(curr_node2, aj) // dystansy od wierzchołków przed i po aktualnych przed zamianą
 		m.Delta = delta // ustaw zmianę długości cyklu na mniejszą
 	case *MoveNode:
 		if bi == curr_node2 { // jeśli wierzchołki są sąsiadami w cyklu (j przed i)
-			delta = distance_matrix[curr_node1][bj] + distance_matrix[curr_node2][ai] - // dystansy od wierzchołków przed i po aktualnych po zamianie
-				distance_matrix[curr_node1][ai] - distance_matrix[curr_node2][bj] // dystansy od wierzchołków przed i po aktualnych przed zamianą
+			delta = distance_matrix.At(curr_node1, bj) + distance_matrix.At(curr_node2, ai) - // dystansy od wierzchołków przed i po aktualnych po zamianie
+				distance_matrix.At(curr_node1, ai) - distance_matrix.At(curr_node2, bj) // dystansy od wierzchołków przed i po aktualnych przed zamianą
 		} else if ai == curr_node2 { // jeśli wierzchołki są sąsiadami w cyklu (i przed j)
-			delta = distance_matrix[curr_node1][aj] + distance_matrix[curr_node2][bi] - // dystansy od wierzchołków przed i po aktualnych po zamianie
-				distance_matrix[curr_node1][bi] - distance_matrix[curr_node2][aj] // dystansy od wierzchołków przed i po aktualnych przed zamianą
+			delta = distance_matrix.At(curr_node1, aj) + distance_matrix.At(curr_node2, bi) - // dystansy od wierzchołków przed i po aktualnych po zamianie
+				distance_matrix.At(curr_node1, bi) - distance_matrix.At(curr_node2, aj) // dystansy od wierzchołków przed i po aktualnych przed zamianą
 		} else { // jeśli wierzchołki nie są sąsiadami w cyklu - tak jak w SwapMove
-			delta = distance_matrix[bi][curr_node2] + distance_matrix[curr_node2][ai] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-				distance_matrix[bj][curr_node1] + distance_matrix[curr_node1][aj] -
-				distance_matrix[bi][curr_node1] - distance_matrix[curr_node1][ai] - // dystansy od wierzchołków przed i po aktualnych przed zamianą
-				distance_matrix[bj][curr_node2] - distance_matrix[curr_node2][aj] // dystansy od wierzchołków przed i po aktualnych przed zamianą
+			delta = distance_matrix.At(bi, curr_node2) + distance_matrix.At(curr_node2, ai) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+				distance_matrix.At(bj, curr_node1) + distance_matrix.At(curr_node1, aj) -
+				distance_matrix.At(bi, curr_node1) - distance_matrix.At(curr_node1, ai) - // dystansy od wierzchołków przed i po aktualnych przed zamianą
+				distance_matrix.At(bj, curr_node2) - distance_matrix.At(curr_node2, aj) // dystansy od wierzchołków przed i po aktualnych przed zamianą
 		}
 		m.Delta = delta // ustaw zmianę długości cyklu na mniejszą
+	case *RelocateMove:
+		delta = RelocateDelta(distance_matrix, curr_node1, bi, ai, bj, curr_node2)
+		m.Delta = delta
+	case *OrOptMove:
+		cycle := order[m.C1]
+		first, last := cycle[m.N1], cycle[(m.N1+m.Len-1)%len(cycle)]                // końce segmentu
+		prev, next := utils.ElemBefore(cycle, m.N1), cycle[(m.N1+m.Len)%len(cycle)] // sąsiedzi segmentu
+		from, to := utils.ElemBefore(order[m.C2], m.N2), order[m.C2][m.N2]          // krawędź, w którą wstawiamy
+		if m.Reversed {
+			delta = OrOptDelta(distance_matrix, last, first, next, prev, from, to)
+		} else {
+			delta = OrOptDelta(distance_matrix, first, last, prev, next, from, to)
+		}
+		m.Delta = delta
+	case *ThreeOptMove:
+		delta = ThreeOptDelta(distance_matrix, order[m.Cycle], m.I, m.J, m.K, m.Case)
+		m.Delta = delta
 	case *MoveEdge:
-		delta = distance_matrix[curr_node1][curr_node2] + distance_matrix[ai][aj] - // dystansy po zamianie krawędzi
-			distance_matrix[ai][curr_node1] - distance_matrix[aj][curr_node2] // dystansy przed zamianą krawędzi
+		delta = distance_matrix.At(curr_node1, curr_node2) + distance_matrix.At(ai, aj) - // dystansy po zamianie krawędzi
+			distance_matrix.At(ai, curr_node1) - distance_matrix.At(aj, curr_node2) // dystansy przed zamianą krawędzi
 		m.Delta = delta // ustaw zmianę długości cyklu na mniejszą
 	}
 	return delta
 }
 
-func FisherYatesShuffle[T comparable](arr []T) []T {
+// zmiana długości cykli po przeniesieniu wierzchołka node spomiędzy prev i next w miejsce krawędzi from - to
+func RelocateDelta(distance_matrix *utils.DistanceMatrix, node int, prev int, next int, from int, to int) int {
+	return distance_matrix.At(prev, next) - distance_matrix.At(prev, node) - distance_matrix.At(node, next) + // usunięcie z cyklu
+		distance_matrix.At(from, node) + distance_matrix.At(node, to) - distance_matrix.At(from, to) // wstawienie do cyklu
+}
+
+func FisherYatesShuffle[T comparable](arr []T, rng *rand.Rand) []T {
 	for i := len(arr) - 1; i > 0; i-- { // iteracja po arr od końca
-		j := rand.Intn(i + 1)           // losowy indeks od 0 do i
+		j := rng.Intn(i + 1)            // losowy indeks od 0 do i
 		arr[i], arr[j] = arr[j], arr[i] // zamień elementy miejscami
 	}
 	return arr // zwróć przetasowaną tablicę
 }
 
-func FindBestMoveGreedy(moves []Move, distance_matrix [][]int, order [][]int) (Move, int) {
-	moves = FisherYatesShuffle(moves) // przetasuj ruchy
-	for m := range moves {            // dla każdego ruchu
+// pierwszy poprawiający ruch w losowej kolejności; moves tasowane na bieżąco (Fisher-Yates od początku tablicy),
+// więc koszt zależy od liczby sprawdzonych ruchów, a nie od długości listy
+func FindBestMoveGreedy(moves []Move, distance_matrix *utils.DistanceMatrix, order [][]int, rng *rand.Rand) (Move, int) {
+	for m := range moves { // dla każdego ruchu
+		k := m + rng.Intn(len(moves)-m)         // losowy ruch spośród niesprawdzonych
+		moves[m], moves[k] = moves[k], moves[m] // zamień elementy miejscami
 		move := moves[m]
 		delta := CalculateDelta(move, distance_matrix, order)
 		if delta < 0 { // jeśli zmiana długości cyklu jest mniejsza od aktualnej i mniejsza od 0
@@ -398,8 +691,8 @@ func FindBestMove(moves []Move) (Move, int) {
 	return best_move, min_delta // zwróć najlepszy ruch i minimalną zmianę długości cyklu
 }
 
-func DistancesBefore(distance_matrix [][]int, order [][]int) [][]int {
-	var distances_before [][]int = make([][]int, NumCycles) // suma dystansów do wierzchołków przed i po aktualnym w cyklu
+func DistancesBefore(distance_matrix *utils.DistanceMatrix, order [][]int) [][]int {
+	var distances_before [][]int = make([][]int, len(order)) // suma dystansów do wierzchołków przed i po aktualnym w cyklu
 
 	for i := range distances_before { // dla każdego cyklu
 		distances_before[i] = make([]int, len(order[i]))
@@ -408,63 +701,138 @@ func DistancesBefore(distance_matrix [][]int, order [][]int) [][]int {
 			// dystans do wierzchołka przed i po aktualnym
 			bj := utils.ElemBefore(order[i], j) // wierzchołek przed j
 			aj := utils.ElemAfter(order[i], j)  // wierzchołek przed j
-			distances_before[i][j] = distance_matrix[bj][curr_node] + distance_matrix[curr_node][aj]
+			distances_before[i][j] = distance_matrix.At(bj, curr_node) + distance_matrix.At(curr_node, aj)
 		}
 	}
 
 	return distances_before
 }
 
-func AllMovesBetweenCycles(distance_matrix [][]int, order [][]int, distances_before [][]int) ([]SwapMove, error) {
+func AllMovesBetweenCycles(distance_matrix *utils.DistanceMatrix, order [][]int, distances_before [][]int) ([]SwapMove, error) {
 	var (
 		moves []SwapMove // aktualnie dostępne ruchy
 	)
 
-	for i := 0; i < len(order[0]); i++ {
-		for j := 0; j < len(order[1]); j++ {
-			// zamiana wierzchołka i z cyklu 1 z j z cyklu 2
-			curr_node1 := order[0][i]
-			curr_node2 := order[1][j]
-			bi := utils.ElemBefore(order[0], i) // wierzchołek przed i w cyklu 1
-			bj := utils.ElemBefore(order[1], j) // wierzchołek przed j w cyklu 2
-			ai := utils.ElemAfter(order[0], i)  // wierzchołek po i w cyklu 1
-			aj := utils.ElemAfter(order[1], j)  // wierzchołek po j w cyklu 2
+	// dla każdej pary cykli
+	for c1 := 0; c1 < len(order); c1++ {
+		for c2 := c1 + 1; c2 < len(order); c2++ {
+			for i := 0; i < len(order[c1]); i++ {
+				for j := 0; j < len(order[c2]); j++ {
+					// zamiana wierzchołka i z cyklu c1 z j z cyklu c2
+					curr_node1 := order[c1][i]
+					curr_node2 := order[c2][j]
+					bi := utils.ElemBefore(order[c1], i) // wierzchołek przed i w cyklu c1
+					bj := utils.ElemBefore(order[c2], j) // wierzchołek przed j w cyklu c2
+					ai := utils.ElemAfter(order[c1], i)  // wierzchołek po i w cyklu c1
+					aj := utils.ElemAfter(order[c2], j)  // wierzchołek po j w cyklu c2
+
+					delta := distance_matrix.At(bi, curr_node2) + distance_matrix.At(curr_node2, ai) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+						distance_matrix.At(bj, curr_node1) + distance_matrix.At(curr_node1, aj) -
+						distances_before[c1][i] - distances_before[c2][j]
+
+					// dodaj ruch do listy
+					moves = append(moves, SwapMove{
+						C1:    c1,
+						C2:    c2,
+						N1:    i,
+						N2:    j,
+						Delta: delta,
+					})
+				}
+			}
+		}
+	}
 
-			delta := distance_matrix[bi][curr_node2] + distance_matrix[curr_node2][ai] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-				distance_matrix[bj][curr_node1] + distance_matrix[curr_node1][aj] -
-				distances_before[0][i] - distances_before[1][j]
+	return moves, nil
+}
 
-			// dodaj ruch do listy
-			moves = append(moves, SwapMove{
-				N1:    i,
-				N2:    j,
-				Delta: delta,
-			})
+// wszystkie przeniesienia wierzchołka do innego cyklu dopuszczone przez limits
+func AllRelocateMoves(distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, distances_before [][]int) []RelocateMove {
+	var (
+		moves []RelocateMove // aktualnie dostępne ruchy
+	)
+	if !limits.Flexible() {
+		return moves // stałe rozmiary cykli
+	}
+
+	for c1 := range order {
+		for c2 := range order {
+			if !limits.CanRelocate(order, c1, c2) {
+				continue
+			}
+			for i := range order[c1] {
+				curr_node := order[c1][i]
+				bi := utils.ElemBefore(order[c1], i)                            // wierzchołek przed i w cyklu c1
+				ai := utils.ElemAfter(order[c1], i)                             // wierzchołek po i w cyklu c1
+				removed := distance_matrix.At(bi, ai) - distances_before[c1][i] // zmiana długości cyklu c1 po usunięciu wierzchołka
+				for j := range order[c2] {
+					bj := utils.ElemBefore(order[c2], j) // wierzchołek przed j w cyklu c2
+					inserted := distance_matrix.At(bj, curr_node) + distance_matrix.At(curr_node, order[c2][j]) - distance_matrix.At(bj, order[c2][j])
+					moves = append(moves, RelocateMove{
+						C1:    c1,
+						C2:    c2,
+						N1:    i,
+						N2:    j,
+						Delta: removed + inserted,
+					})
+				}
+			}
 		}
 	}
 
-	return moves, nil
+	return moves
+}
+
+func AllRelocateMovesNoDistance(order [][]int, limits *SizeLimits) []RelocateMove {
+	var (
+		moves []RelocateMove // aktualnie dostępne ruchy
+	)
+	for c1 := range order {
+		for c2 := range order {
+			if !limits.CanRelocate(order, c1, c2) {
+				continue
+			}
+			for i := range order[c1] {
+				for j := range order[c2] {
+					moves = append(moves, RelocateMove{
+						C1:    c1,
+						C2:    c2,
+						N1:    i,
+						N2:    j,
+						Delta: math.MaxInt,
+					})
+				}
+			}
+		}
+	}
+	return moves
 }
 
 func AllMovesBetweenCyclesNoDistance(order [][]int) ([]SwapMove, error) {
 	var (
 		moves []SwapMove // aktualnie dostępne ruchy
 	)
-	for i := 0; i < len(order[0]); i++ {
-		for j := 0; j < len(order[1]); j++ {
-			// zamiana wierzchołka i z cyklu 1 z j z cyklu 2
-			moves = append(moves, SwapMove{
-				N1:    i,
-				N2:    j,
-				Delta: math.MaxInt,
-			})
+	for c1 := 0; c1 < len(order); c1++ {
+		for c2 := c1 + 1; c2 < len(order); c2++ {
+			for i := 0; i < len(order[c1]); i++ {
+				for j := 0; j < len(order[c2]); j++ {
+					// zamiana wierzchołka i z cyklu c1 z j z cyklu c2
+					moves = append(moves, SwapMove{
+						C1:    c1,
+						C2:    c2,
+						N1:    i,
+						N2:    j,
+						Delta: math.MaxInt,
+					})
+				}
+			}
 		}
 	}
 
 	return moves, nil
 }
 
-func AllMovesNodesCycle(distance_matrix [][]int, order []int, cycle int, distances_before []int) []MoveNode {
+func AllMovesNodesCycle(distance_matrix *utils.DistanceMatrix, order []int, cycle int, distances_before []int) []MoveNode {
 	var (
 		n1         int        // wierzchołek 1
 		n2         int        // wierzchołek 2
@@ -482,14 +850,14 @@ func AllMovesNodesCycle(distance_matrix [][]int, order []int, cycle int, distanc
 			ai := utils.ElemAfter(order, i)  // wierzchołek po i w cyklu
 			aj := utils.ElemAfter(order, j)  // wierzchołek po j w cyklu
 			if bi == n2 {                    // jeśli wierzchołki są sąsiadami w cyklu (j przed i)
-				delta = distance_matrix[n1][bj] + distance_matrix[n2][ai] - // dystansy od wierzchołków przed i po aktualnych po zamianie
-					distance_matrix[n1][ai] - distance_matrix[n2][bj] // dystansy od wierzchołków przed i po aktualnych przed zamianą
+				delta = distance_matrix.At(n1, bj) + distance_matrix.At(n2, ai) - // dystansy od wierzchołków przed i po aktualnych po zamianie
+					distance_matrix.At(n1, ai) - distance_matrix.At(n2, bj) // dystansy od wierzchołków przed i po aktualnych przed zamianą
 			} else if ai == n2 { // jeśli wierzchołki są sąsiadami w cyklu (i przed j)
-				delta = distance_matrix[n1][aj] + distance_matrix[n2][bi] - // dystansy od wierzchołków przed i po aktualnych po zamianie
-					distance_matrix[n1][bi] - distance_matrix[n2][aj] // dystansy od wierzchołków przed i po aktualnych przed zamianą
+				delta = distance_matrix.At(n1, aj) + distance_matrix.At(n2, bi) - // dystansy od wierzchołków przed i po aktualnych po zamianie
+					distance_matrix.At(n1, bi) - distance_matrix.At(n2, aj) // dystansy od wierzchołków przed i po aktualnych przed zamianą
 			} else { // jeśli wierzchołki nie są sąsiadami w cyklu
-				delta = distance_matrix[bi][n2] + distance_matrix[n2][ai] + // dystansy od wierzchołków przed i po aktualnych po zamianie
-					distance_matrix[bj][n1] + distance_matrix[n1][aj] -
+				delta = distance_matrix.At(bi, n2) + distance_matrix.At(n2, ai) + // dystansy od wierzchołków przed i po aktualnych po zamianie
+					distance_matrix.At(bj, n1) + distance_matrix.At(n1, aj) -
 					distances_before[i] - distances_before[j] // dystansy od wierzchołków przed i po aktualnych przed zamianą
 			}
 
@@ -528,7 +896,7 @@ func AllMovesNodesCycleNoDistance(order []int, cycle int) []MoveNode {
 	return moves_node
 }
 
-func AllMovesEdgesCycle(distance_matrix [][]int, order []int, cycle int) []MoveEdge {
+func AllMovesEdgesCycle(distance_matrix *utils.DistanceMatrix, order []int, cycle int) []MoveEdge {
 	var (
 		n1         int        // wierzchołek 1
 		n2         int        // wierzchołek 2
@@ -542,8 +910,8 @@ func AllMovesEdgesCycle(distance_matrix [][]int, order []int, cycle int) []MoveE
 			n1, n2 = order[i], order[j] // wierzchołki 1 i 2 - nr w cyklu
 			ai := utils.ElemAfter(order, i)
 			aj := utils.ElemAfter(order, j)
-			delta = distance_matrix[n1][n2] + distance_matrix[ai][aj] - // dystansy po zamianie krawędzi
-				distance_matrix[ai][n1] - distance_matrix[aj][n2] // dystansy przed zamianą krawędzi
+			delta = distance_matrix.At(n1, n2) + distance_matrix.At(ai, aj) - // dystansy po zamianie krawędzi
+				distance_matrix.At(ai, n1) - distance_matrix.At(aj, n2) // dystansy przed zamianą krawędzi
 			moves_node = append(moves_node, MoveEdge{
 				N1:    i,
 				N2:    j,
@@ -0,0 +1,76 @@
+package solver
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// klucze ruchów do porównania zbiorów ruchów niezależnie od kolejności
+func moveKeys(moves []Move) []string {
+	keys := make([]string, len(moves))
+	for m, move := range moves {
+		keys[m] = fmt.Sprintf("%T%+v", move, move)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ruchy zachłanne aktualizowane po zmianie rozmiarów cykli muszą być tym samym zbiorem co zbudowane od nowa
+func TestUpdateMovesNoDistance(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		sizes     []int
+		tolerance int
+		edges     bool
+		or_opt    bool
+	}{
+		{"nodes", []int{7, 7, 6}, 2, false, false},
+		{"edges", []int{7, 7, 6}, 3, true, false},
+		{"or-opt", []int{7, 7, 6}, 3, true, true},
+		{"or-opt four cycles", []int{6, 6, 5, 5}, 2, true, true},
+		{"or-opt small cycles", []int{5, 5}, 2, true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			num_nodes := 0
+			for _, s := range tc.sizes {
+				num_nodes += s
+			}
+			limits, err := NewSizeLimits(num_nodes, tc.sizes, tc.tolerance, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := randomOrder(tc.sizes, rng)
+			tour := NewTour(order)
+			all_moves, err := AllMovesNoDistance(order, limits, tc.edges, tc.or_opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for step := range 50 {
+				var resizing []Move
+				for _, m := range all_moves {
+					if ChangesCycleSizes(m) {
+						resizing = append(resizing, m)
+					}
+				}
+				if len(resizing) == 0 {
+					t.Fatalf("step %d: no move changing cycle sizes", step)
+				}
+				move := resizing[rng.Intn(len(resizing))]
+				if err := move.ExecuteMove(tour); err != nil {
+					t.Fatalf("step %d: %v", step, err)
+				}
+				all_moves = UpdateMovesNoDistance(all_moves, order, limits, move, tc.edges, tc.or_opt)
+				expected, err := AllMovesNoDistance(order, limits, tc.edges, tc.or_opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := moveKeys(all_moves), moveKeys(expected); !slices.Equal(got, want) {
+					t.Fatalf("step %d after %T%+v: %d moves, expected %d", step, move, move, len(got), len(want))
+				}
+			}
+		})
+	}
+}
@@ -3,239 +3,331 @@ package solver
 import (
 	"IMO/reader"
 	"IMO/utils"
+	"context"
+	"fmt"
 	"math"
 	"math/rand"
-	"time"
 )
 
-func MSLS(distance_matrix [][]int, order [][]int, nodes []reader.Node, num_iterations int) (int, error) {
+func init() {
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicMSLS), Description: "multiple start local search", Parameters: []string{"heuristic", "local_search", "iterations"}}, MSLS)
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicILS), Description: "iterated local search", Parameters: []string{"heuristic", "local_search", "perturbation_ratio", "time_limit"}, TimeBounded: true}, ILS)
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicLNSWithLS), Description: "large neighbourhood search with local search", Parameters: []string{"heuristic", "local_search", "destroy_ratio", "time_limit"}, TimeBounded: true}, LNSWithLS)
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicLNS), Description: "large neighbourhood search", Parameters: []string{"heuristic", "local_search", "destroy_ratio", "time_limit"}, TimeBounded: true}, LNSWithoutLS)
+}
+
+func MSLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
 	var (
-		cost       int     = math.MaxInt              // koszt rozwiązania najlepszego
-		length     int                                // długość aktualnych cykli
-		best_order [][]int = make([][]int, NumCycles) // najlepsze cykle
+		cost       int     = math.MaxInt               // koszt rozwiązania najlepszego
+		length     int                                 // długość aktualnych cykli
+		best_order [][]int = make([][]int, len(order)) // najlepsze cykle
 	)
-	for _ = range num_iterations { // pusta pętla
-		err := Random(distance_matrix, order, nodes) // losu losu
+	construct, local_search, err := metaheuristicFuncs(cfg, rng)
+	if err != nil {
+		return 0, err
+	}
+	progress := newProgress(cfg) // zdarzenia dla cfg.Observer
+	iter := 0
+	for ; iter < cfg.Iterations && (iter == 0 || ctx.Err() == nil); iter++ { // przynajmniej jedna iteracja - jest rozwiązanie do zwrócenia
+		for c := range order {
+			order[c] = make([]int, limits.Target[c]) // przywrócenie docelowych długości cykli
+		}
+		err := construct(distance_matrix, order, nodes) // losu losu
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("start solution: %w", err)
 		}
-		err = SteepestEdge(distance_matrix, order) // najlepszy algorytm ls
+		progress.restart(iter, utils.CalculateCyclesLen(order, distance_matrix), order)
+		err = local_search(ctx, distance_matrix, order, limits) // lokalne przeszukiwanie
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("local search: %w", err)
 		}
-		length = utils.CalculateCycleLen(order[0], distance_matrix) + utils.CalculateCycleLen(order[1], distance_matrix)
+		length = utils.CalculateCyclesLen(order, distance_matrix)
 		if length < cost {
 			cost = length
-			utils.CopyCycles(best_order, order)
+			err = utils.CopyCycles(best_order, order)
+			if err != nil {
+				return iter, err
+			}
 		}
+		progress.iteration(iter+1, length, order)
+	}
+	err = utils.CopyCycles(order, best_order)
+	if err != nil {
+		return iter, err
 	}
-	utils.CopyCycles(order, best_order)
-	return num_iterations, nil
+	return iter, nil
 }
 
-func ILS(distance_matrix [][]int, order [][]int, nodes []reader.Node, alg_time int) (int, error) {
+func ILS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
 	var (
-		cost               int     = math.MaxInt              // koszt rozwiązania najlepszego
-		length             int                                // długość aktualnych cykli
-		best_order         [][]int = make([][]int, NumCycles) // najlepsze cykle
-		perturbation_ratio float32 = 0.3                      // współczynnik perturbacji
-		start_time         time.Time = time.Now() // czas rozpoczęcia algorytmu
-		iter               int = 0 // liczba iteracji
+		cost       int     = math.MaxInt               // koszt rozwiązania najlepszego
+		length     int                                 // długość aktualnych cykli
+		best_order [][]int = make([][]int, len(order)) // najlepsze cykle
+		iter       int     = 0                         // liczba iteracji
 	)
-	err := Random(distance_matrix, order, nodes) // losu losu startowe
+	construct, local_search, err := metaheuristicFuncs(cfg, rng)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := cfg.withTimeLimit(ctx) // limit czasu; wcześniejsze zakończenie ctx też kończy algorytm
+	defer cancel()
+	progress := newProgress(cfg)
+	err = construct(distance_matrix, order, nodes) // losu losu startowe
 	if err != nil {
-		panic("Error")
+		return iter, fmt.Errorf("start solution: %w", err)
 	}
-	err = SteepestEdge(distance_matrix, order) // startowy local search
+	progress.restart(iter, utils.CalculateCyclesLen(order, distance_matrix), order)
+	err = local_search(ctx, distance_matrix, order, limits) // startowy local search
 	if err != nil {
-		panic("Error")
+		return iter, fmt.Errorf("local search: %w", err)
 	}
-	utils.CopyCycles(best_order, order)
-	for int(time.Since(start_time).Seconds()) < alg_time { // pętla czasowa
-		utils.CopyCycles(order, best_order)
-		err = Perturbarion(order, perturbation_ratio) // nałożenie perturbacji
+	err = utils.CopyCycles(best_order, order)
+	if err != nil {
+		return iter, err
+	}
+	cost = utils.CalculateCyclesLen(best_order, distance_matrix) // punkt odniesienia dla pierwszej iteracji
+	progress.best(iter, cost, best_order)
+	for cfg.withinBudget(ctx, iter) { // pętla czasowa
+		err = utils.CopyCycles(order, best_order)
+		if err != nil {
+			return iter, err
+		}
+		err = Perturbarion(order, cfg.PerturbationRatio, rng) // nałożenie perturbacji
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("perturbation: %w", err)
 		}
-		err = SteepestEdge(distance_matrix, order) // local search w celu poprawy jakości
+		err = local_search(ctx, distance_matrix, order, limits) // local search w celu poprawy jakości
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("local search: %w", err)
 		}
-		length = utils.CalculateCycleLen(order[0], distance_matrix) + utils.CalculateCycleLen(order[1], distance_matrix)
+		length = utils.CalculateCyclesLen(order, distance_matrix)
 		if length < cost { // warunek na poprawę rozwiązania
 			cost = length
-			utils.CopyCycles(best_order, order)
+			err = utils.CopyCycles(best_order, order)
+			if err != nil {
+				return iter, err
+			}
 		}
 		iter += 1
+		progress.iteration(iter, length, order)
+	}
+	err = utils.CopyCycles(order, best_order)
+	if err != nil {
+		return iter, err
 	}
-	utils.CopyCycles(order, best_order)
 	return iter, nil
 }
-func LNSWithLS(distance_matrix [][]int, order [][]int, nodes []reader.Node, alg_time int) (int, error) {
+func LNSWithLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
 	var (
-		cost          int     = math.MaxInt              // koszt rozwiązania najlepszego
-		length        int                                // długość aktualnych cykli
-		best_order    [][]int = make([][]int, NumCycles) // najlepsze cykle
-		destroy_ratio float32 = 0.3                      // współczynnik niszczenia
-		start_time         time.Time = time.Now() // czas rozpoczęcia algorytmu
-		iter               int = 0 // liczba iteracji
+		cost       int     = math.MaxInt               // koszt rozwiązania najlepszego
+		length     int                                 // długość aktualnych cykli
+		best_order [][]int = make([][]int, len(order)) // najlepsze cykle
+		iter       int     = 0                         // liczba iteracji
 	)
-	err := Random(distance_matrix, order, nodes) // losu losu startowe
+	construct, local_search, err := metaheuristicFuncs(cfg, rng)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := cfg.withTimeLimit(ctx) // limit czasu; wcześniejsze zakończenie ctx też kończy algorytm
+	defer cancel()
+	progress := newProgress(cfg)
+	err = construct(distance_matrix, order, nodes) // losu losu startowe
+	if err != nil {
+		return iter, fmt.Errorf("start solution: %w", err)
+	}
+	progress.restart(iter, utils.CalculateCyclesLen(order, distance_matrix), order)
+	err = local_search(ctx, distance_matrix, order, limits) // startowy local search
 	if err != nil {
-		panic("Error")
+		return iter, fmt.Errorf("local search: %w", err)
 	}
-	err = SteepestEdge(distance_matrix, order) // startowy local search
+	err = utils.CopyCycles(best_order, order)
 	if err != nil {
-		panic("Error")
+		return iter, err
 	}
-	utils.CopyCycles(best_order, order)
-	for int(time.Since(start_time).Seconds()) < alg_time { // pętla czasowa
-		utils.CopyCycles(order, best_order)
-		err = Destroy(order, destroy_ratio) // niszczymy jakiś procent wierzchołków
+	cost = utils.CalculateCyclesLen(best_order, distance_matrix) // punkt odniesienia dla pierwszej iteracji
+	progress.best(iter, cost, best_order)
+	for cfg.withinBudget(ctx, iter) { // pętla czasowa
+		err = utils.CopyCycles(order, best_order)
+		if err != nil {
+			return iter, err
+		}
+		err = Destroy(order, cfg.DestroyRatio, rng) // niszczymy jakiś procent wierzchołków
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("destroy: %w", err)
 		}
-		err = Repair(order, distance_matrix, nodes) // naprawiamy szkody przy pomocy greedy cycle (tylko ta metoda działa dla naszej implementacji)
+		err = Repair(order, distance_matrix, nodes, limits, rng) // naprawiamy szkody przy pomocy greedy cycle (tylko ta metoda działa dla naszej implementacji)
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("repair: %w", err)
 		}
-		err = SteepestEdge(distance_matrix, order) // dodatkowy local search
+		err = local_search(ctx, distance_matrix, order, limits) // dodatkowy local search
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("local search: %w", err)
 		}
-		length = utils.CalculateCycleLen(order[0], distance_matrix) + utils.CalculateCycleLen(order[1], distance_matrix)
+		length = utils.CalculateCyclesLen(order, distance_matrix)
 		if length < cost {
 			cost = length
-			utils.CopyCycles(best_order, order)
+			err = utils.CopyCycles(best_order, order)
+			if err != nil {
+				return iter, err
+			}
 		}
 		iter += 1
+		progress.iteration(iter, length, order)
+	}
+	err = utils.CopyCycles(order, best_order)
+	if err != nil {
+		return iter, err
 	}
-	utils.CopyCycles(order, best_order)
 	return iter, nil
 }
-func LNSWithoutLS(distance_matrix [][]int, order [][]int, nodes []reader.Node, alg_time int) (int, error) {
+func LNSWithoutLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
 	var (
-		cost          int     = math.MaxInt              // koszt rozwiązania najlepszego
-		length        int                                // długość aktualnych cykli
-		best_order    [][]int = make([][]int, NumCycles) // najlepsze cykle
-		destroy_ratio float32 = 0.3                      // współczynnik niszczenia
-		start_time         time.Time = time.Now() // czas rozpoczęcia algorytmu
-		iter               int = 0 // liczba iteracji
+		cost       int     = math.MaxInt               // koszt rozwiązania najlepszego
+		length     int                                 // długość aktualnych cykli
+		best_order [][]int = make([][]int, len(order)) // najlepsze cykle
+		iter       int     = 0                         // liczba iteracji
 	)
-	err := Random(distance_matrix, order, nodes) // losu losu startowe
+	construct, local_search, err := metaheuristicFuncs(cfg, rng)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := cfg.withTimeLimit(ctx) // limit czasu; wcześniejsze zakończenie ctx też kończy algorytm
+	defer cancel()
+	progress := newProgress(cfg)
+	err = construct(distance_matrix, order, nodes) // losu losu startowe
+	if err != nil {
+		return iter, fmt.Errorf("start solution: %w", err)
+	}
+	progress.restart(iter, utils.CalculateCyclesLen(order, distance_matrix), order)
+	err = local_search(ctx, distance_matrix, order, limits) // local search startowy
 	if err != nil {
-		panic("Error")
+		return iter, fmt.Errorf("local search: %w", err)
 	}
-	err = SteepestEdge(distance_matrix, order) // local search startowy
+	err = utils.CopyCycles(best_order, order)
 	if err != nil {
-		panic("Error")
+		return iter, err
 	}
-	utils.CopyCycles(best_order, order)
-	for int(time.Since(start_time).Seconds()) < alg_time { // pętla czasowa
-		utils.CopyCycles(order, best_order)
-		err = Destroy(order, destroy_ratio) // niszyczymy ileś wierzchołków
+	cost = utils.CalculateCyclesLen(best_order, distance_matrix) // punkt odniesienia dla pierwszej iteracji
+	progress.best(iter, cost, best_order)
+	for cfg.withinBudget(ctx, iter) { // pętla czasowa
+		err = utils.CopyCycles(order, best_order)
 		if err != nil {
-			panic("Error")
+			return iter, err
 		}
-		err = Repair(order, distance_matrix, nodes) // naprawa przy pomocy greedy cycle
+		err = Destroy(order, cfg.DestroyRatio, rng) // niszyczymy ileś wierzchołków
 		if err != nil {
-			panic("Error")
+			return iter, fmt.Errorf("destroy: %w", err)
 		}
-		length = utils.CalculateCycleLen(order[0], distance_matrix) + utils.CalculateCycleLen(order[1], distance_matrix)
+		err = Repair(order, distance_matrix, nodes, limits, rng) // naprawa przy pomocy greedy cycle
+		if err != nil {
+			return iter, fmt.Errorf("repair: %w", err)
+		}
+		length = utils.CalculateCyclesLen(order, distance_matrix)
 		if length < cost {
 			cost = length
-			utils.CopyCycles(best_order, order)
+			err = utils.CopyCycles(best_order, order)
+			if err != nil {
+				return iter, err
+			}
 		}
 		iter += 1
+		progress.iteration(iter, length, order)
+	}
+	err = utils.CopyCycles(order, best_order)
+	if err != nil {
+		return iter, err
 	}
-	utils.CopyCycles(order, best_order)
 	return iter, nil
 }
-func Perturbarion(order [][]int, perturbation_ratio float32) error {
+
+// heurystyka startowa i lokalne przeszukiwanie metaheurystyki według cfg, korzystające z generatora rng
+func metaheuristicFuncs(cfg *Config, rng *rand.Rand) (func(*utils.DistanceMatrix, [][]int, []reader.Node) error, func(context.Context, *utils.DistanceMatrix, [][]int, *SizeLimits) error, error) {
+	construct, err := HeuristicFunc(cfg, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+	local_search, err := LocalSearchFunc(cfg, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+	return construct, local_search, nil
+}
+func Perturbarion(order [][]int, perturbation_ratio float32, rng *rand.Rand) error {
 	var (
-		num_of_max_perturbation int  = int(perturbation_ratio * float32(len(order[0]))) // maksymalna liczba przemieszań
-		num_of_perturbation_c1  int  = 0                                                // liczba przemieszań dla cyklu pierwszego
-		num_of_perturbation_c2  int  = 0                                                // liczba przemieszań dla cyklu drugiego
-		sw1                     int  = -1                                               // indeks zamiany 1
-		sw2                     int  = -1                                               // indeks zamiany 2
-		rand_move               int                                                     // indeks losowego ruchu od 0-2
-		move                    Move                                                    // wykonywany losowy ruch
+		num_of_perturbation []int = make([]int, len(order)) // liczba przemieszań dla każdego cyklu
+		max_perturbation    int   = 0                       // najwięcej przemieszań w jednym cyklu
+		sw1                 int   = -1                      // indeks zamiany 1
+		sw2                 int   = -1                      // indeks zamiany 2
+		rand_move           int                             // indeks losowego ruchu od 0-2
+		move_types          int   = 3                       // liczba rodzajów ruchów
+		move                Move                            // wykonywany losowy ruch
+		tour                *Tour = NewTour(order)          // cykle z pozycjami wierzchołków
 	)
-	if num_of_max_perturbation == 0 {
-		panic("Za niski współczynnik ")
-	}
-	for num_of_perturbation_c1 == 0 {
-		num_of_perturbation_c1 = rand.Intn(num_of_max_perturbation) // losu losu ale tak by nie wylosować zera
+	for c := range order {
+		num_of_max_perturbation := int(perturbation_ratio * float32(len(order[c]))) // maksymalna liczba przemieszań
+		if num_of_max_perturbation == 0 {
+			return fmt.Errorf("%w: perturbation ratio %v too low for cycle %d of %d nodes", ErrInvalidConfig, perturbation_ratio, c, len(order[c]))
+		}
+		num_of_perturbation[c] = 1 + rng.Intn(num_of_max_perturbation) // losu losu ale tak by nie wylosować zera
+		max_perturbation = max(max_perturbation, num_of_perturbation[c])
 	}
-	for num_of_perturbation_c2 == 0 {
-		num_of_perturbation_c2 = rand.Intn(num_of_max_perturbation)
+	if len(order) < 2 {
+		move_types = 2 // jeden cykl - brak zamian między cyklami
 	}
 
-	for i := range int(math.Max(float64(num_of_perturbation_c1), float64(num_of_perturbation_c2))) {
-		rand_move = rand.Intn(3)
+	for i := range max_perturbation {
+		rand_move = rng.Intn(move_types)
 		switch rand_move {
 		case 0:
-			if i < num_of_perturbation_c1 {
-				sw1 = rand.Intn(len(order[0]))
-				sw2 = rand.Intn(len(order[0]))
-
-				move = &MoveEdge{Cycle: 0, N1: sw1, N2: sw2, Delta: 0}
-				move.ExecuteMove(order)
-			}
-			if i < num_of_perturbation_c2 {
-				sw1 = rand.Intn(len(order[1]))
-				sw2 = rand.Intn(len(order[1]))
+			for c := range order {
+				if i < num_of_perturbation[c] {
+					sw1 = rng.Intn(len(order[c]))
+					sw2 = rng.Intn(len(order[c]))
 
-				move = &MoveEdge{Cycle: 0, N1: sw1, N2: sw2, Delta: 0} // zamiana krawędzi
-				move.ExecuteMove(order)
+					move = &MoveEdge{Cycle: c, N1: sw1, N2: sw2, Delta: 0} // zamiana krawędzi
+					if err := move.ExecuteMove(tour); err != nil {
+						return err
+					}
+				}
 			}
 		case 1:
-			if i < num_of_perturbation_c1 {
-				sw1 = rand.Intn(len(order[0]))
-				sw2 = rand.Intn(len(order[0]))
+			for c := range order {
+				if i < num_of_perturbation[c] {
+					sw1 = rng.Intn(len(order[c]))
+					sw2 = rng.Intn(len(order[c]))
 
-				move = &MoveNode{Cycle: 0, N1: sw1, N2: sw2, Delta: 0}
-				move.ExecuteMove(order)
-			}
-			if i < num_of_perturbation_c2 {
-				sw1 = rand.Intn(len(order[1]))
-				sw2 = rand.Intn(len(order[1]))
-
-				move = &MoveNode{Cycle: 0, N1: sw1, N2: sw2, Delta: 0} // zamiana krawędzi
-				move.ExecuteMove(order)
+					move = &MoveNode{Cycle: c, N1: sw1, N2: sw2, Delta: 0} // zamiana wierzchołków
+					if err := move.ExecuteMove(tour); err != nil {
+						return err
+					}
+				}
 			}
 		case 2:
-			sw1 = rand.Intn(len(order[0]))
-			sw2 = rand.Intn(len(order[1]))
+			c1, c2, err := utils.Pick2RandomValues(len(order), rng)
+			if err != nil {
+				return err
+			}
+			sw1 = rng.Intn(len(order[c1]))
+			sw2 = rng.Intn(len(order[c2]))
 
-			move = &SwapMove{N1: sw1, N2: sw2, Delta: 0}
-			move.ExecuteMove(order)
+			move = &SwapMove{C1: c1, C2: c2, N1: sw1, N2: sw2, Delta: 0}
+			if err := move.ExecuteMove(tour); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
-func Destroy(order [][]int, destroy_ratio float32) error {
-	var (
-		delete_c1 int = int(destroy_ratio * float32(len(order[0]))) // wyznaczenie liczby wierzchołków do zniknięcia
-		delete_c2 int = int(destroy_ratio * float32(len(order[1])))
-		del       int
-	)
-	for i := range int(math.Max(float64(delete_c1), float64(delete_c2))) {
-		if i < delete_c1 {
-			del = rand.Intn(len(order[0]))         // losu do usunięcia
-			order[0] = utils.Remove(order[0], del) // usuwanie losowego wierzchołka
-		}
-		if i < delete_c2 {
-			del = rand.Intn(len(order[1]))
-			order[1] = utils.Remove(order[1], del)
+func Destroy(order [][]int, destroy_ratio float32, rng *rand.Rand) error {
+	for c := range order {
+		delete_c := int(destroy_ratio * float32(len(order[c]))) // wyznaczenie liczby wierzchołków do zniknięcia
+		for range delete_c {
+			del := rng.Intn(len(order[c]))         // losu do usunięcia
+			order[c] = utils.Remove(order[c], del) // usuwanie losowego wierzchołka
 		}
 	}
 	return nil
 }
-func Repair(order [][]int, distance_matrix [][]int, nodes []reader.Node) error {
-	err := ContinueGreedyCycle(distance_matrix, order, nodes) // modyfikacja greedy cycle do kontunuuacji budowy cyklu
-	if err != nil {
-		panic("Error")
-	}
-	return nil
+func Repair(order [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) error {
+	return ContinueGreedyCycle(distance_matrix, order, nodes, limits, rng) // modyfikacja greedy cycle do kontunuuacji budowy cyklu
 }
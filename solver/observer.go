@@ -0,0 +1,203 @@
+package solver
+
+import (
+	"sync"
+	"time"
+)
+
+// stan metaheurystyki w chwili zdarzenia
+type Event struct {
+	Algorithm Metaheuristic // metaheurystyka zgłaszająca zdarzenie
+	Seed      int64         // ziarno uruchomienia (cfg.Seed) - rozróżnia powtórzenia
+	Iteration int           // liczba zakończonych iteracji; 0 - przed główną pętlą
+	Elapsed   time.Duration // czas od startu metaheurystyki
+	Current   int           // koszt bieżącego rozwiązania
+	Best      int           // koszt najlepszego dotąd rozwiązania
+	Order     [][]int       // rozwiązanie, którego dotyczy zdarzenie - tylko do odczytu i tylko w trakcie wywołania
+}
+
+// zmiana populacji HAE - wstawienie osobnika
+type PopulationEvent struct {
+	Event
+	Index     int       // pozycja wstawionego osobnika w populacji posortowanej rosnąco po koszcie
+	Costs     []int     // koszty osobników po zmianie - tylko do odczytu i tylko w trakcie wywołania
+	Diversity Diversity // różnorodność populacji po zmianie (w ihae - populacji wyspy)
+}
+
+// krzyżowanie w HAE - zgodność rodziców po dopasowaniu etykiet cykli; Current i Order - potomek
+type CrossoverEvent struct {
+	Event
+	Overlap Overlap
+}
+
+// obserwator przebiegu metaheurystyki (cfg.Observer); metody wywoływane synchronicznie w pętli algorytmu,
+// więc powinny być szybkie. Własne kryterium stopu: obserwator anuluje ctx przekazany do metaheurystyki -
+// algorytm kończy się po bieżącej iteracji z najlepszym dotąd rozwiązaniem
+type Observer interface {
+	OnNewBest(event Event)                    // nowe najlepsze rozwiązanie
+	OnIteration(event Event)                  // koniec iteracji głównej pętli
+	OnPopulationChange(event PopulationEvent) // nowy osobnik w populacji HAE
+	OnRestart(event Event)                    // nowe rozwiązanie startowe z heurystyki konstrukcyjnej
+	OnCrossover(event CrossoverEvent)         // nowy potomek w HAE
+}
+
+// obserwator z funkcji; pola nil - zdarzenie pomijane
+type ObserverFuncs struct {
+	NewBest          func(Event)
+	Iteration        func(Event)
+	PopulationChange func(PopulationEvent)
+	Restart          func(Event)
+	Crossover        func(CrossoverEvent)
+}
+
+func (o ObserverFuncs) OnNewBest(event Event) {
+	if o.NewBest != nil {
+		o.NewBest(event)
+	}
+}
+
+func (o ObserverFuncs) OnIteration(event Event) {
+	if o.Iteration != nil {
+		o.Iteration(event)
+	}
+}
+
+func (o ObserverFuncs) OnPopulationChange(event PopulationEvent) {
+	if o.PopulationChange != nil {
+		o.PopulationChange(event)
+	}
+}
+
+func (o ObserverFuncs) OnRestart(event Event) {
+	if o.Restart != nil {
+		o.Restart(event)
+	}
+}
+
+func (o ObserverFuncs) OnCrossover(event CrossoverEvent) {
+	if o.Crossover != nil {
+		o.Crossover(event)
+	}
+}
+
+// kilka obserwatorów jednego uruchomienia - zdarzenia przekazywane po kolei
+type Observers []Observer
+
+func (o Observers) OnNewBest(event Event) {
+	for _, observer := range o {
+		observer.OnNewBest(event)
+	}
+}
+
+func (o Observers) OnIteration(event Event) {
+	for _, observer := range o {
+		observer.OnIteration(event)
+	}
+}
+
+func (o Observers) OnPopulationChange(event PopulationEvent) {
+	for _, observer := range o {
+		observer.OnPopulationChange(event)
+	}
+}
+
+func (o Observers) OnRestart(event Event) {
+	for _, observer := range o {
+		observer.OnRestart(event)
+	}
+}
+
+func (o Observers) OnCrossover(event CrossoverEvent) {
+	for _, observer := range o {
+		observer.OnCrossover(event)
+	}
+}
+
+// zgłaszanie zdarzeń jednego uruchomienia metaheurystyki do cfg.Observer; bez obserwatora nic nie robi.
+// Metody można wywoływać z wielu gorutyn (pmsls, wyspy HAE) - obserwator dostaje zdarzenia po jednym
+type progress struct {
+	mu       sync.Mutex
+	observer Observer
+	start    time.Time
+	event    Event // ostatni stan - Best pamiętany między zdarzeniami
+}
+
+func newProgress(cfg *Config) *progress {
+	return &progress{
+		observer: cfg.Observer,
+		start:    time.Now(),
+		event:    Event{Algorithm: cfg.Metaheuristic, Seed: cfg.Seed, Best: -1},
+	}
+}
+
+func (p *progress) update(iter int, current int, order [][]int) Event {
+	p.event.Iteration = iter
+	p.event.Elapsed = time.Since(p.start)
+	p.event.Current = current
+	p.event.Order = order
+	return p.event
+}
+
+// koszt bieżącego rozwiązania po iteracji iter; nowe najlepsze - cost < dotychczasowego Best
+func (p *progress) iteration(iter int, cost int, order [][]int) {
+	if p.observer == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.newBest(iter, cost, order)
+	p.observer.OnIteration(p.update(iter, cost, order))
+}
+
+// zgłoszenie nowego najlepszego rozwiązania, jeśli cost je poprawia
+func (p *progress) best(iter int, cost int, order [][]int) {
+	if p.observer == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.newBest(iter, cost, order)
+}
+
+// nowe rozwiązanie startowe o koszcie cost
+func (p *progress) restart(iter int, cost int, order [][]int) {
+	if p.observer == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.newBest(iter, cost, order) // zdarzenie restartu ma już aktualny Best
+	p.observer.OnRestart(p.update(iter, cost, order))
+}
+
+// wstawienie osobnika population[index] o koszcie costs[index] do populacji
+func (p *progress) population(iter int, index int, costs []int, population [][][]int) {
+	if p.observer == nil {
+		return
+	}
+	diversity := PopulationDiversity(population) // poza blokadą - populacja należy do wywołującego
+	order := population[index]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.observer.OnPopulationChange(PopulationEvent{Event: p.update(iter, costs[index], order), Index: index, Costs: costs, Diversity: diversity})
+	p.newBest(iter, costs[index], order)
+}
+
+// potomek order o koszcie cost z rodziców o zgodności overlap
+func (p *progress) crossover(iter int, overlap Overlap, cost int, order [][]int) {
+	if p.observer == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.observer.OnCrossover(CrossoverEvent{Event: p.update(iter, cost, order), Overlap: overlap})
+}
+
+// best bez blokady
+func (p *progress) newBest(iter int, cost int, order [][]int) {
+	if p.event.Best != -1 && cost >= p.event.Best {
+		return
+	}
+	p.event.Best = cost
+	p.observer.OnNewBest(p.update(iter, cost, order))
+}
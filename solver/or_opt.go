@@ -0,0 +1,554 @@
+package solver
+
+import (
+	"IMO/utils"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// maksymalna liczba wierzchołków w przenoszonym segmencie
+const MaxOrOptSegment int = 3
+
+// ruch Or-opt - przeniesienie segmentu Len kolejnych wierzchołków od N1 z cyklu C1 przed wierzchołek N2 w cyklu C2
+type OrOptMove struct {
+	C1       int  // numer cyklu, z którego zabieramy segment
+	C2       int  // numer cyklu, do którego wstawiamy segment (może być ten sam)
+	N1       int  // pierwszy wierzchołek segmentu - nr w cyklu C1
+	Len      int  // liczba wierzchołków w segmencie
+	N2       int  // wierzchołek z cyklu C2, przed który wstawiamy - nr w cyklu
+	Reversed bool // wstawienie segmentu w odwrotnej kolejności
+	Delta    int  // zmiana długości cyklów po przeniesieniu
+}
+
+// ruch Or-opt - przeniesienie segmentu Segment z cyklu C1 w miejsce krawędzi P - S w cyklu C2;
+// po ruchu P sąsiaduje z Segment[0], a S z ostatnim wierzchołkiem segmentu
+type OrOptMoveDetail struct {
+	C1      int   // numer cyklu, z którego zabieramy segment
+	C2      int   // numer cyklu, do którego wstawiamy segment
+	Segment []int // przenoszone wierzchołki w kolejności
+	PA      int   // sąsiad pierwszego wierzchołka segmentu spoza segmentu
+	PB      int   // sąsiad ostatniego wierzchołka segmentu spoza segmentu
+	P       int   // wierzchołek krawędzi w cyklu C2, który będzie sąsiadem Segment[0]
+	S       int   // wierzchołek krawędzi w cyklu C2, który będzie sąsiadem ostatniego wierzchołka segmentu
+	Delta   int   // zmiana długości cyklów po przeniesieniu
+}
+
+func (m *OrOptMove) ExecuteMove(tour *Tour) error {
+	segment := make([]int, m.Len)
+	for k := range segment {
+		segment[k] = tour.Order[m.C1][(m.N1+k)%len(tour.Order[m.C1])]
+	}
+	if m.Reversed {
+		utils.Reverse(segment)
+	}
+	next := tour.Order[m.C2][m.N2] // wierzchołek, przed który wstawiamy - indeks zmieni się po usunięciu segmentu
+	tour.RemoveSegment(m.C1, m.N1, m.Len)
+	tour.InsertSegment(m.C2, tour.Position(next), segment)
+	return nil
+}
+
+func (m *OrOptMove) GetDelta() int {
+	return m.Delta // zmiana długości cyklów po przeniesieniu
+}
+
+func (m *OrOptMove) SetDelta(delta int) {
+	m.Delta = delta
+}
+
+func (m *OrOptMoveDetail) ExecuteMove(tour *Tour) error {
+	positions, ok := tour.SegmentPositions(m.C1, m.Segment)
+	if !ok {
+		return fmt.Errorf("%w: Or-opt segment %v not found in cycle %d", ErrInfeasible, m.Segment, m.C1)
+	}
+	tour.RemoveSegment(m.C1, positions[0], len(positions))
+
+	segment := append([]int(nil), m.Segment...)
+	j := tour.Position(m.S)
+	if tour.Pred(m.S) != m.P { // krawędź w drugą stronę (S przed P) - wstawiamy odwrócony segment przed P
+		j = tour.Position(m.P)
+		utils.Reverse(segment)
+	}
+	tour.InsertSegment(m.C2, j, segment)
+	return nil
+}
+
+func (m *OrOptMoveDetail) GetDelta() int {
+	return m.Delta // zmiana długości cyklów po przeniesieniu
+}
+
+func (m *OrOptMoveDetail) SetDelta(delta int) {
+	m.Delta = delta
+}
+
+// zmiana długości cykli po przeniesieniu segmentu first..last spomiędzy prev i next w miejsce krawędzi from - to;
+// po ruchu from sąsiaduje z first, a to z last
+func OrOptDelta(distance_matrix *utils.DistanceMatrix, first int, last int, prev int, next int, from int, to int) int {
+	return distance_matrix.At(prev, next) - distance_matrix.At(prev, first) - distance_matrix.At(last, next) + // usunięcie segmentu
+		distance_matrix.At(from, first) + distance_matrix.At(last, to) - distance_matrix.At(from, to) // wstawienie segmentu
+}
+
+// usunięcie length wierzchołków od indeksu start (z zawinięciem) - nowa tablica
+func RemoveSegment(cycle []int, start int, length int) []int {
+	rest := make([]int, 0, len(cycle)-length)
+	for k := range cycle {
+		if (k-start+len(cycle))%len(cycle) >= length { // poza segmentem
+			rest = append(rest, cycle[k])
+		}
+	}
+	return rest
+}
+
+// wstawienie segmentu przed indeks idx - nowa tablica
+func InsertSegment(cycle []int, idx int, segment []int) []int {
+	result := make([]int, 0, len(cycle)+len(segment))
+	result = append(result, cycle[:idx]...)
+	result = append(result, segment...)
+	return append(result, cycle[idx:]...)
+}
+
+// indeksy kolejnych wierzchołków segmentu w cyklu (w przód lub w tył), gdy segment[0] ma indeks i;
+// posortowane od pierwszego indeksu usuwanego fragmentu
+func SegmentPositions(cycle []int, segment []int, i int) ([]int, bool) {
+	for _, step := range []int{1, -1} {
+		positions := make([]int, len(segment))
+		ok := true
+		for k := range segment {
+			positions[k] = ((i+step*k)%len(cycle) + len(cycle)) % len(cycle)
+			if cycle[positions[k]] != segment[k] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			if step == -1 {
+				utils.Reverse(positions)
+			}
+			return positions, true
+		}
+	}
+	return nil, false
+}
+
+// czy przeniesienie segmentu length wierzchołków z cyklu c1 do c2 jest możliwe
+func OrOptAllowed(order [][]int, limits *SizeLimits, c1 int, c2 int, length int) bool {
+	if c1 == c2 {
+		return len(order[c1]) >= length+2 // musi zostać krawędź poza segmentem
+	}
+	return limits.CanRelocateNodes(order, c1, c2, length)
+}
+
+// najlepszy ruch Or-opt (przeszukiwanie stromego spadku) - pierwszy o najmniejszej delcie, bez budowania listy
+// wszystkich ruchów; nil - brak dozwolonych ruchów
+func BestOrOptMove(distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) *OrOptMove {
+	var (
+		best  OrOptMove
+		found bool
+	)
+	consider := func(move OrOptMove) {
+		if !found || move.Delta < best.Delta {
+			best, found = move, true
+		}
+	}
+	for c1 := range order {
+		for c2 := range order {
+			for length := 1; length <= MaxOrOptSegment; length++ {
+				if !OrOptAllowed(order, limits, c1, c2, length) {
+					continue
+				}
+				for i := range order[c1] {
+					first := order[c1][i]
+					last := order[c1][(i+length-1)%len(order[c1])]
+					prev := utils.ElemBefore(order[c1], i)
+					next := order[c1][(i+length)%len(order[c1])]
+					for j := range order[c2] {
+						if c1 == c2 && !orOptIntraValid(len(order[c1]), i, length, j) {
+							continue
+						}
+						from, to := utils.ElemBefore(order[c2], j), order[c2][j]
+						consider(OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j,
+							Delta: OrOptDelta(distance_matrix, first, last, prev, next, from, to)})
+						if length > 1 {
+							consider(OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j, Reversed: true,
+								Delta: OrOptDelta(distance_matrix, last, first, next, prev, from, to)}) // odwrócony: last sąsiaduje z next
+						}
+					}
+				}
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &best
+}
+
+// wszystkie ruchy Or-opt bez obliczonych delt (przeszukiwanie zachłanne)
+func AllOrOptMovesNoDistance(order [][]int, limits *SizeLimits) []OrOptMove {
+	var moves []OrOptMove // aktualnie dostępne ruchy
+	for c1 := range order {
+		for c2 := range order {
+			for length := 1; length <= MaxOrOptSegment; length++ {
+				if !OrOptAllowed(order, limits, c1, c2, length) {
+					continue
+				}
+				for i := range order[c1] {
+					for j := range order[c2] {
+						if c1 == c2 && !orOptIntraValid(len(order[c1]), i, length, j) {
+							continue
+						}
+						moves = append(moves, OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j, Delta: math.MaxInt})
+						if length > 1 {
+							moves = append(moves, OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j, Reversed: true, Delta: math.MaxInt})
+						}
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// czy ruch Or-opt bez delty zachowuje znaczenie po zmianie rozmiarów cykli: przeniesienie nadal dozwolone,
+// a segment i miejsce wstawienia w indeksach wspólnych dla obu rozmiarów, bez zawinięcia
+func (r *resizedCycles) orOptStable(m *OrOptMove) bool {
+	if !r.or_opt[m.Len-1][m.C1][m.C2] || m.N2 >= r.bound[m.C2] {
+		return false
+	}
+	if !r.changed[m.C1] {
+		return true // segment z cyklu bez zmian
+	}
+	if m.N1+m.Len > r.bound[m.C1] {
+		return false // segment zawija się przy jednym z rozmiarów
+	}
+	// w tym samym cyklu wstawienie przed segment dozwolone przy obu rozmiarach
+	return m.C1 != m.C2 || m.N2 > m.N1 || m.N1-m.N2 < r.bound[m.C1]-m.Len
+}
+
+// ruchy Or-opt bez delt, które pojawiły się po przeniesieniu moved wierzchołków z cyklu from do to (order - cykle
+// po ruchu): z segmentami i miejscami wstawienia poza wspólną częścią cykli oraz między parami cykli z limits.Unlocked
+func orOptMovesNoDistanceAfter(order [][]int, limits *SizeLimits, r *resizedCycles, from int, to int, moved int) []OrOptMove {
+	var moves []OrOptMove
+	add := func(c1 int, c2 int, length int, i int, j int) {
+		if c1 == c2 && !orOptIntraValid(len(order[c1]), i, length, j) {
+			return
+		}
+		moves = append(moves, OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j, Delta: math.MaxInt})
+		if length > 1 {
+			moves = append(moves, OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j, Reversed: true, Delta: math.MaxInt})
+		}
+	}
+	for length := 1; length <= MaxOrOptSegment; length++ {
+		unlocked := limits.Unlocked(order, from, to, moved, length)
+		for c1 := range order {
+			for c2 := range order {
+				if !r.or_opt[length-1][c1][c2] {
+					continue
+				}
+				n1, n2 := len(order[c1]), len(order[c2])
+				b1, b2 := r.bound[c1], r.bound[c2]
+				allowed_before := !slices.Contains(unlocked, Pair[int]{A: c1, B: c2})
+				if c1 == c2 {
+					allowed_before = r.before[c1] >= length+2
+				}
+				if !allowed_before {
+					tailPairs(n1, 0, n2, 0, func(i int, j int) { add(c1, c2, length, i, j) })
+					continue
+				}
+				s1 := n1 // początki segmentów, które nie zawijają się przy żadnym rozmiarze
+				if r.changed[c1] {
+					s1 = max(0, b1-length+1)
+				}
+				tailPairs(n1, s1, n2, b2, func(i int, j int) { add(c1, c2, length, i, j) })
+				if c1 == c2 && r.changed[c1] {
+					// wstawienie przed segment blisko zawinięcia - dozwolone tylko przy jednym z rozmiarów
+					for i := max(0, b1-length); i < s1; i++ {
+						for j := 0; j <= i-(b1-length) && j < b2; j++ {
+							add(c1, c2, length, i, j)
+						}
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// wstawienie przed j w tym samym cyklu: j nie może należeć do segmentu ani być wierzchołkiem tuż za nim (brak zmiany)
+func orOptIntraValid(cycle_len int, i int, length int, j int) bool {
+	return (j-i+cycle_len)%cycle_len > length
+}
+
+// segment length kolejnych wierzchołków cyklu c od indeksu i w kierunku step (1 - w przód, -1 - w tył) opisany
+// indeksami - tablica wierzchołków budowana dopiero dla ruchu poprawiającego wynik
+type segmentSpan struct {
+	c      int // numer cyklu
+	i      int // indeks pierwszego wierzchołka segmentu
+	step   int // kierunek kolejnych wierzchołków
+	length int // liczba wierzchołków
+}
+
+// segment length wierzchołków zajmujący indeksy i..i+length-1 cyklu c (z zawinięciem), zorientowany tak,
+// żeby pierwszy wierzchołek miał mniejszy numer niż ostatni - każdy segment raz, niezależnie od kierunku
+func windowSpan(order [][]int, c int, i int, length int) segmentSpan {
+	j := (i + length - 1) % len(order[c])
+	if order[c][j] < order[c][i] {
+		return segmentSpan{c: c, i: j, step: -1, length: length}
+	}
+	return segmentSpan{c: c, i: i, step: 1, length: length}
+}
+
+// segmenty o długości 1..MaxOrOptSegment zaczynające się od indeksu i cyklu c (w przód i w tył)
+func spansFrom(order [][]int, c int, i int) []segmentSpan {
+	var spans []segmentSpan
+	for _, step := range []int{1, -1} {
+		for length := 1; length <= MaxOrOptSegment && length < len(order[c]); length++ {
+			if length == 1 && step == -1 {
+				continue // pojedynczy wierzchołek już rozważony
+			}
+			spans = append(spans, segmentSpan{c: c, i: i, step: step, length: length})
+		}
+	}
+	return spans
+}
+
+// k-ty wierzchołek segmentu; k = -1 i k = length - sąsiedzi pierwszego i ostatniego wierzchołka spoza segmentu
+func (s segmentSpan) node(order [][]int, k int) int {
+	n := len(order[s.c])
+	return order[s.c][((s.i+s.step*k)%n+n)%n]
+}
+
+// czy wierzchołek należy do segmentu - z pozycji w tour, bez przeszukiwania segmentu
+func (s segmentSpan) holds(tour *Tour, node int) bool {
+	if !tour.Contains(s.c, node) {
+		return false
+	}
+	n := len(tour.Order[s.c])
+	return (((tour.Position(node)-s.i)*s.step)%n+n)%n < s.length
+}
+
+// ruch Or-opt przenoszący segment w miejsce krawędzi edge cyklu c2 (edge.A sąsiaduje potem z pierwszym wierzchołkiem)
+func (s segmentSpan) move(order [][]int, c2 int, edge Pair[int], delta int) OrOptMoveDetail {
+	segment := make([]int, s.length)
+	for k := range segment {
+		segment[k] = s.node(order, k)
+	}
+	return OrOptMoveDetail{C1: s.c, C2: c2, Segment: segment, PA: s.node(order, -1), PB: s.node(order, s.length), P: edge.A, S: edge.B, Delta: delta}
+}
+
+// pierwszy i ostatni wierzchołek segmentu oraz ich sąsiedzi spoza segmentu
+func (s segmentSpan) ends(order [][]int) (int, int, int, int) {
+	return s.node(order, 0), s.node(order, s.length-1), s.node(order, -1), s.node(order, s.length)
+}
+
+// ruchy Or-opt poprawiające wynik dla segmentu seg w miejsce dowolnej krawędzi cykli, do których można go przenieść
+func orOptMovesOfSegment(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, seg segmentSpan) []OrOptMoveDetail {
+	var moves []OrOptMoveDetail
+	for c2 := range tour.Order {
+		moves = append(moves, orOptMovesOfSegmentInto(distance_matrix, tour, limits, seg, c2)...)
+	}
+	return moves
+}
+
+// ruchy Or-opt poprawiające wynik dla segmentu seg w miejsce dowolnej krawędzi cyklu c2
+func orOptMovesOfSegmentInto(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, seg segmentSpan, c2 int) []OrOptMoveDetail {
+	var (
+		order [][]int = tour.Order
+		moves []OrOptMoveDetail
+	)
+	if !OrOptAllowed(order, limits, seg.c, c2, seg.length) {
+		return nil
+	}
+	first, last, pa, pb := seg.ends(order)
+	for j := range order[c2] {
+		p, s := order[c2][j], utils.ElemAfter(order[c2], j)
+		if seg.c == c2 && (seg.holds(tour, p) || seg.holds(tour, s)) {
+			continue
+		}
+		// obie orientacje wstawienia
+		for _, edge := range []Pair[int]{{A: p, B: s}, {A: s, B: p}} {
+			if delta := OrOptDelta(distance_matrix, first, last, pa, pb, edge.A, edge.B); delta < 0 {
+				moves = append(moves, seg.move(order, c2, edge, delta))
+			}
+		}
+	}
+	return moves
+}
+
+// ruchy Or-opt poprawiające wynik dla segmentów length wierzchołków z cyklu from w miejsce krawędzi cyklu to
+func OrOptMovesBetween(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, from int, to int, length int) []OrOptMoveDetail {
+	var moves []OrOptMoveDetail
+	if !OrOptAllowed(tour.Order, limits, from, to, length) || length >= len(tour.Order[from]) {
+		return nil
+	}
+	for i := range tour.Order[from] {
+		moves = append(moves, orOptMovesOfSegmentInto(distance_matrix, tour, limits, windowSpan(tour.Order, from, i, length), to)...)
+	}
+	return moves
+}
+
+// wszystkie ruchy Or-opt poprawiające wynik (inicjalizacja FastLocalSearch)
+func BestOrOptMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits) []OrOptMoveDetail {
+	var moves []OrOptMoveDetail
+	for c := range tour.Order {
+		for length := 1; length <= MaxOrOptSegment && length < len(tour.Order[c]); length++ {
+			for i := range tour.Order[c] {
+				moves = append(moves, orOptMovesOfSegment(distance_matrix, tour, limits, windowSpan(tour.Order, c, i, length))...)
+			}
+		}
+	}
+	return moves
+}
+
+// ruchy Or-opt poprawiające wynik w pobliżu zmienionych wierzchołków nodes[c]: segmenty zaczynające się przy nich
+// przenoszone w dowolne miejsce oraz dowolne segmenty wstawiane w krawędzie wokół nich
+func OrOptMovesAround(distance_matrix *utils.DistanceMatrix, tour *Tour, limits *SizeLimits, nodes [][]int) []OrOptMoveDetail {
+	var (
+		order [][]int = tour.Order
+		moves []OrOptMoveDetail
+		edges [][]Pair[int]      = make([][]Pair[int], len(order)) // krawędzie wokół zmienionych wierzchołków w obu orientacjach
+		seen  map[Pair[int]]bool = make(map[Pair[int]]bool)
+	)
+	for c := range nodes {
+		for _, n := range nodes[c] {
+			if !tour.Contains(c, n) {
+				continue
+			}
+			i := tour.Position(n)
+			// segmenty od n i od jego sąsiadów - zmienione krawędzie przy usuwaniu
+			for _, k := range []int{utils.IndexBefore(order[c], i), i, utils.IndexAfter(order[c], i)} {
+				for _, seg := range spansFrom(order, c, k) {
+					moves = append(moves, orOptMovesOfSegment(distance_matrix, tour, limits, seg)...)
+				}
+			}
+			for _, neighbour := range []int{tour.Pred(n), tour.Succ(n)} {
+				for _, edge := range []Pair[int]{{A: neighbour, B: n}, {A: n, B: neighbour}} {
+					if !seen[edge] {
+						seen[edge] = true
+						edges[c] = append(edges[c], edge)
+					}
+				}
+			}
+		}
+	}
+	// dowolne segmenty w krawędzie wokół zmienionych wierzchołków
+	for c1 := range order {
+		for length := 1; length <= MaxOrOptSegment && length < len(order[c1]); length++ {
+			for c := range edges {
+				if len(edges[c]) == 0 || !OrOptAllowed(order, limits, c1, c, length) {
+					continue
+				}
+				for i := range order[c1] {
+					seg := windowSpan(order, c1, i, length)
+					first, last, pa, pb := seg.ends(order)
+					for _, edge := range edges[c] {
+						if c1 == c && (seg.holds(tour, edge.A) || seg.holds(tour, edge.B)) {
+							continue
+						}
+						if delta := OrOptDelta(distance_matrix, first, last, pa, pb, edge.A, edge.B); delta < 0 {
+							moves = append(moves, seg.move(order, c, edge, delta))
+						}
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// ruchy Or-opt z listy kandydatów poprawiające wynik: segment zaczynający się od i wstawiany obok kandydata -
+// nowa krawędź i - candidate
+func AllCandidateOrOptMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, candidates [][]int, limits *SizeLimits) []Move {
+	var (
+		order  [][]int = tour.Order
+		moves  []OrOptMoveDetail
+		result []Move
+	)
+
+	for i := 0; i < distance_matrix.Dimension; i++ {
+		cycle := tour.Cycle(i)
+		segments := spansFrom(order, cycle, tour.Position(i))
+		for _, candidate := range candidates[i] {
+			cycle_candidate := tour.Cycle(candidate)
+			for _, seg := range segments {
+				if !OrOptAllowed(order, limits, cycle, cycle_candidate, seg.length) {
+					continue
+				}
+				first, last, pa, pb := seg.ends(order)
+				for _, s := range []int{tour.Pred(candidate), tour.Succ(candidate)} {
+					if cycle == cycle_candidate && (seg.holds(tour, candidate) || seg.holds(tour, s)) {
+						continue
+					}
+					edge := Pair[int]{A: candidate, B: s}
+					if delta := OrOptDelta(distance_matrix, first, last, pa, pb, edge.A, edge.B); delta < 0 {
+						moves = append(moves, seg.move(order, cycle_candidate, edge, delta))
+					}
+				}
+			}
+		}
+	}
+	for m := range moves {
+		result = append(result, &moves[m])
+	}
+	return result
+}
+
+// czy ruch zmienia rozmiary cykli (indeksy w ruchach przestają być aktualne)
+func ChangesCycleSizes(move Move) bool {
+	_, _, moved := MovedNodes(move)
+	return moved > 0
+}
+
+// cykl źródłowy, docelowy i liczba wierzchołków przeniesionych między cyklami przez ruch; 0 - rozmiary cykli bez zmian
+func MovedNodes(move Move) (int, int, int) {
+	switch m := move.(type) {
+	case *RelocateMove:
+		return m.C1, m.C2, 1
+	case *RelocateMoveDetail:
+		return m.C1, m.C2, 1
+	case *OrOptMove:
+		if m.C1 != m.C2 {
+			return m.C1, m.C2, m.Len
+		}
+	case *OrOptMoveDetail:
+		if m.C1 != m.C2 {
+			return m.C1, m.C2, len(m.Segment)
+		}
+	}
+	return 0, 0, 0
+}
+
+// nowe ruchy Or-opt po wykonaniu ruchu move (FastLocalSearch)
+func FindNewOrOptMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, move Move, limits *SizeLimits) []Move {
+	var (
+		order   [][]int = tour.Order
+		moves   []OrOptMoveDetail
+		result  []Move
+		touched [][]int = make([][]int, len(order)) // wierzchołki, przy których zmieniły się krawędzie
+	)
+	switch m := move.(type) {
+	case *SwapMoveDetail:
+		touched[m.C1] = []int{m.N2, m.SN1, m.PN1}
+		touched[m.C2] = []int{m.N1, m.SN2, m.PN2}
+	case *MoveEdgeDetail:
+		touched[m.Cycle] = []int{m.N1, m.N2, m.SN1, m.SN2}
+	case *RelocateMoveDetail:
+		touched[m.C1] = []int{m.PN, m.SN}
+		touched[m.C2] = []int{m.P, m.N, m.S}
+	case *OrOptMoveDetail:
+		touched[m.C1] = []int{m.PA, m.PB}
+		touched[m.C2] = append(touched[m.C2], m.P, m.S, m.Segment[0], m.Segment[len(m.Segment)-1])
+	}
+	moves = OrOptMovesAround(distance_matrix, tour, limits, touched)
+	// cykl przekroczył granicę rozmiaru - przeniesienia segmentów wcześniej niedozwolone tylko między odblokowanymi cyklami
+	from, to, moved := MovedNodes(move)
+	for length := 1; length <= MaxOrOptSegment; length++ {
+		for _, pair := range limits.Unlocked(order, from, to, moved, length) {
+			moves = append(moves, OrOptMovesBetween(distance_matrix, tour, limits, pair.A, pair.B, length)...)
+		}
+	}
+	for m := range moves {
+		result = append(result, &moves[m])
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package solver
+
+import (
+	"IMO/reader"
+	"IMO/utils"
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+func init() {
+	RegisterMetaheuristic(AlgorithmInfo{Name: string(MetaheuristicParallelMSLS), Description: "multiple start local search on parallel workers sharing the best solution", Parameters: []string{"heuristic", "local_search", "iterations", "time_limit", "workers"}, TimeBounded: true}, ParallelMSLS)
+}
+
+// najlepsze rozwiązanie i licznik startów współdzielone przez wątki równoległego MSLS
+type sharedBest struct {
+	mu         sync.Mutex
+	iterations int       // limit startów
+	next       int       // numer kolejnego startu
+	done       int       // liczba zakończonych startów
+	cost       int       // koszt najlepszego rozwiązania; -1 - brak
+	start      int       // numer startu najlepszego rozwiązania - rozstrzyga remisy niezależnie od kolejności wątków
+	best_order [][]int   // najlepsze cykle
+	err        error     // pierwszy błąd wątku
+	progress   *progress // zdarzenia dla cfg.Observer - wywoływane pod blokadą, po jednym naraz
+}
+
+// numer kolejnego startu; false - wyczerpany limit startów, koniec czasu albo błąd innego wątku.
+// Start 0 zawsze jest wykonywany - jest rozwiązanie do zwrócenia
+func (s *sharedBest) claim(ctx context.Context) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= s.iterations || s.err != nil || (s.next > 0 && ctx.Err() != nil) {
+		return 0, false
+	}
+	s.next++
+	return s.next - 1, true
+}
+
+// rozwiązanie startu start o koszcie length po lokalnym przeszukiwaniu
+func (s *sharedBest) offer(start int, order [][]int, length int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done++
+	if s.cost == -1 || length < s.cost || (length == s.cost && start < s.start) {
+		s.cost = length
+		s.start = start
+		if err := utils.CopyCycles(s.best_order, order); err != nil {
+			return err
+		}
+	}
+	s.progress.iteration(s.done, length, order)
+	return nil
+}
+
+func (s *sharedBest) restart(order [][]int, length int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress.restart(s.done, length, order)
+}
+
+func (s *sharedBest) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// MSLS na cfg.Workers gorutynach (0 - liczba procesorów): do cfg.Iterations startów albo do cfg.TimeLimit.
+// Start k korzysta z własnego generatora z ziarna wylosowanego raz z rng, więc przy limicie iteracji
+// wynik nie zależy od przydziału startów do wątków; każdy wątek ma własne cykle robocze
+func ParallelMSLS(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error) {
+	// sprawdzenie algorytmów przed uruchomieniem wątków
+	if _, _, err := metaheuristicFuncs(cfg, rng); err != nil {
+		return 0, err
+	}
+	ctx, cancel := cfg.withTimeLimit(ctx) // limit czasu; wcześniejsze zakończenie ctx też kończy algorytm
+	defer cancel()
+	workers := cfg.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, cfg.Iterations)
+	base_seed := rng.Int63()
+	shared := &sharedBest{
+		iterations: cfg.Iterations,
+		cost:       -1,
+		best_order: make([][]int, len(order)),
+		progress:   newProgress(cfg),
+	}
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := parallelMSLSWorker(ctx, distance_matrix, nodes, cfg, limits, base_seed, shared); err != nil {
+				shared.fail(err)
+				cancel() // przerwanie pozostałych wątków
+			}
+		}()
+	}
+	wg.Wait()
+	if shared.err != nil {
+		return shared.done, shared.err
+	}
+	err := utils.CopyCycles(order, shared.best_order)
+	return shared.done, err
+}
+
+// wątek równoległego MSLS - pobiera kolejne starty aż do wyczerpania limitu
+func parallelMSLSWorker(ctx context.Context, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, cfg *Config, limits *SizeLimits, base_seed int64, shared *sharedBest) error {
+	order := make([][]int, len(limits.Target)) // cykle robocze wątku
+	for {
+		start, ok := shared.claim(ctx)
+		if !ok {
+			return nil
+		}
+		construct, local_search, err := metaheuristicFuncs(cfg, rand.New(rand.NewSource(base_seed+int64(start))))
+		if err != nil {
+			return err
+		}
+		for c := range order {
+			order[c] = make([]int, limits.Target[c]) // przywrócenie docelowych długości cykli
+		}
+		if err := construct(distance_matrix, order, nodes); err != nil {
+			return fmt.Errorf("start solution: %w", err)
+		}
+		shared.restart(order, utils.CalculateCyclesLen(order, distance_matrix))
+		if err := local_search(ctx, distance_matrix, order, limits); err != nil {
+			return fmt.Errorf("local search: %w", err)
+		}
+		if err := shared.offer(start, order, utils.CalculateCyclesLen(order, distance_matrix)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package solver
+
+import (
+	"IMO/reader"
+	"IMO/utils"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// opis zarejestrowanego algorytmu
+type AlgorithmInfo struct {
+	Name        string   // kod algorytmu używany w Config i CLI
+	Description string   // krótki opis
+	Parameters  []string // pola Config (nazwy z pliku konfiguracyjnego) wpływające na algorytm
+	TimeBounded bool     // czy czas działania wyznacza limit czasu (time_limit, random_walk_time), a nie zbieżność
+}
+
+// heurystyka konstrukcyjna - wypełnia order o docelowych długościach cykli; losowość wyłącznie z rng
+type Constructor func(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, rng *rand.Rand) error
+
+// lokalne przeszukiwanie - poprawia order w miejscu; losowość wyłącznie z rng.
+// Po zakończeniu ctx przerywa przeszukiwanie i zwraca nil - order zawiera rozwiązanie po dotychczasowych ruchach
+type Improver func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, cfg *Config, rng *rand.Rand) error
+
+// metaheurystyka - zapisuje najlepsze rozwiązanie w order, zwraca liczbę wykonanych iteracji; losowość wyłącznie z rng.
+// Po zakończeniu ctx kończy pracę i zwraca najlepsze dotąd rozwiązanie
+type MetaheuristicFunc func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, limits *SizeLimits, rng *rand.Rand) (int, error)
+
+// krzyżowanie - nowy potomek z rodziców p1 i p2 (bez ich modyfikacji), spełniający limits; losowość wyłącznie z rng
+type CrossoverFunc func(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, cfg *Config, rng *rand.Rand) ([][]int, error)
+
+type registered[F any] struct {
+	info AlgorithmInfo
+	f    F
+}
+
+// algorytmy jednego rodzaju zarejestrowane pod nazwami
+type registry[F any] struct {
+	kind    string // rodzaj algorytmu w komunikatach błędów
+	mu      sync.RWMutex
+	entries map[string]registered[F]
+}
+
+func newRegistry[F any](kind string) *registry[F] {
+	return &registry[F]{kind: kind, entries: make(map[string]registered[F])}
+}
+
+// rejestracja algorytmu; ponowne użycie nazwy to błąd programisty - panic jak w database/sql.Register
+func (r *registry[F]) register(info AlgorithmInfo, f F) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info.Name == "" {
+		panic(fmt.Sprintf("solver: %s registered without a name", r.kind))
+	}
+	if _, exists := r.entries[info.Name]; exists {
+		panic(fmt.Sprintf("solver: %s %q registered twice", r.kind, info.Name))
+	}
+	r.entries[info.Name] = registered[F]{info: info, f: f}
+}
+
+func (r *registry[F]) lookup(name string) (F, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[name]
+	if !ok {
+		var zero F
+		return zero, fmt.Errorf("%w: %s %q (available: %s)", ErrUnknownAlgorithm, r.kind, name, strings.Join(r.names(), ", "))
+	}
+	return entry.f, nil
+}
+
+// nazwy posortowane alfabetycznie; wymaga blokady
+func (r *registry[F]) names() []string {
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *registry[F]) list() []AlgorithmInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]AlgorithmInfo, 0, len(r.entries))
+	for _, name := range r.names() {
+		infos = append(infos, r.entries[name].info)
+	}
+	return infos
+}
+
+var (
+	constructors   = newRegistry[Constructor]("heuristic")
+	improvers      = newRegistry[Improver]("local search algorithm")
+	metaheuristics = newRegistry[MetaheuristicFunc]("metaheuristic")
+	crossovers     = newRegistry[CrossoverFunc]("crossover operator")
+)
+
+func RegisterConstructor(info AlgorithmInfo, f Constructor) {
+	constructors.register(info, f)
+}
+
+func RegisterImprover(info AlgorithmInfo, f Improver) {
+	improvers.register(info, f)
+}
+
+func RegisterMetaheuristic(info AlgorithmInfo, f MetaheuristicFunc) {
+	metaheuristics.register(info, f)
+}
+
+func RegisterCrossover(info AlgorithmInfo, f CrossoverFunc) {
+	crossovers.register(info, f)
+}
+
+// zarejestrowane heurystyki konstrukcyjne, posortowane po nazwie
+func Constructors() []AlgorithmInfo {
+	return constructors.list()
+}
+
+// zarejestrowane algorytmy lokalnego przeszukiwania, posortowane po nazwie
+func Improvers() []AlgorithmInfo {
+	return improvers.list()
+}
+
+// zarejestrowane metaheurystyki, posortowane po nazwie
+func Metaheuristics() []AlgorithmInfo {
+	return metaheuristics.list()
+}
+
+// zarejestrowane operatory krzyżowania HAE, posortowane po nazwie
+func Crossovers() []AlgorithmInfo {
+	return crossovers.list()
+}
+
+// heurystyka konstrukcyjna bez parametrów z Config
+func plainConstructor(f func(*utils.DistanceMatrix, [][]int, []reader.Node, *rand.Rand) error) Constructor {
+	return func(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, rng *rand.Rand) error {
+		return f(distance_matrix, order, nodes, rng)
+	}
+}
+
+// deterministyczne lokalne przeszukiwanie bez parametrów z Config
+func plainImprover(f func(context.Context, *utils.DistanceMatrix, [][]int, *SizeLimits) error) Improver {
+	return func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, cfg *Config, rng *rand.Rand) error {
+		return f(ctx, distance_matrix, order, limits)
+	}
+}
+
+// losowe lokalne przeszukiwanie bez parametrów z Config
+func randomizedImprover(f func(context.Context, *utils.DistanceMatrix, [][]int, *SizeLimits, *rand.Rand) error) Improver {
+	return func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits, cfg *Config, rng *rand.Rand) error {
+		return f(ctx, distance_matrix, order, limits, rng)
+	}
+}
+
+// krzyżowanie bez parametrów z Config
+func plainCrossover(f func([][]int, [][]int, *utils.DistanceMatrix, []reader.Node, *SizeLimits, *rand.Rand) ([][]int, error)) CrossoverFunc {
+	return func(p1 [][]int, p2 [][]int, distance_matrix *utils.DistanceMatrix, nodes []reader.Node, limits *SizeLimits, cfg *Config, rng *rand.Rand) ([][]int, error) {
+		return f(p1, p2, distance_matrix, nodes, limits, rng)
+	}
+}
+
+// wypisanie wszystkich zarejestrowanych algorytmów (dla opcji -list w CLI)
+func PrintAlgorithms(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, group := range []struct {
+		title string
+		infos []AlgorithmInfo
+	}{
+		{"heuristics", Constructors()},
+		{"local search algorithms", Improvers()},
+		{"metaheuristics", Metaheuristics()},
+		{"crossover operators", Crossovers()},
+	} {
+		fmt.Fprintf(tw, "%s:\n", group.title)
+		for _, info := range group.infos {
+			var notes []string
+			if len(info.Parameters) > 0 {
+				notes = append(notes, "params: "+strings.Join(info.Parameters, ", "))
+			}
+			if info.TimeBounded {
+				notes = append(notes, "time-bounded")
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", info.Name, info.Description, strings.Join(notes, "; "))
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,209 @@
+package solver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// najmniejszy dopuszczalny rozmiar cyklu - delty ruchów zakładają różnych poprzednika i następnika wierzchołka
+const MinCycleSize int = 3
+
+// ograniczenia rozmiarów cykli: docelowe długości (konstrukcja) i dopuszczalny przedział [Min, Max] dla każdego cyklu
+type SizeLimits struct {
+	Target []int // docelowe długości cykli
+	Min    []int // minimalna liczba wierzchołków w cyklu
+	Max    []int // maksymalna liczba wierzchołków w cyklu
+}
+
+// ograniczenia dla docelowych długości target; przedział target +- tolerance, nie mniej niż MinCycleSize,
+// min_size / max_size > 0 nadpisują dolną / górną granicę dla wszystkich cykli
+func NewSizeLimits(num_nodes int, target []int, tolerance int, min_size int, max_size int) (*SizeLimits, error) {
+	if len(target) == 0 {
+		return nil, fmt.Errorf("%w: no cycles given", ErrInvalidConfig)
+	}
+	if tolerance < 0 {
+		return nil, fmt.Errorf("%w: invalid size tolerance %d", ErrInvalidConfig, tolerance)
+	}
+	if min_size > 0 && min_size < MinCycleSize {
+		return nil, fmt.Errorf("%w: minimal cycle size %d below %d", ErrInvalidConfig, min_size, MinCycleSize)
+	}
+	limits := &SizeLimits{
+		Target: append([]int(nil), target...),
+		Min:    make([]int, len(target)),
+		Max:    make([]int, len(target)),
+	}
+	sum := 0
+	for c, t := range target {
+		if t < 1 {
+			return nil, fmt.Errorf("%w: invalid size %d of cycle %d", ErrInvalidConfig, t, c)
+		}
+		sum += t
+		limits.Min[c] = max(MinCycleSize, t-tolerance)
+		limits.Max[c] = t + tolerance
+		if min_size > 0 {
+			limits.Min[c] = min_size
+		}
+		if max_size > 0 {
+			limits.Max[c] = max_size
+		}
+		if t < limits.Min[c] || t > limits.Max[c] {
+			return nil, fmt.Errorf("%w: size %d of cycle %d outside of [%d, %d]", ErrInvalidConfig, t, c, limits.Min[c], limits.Max[c])
+		}
+	}
+	if sum != num_nodes {
+		return nil, fmt.Errorf("%w: cycle sizes sum up to %d, expected %d nodes", ErrInvalidConfig, sum, num_nodes)
+	}
+	return limits, nil
+}
+
+// równe rozmiary cykli bez możliwości zmiany (zachowanie z dwoma cyklami po połowie)
+func EqualSizeLimits(num_nodes int, num_cycles int) (*SizeLimits, error) {
+	if num_cycles < 1 || num_cycles > num_nodes {
+		return nil, fmt.Errorf("%w: invalid number of cycles %d for %d nodes", ErrInvalidConfig, num_cycles, num_nodes)
+	}
+	return NewSizeLimits(num_nodes, CycleSizes(num_nodes, num_cycles), 0, 0, 0)
+}
+
+// rozmiary cykli z zapisu "60,40" lub "60/40"; jeśli suma różna od num_nodes to wartości traktowane jako proporcje
+func ParseSizes(spec string, num_nodes int) ([]int, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '/' || r == ':' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty cycle sizes %q", ErrInvalidConfig, spec)
+	}
+	sizes := make([]int, len(fields))
+	sum := 0
+	for c, f := range fields {
+		size, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("%w: invalid cycle size %q in %q", ErrInvalidConfig, f, spec)
+		}
+		sizes[c] = size
+		sum += size
+	}
+	if sum == num_nodes {
+		return sizes, nil
+	}
+
+	// proporcje - metoda największych reszt
+	scaled := make([]int, len(sizes))
+	rest := make([]int, len(sizes))
+	assigned := 0
+	for c := range sizes {
+		scaled[c] = sizes[c] * num_nodes / sum
+		rest[c] = sizes[c] * num_nodes % sum
+		assigned += scaled[c]
+	}
+	for ; assigned < num_nodes; assigned++ {
+		best := 0
+		for c := range rest {
+			if rest[c] > rest[best] {
+				best = c
+			}
+		}
+		scaled[best]++
+		rest[best] = -1
+	}
+	for c := range scaled {
+		if scaled[c] < 1 {
+			return nil, fmt.Errorf("%w: cycle sizes %q give an empty cycle for %d nodes", ErrInvalidConfig, spec, num_nodes)
+		}
+	}
+	return scaled, nil
+}
+
+// ograniczenia z parametrów linii komend; spec pusty - równy podział na num_cycles cykli
+func SizeLimitsFromSpec(num_nodes int, num_cycles int, spec string, tolerance int, min_size int, max_size int) (*SizeLimits, error) {
+	var (
+		sizes []int
+		err   error
+	)
+	if spec == "" {
+		if num_cycles < 1 || num_cycles > num_nodes {
+			return nil, fmt.Errorf("%w: invalid number of cycles %d for %d nodes", ErrInvalidConfig, num_cycles, num_nodes)
+		}
+		sizes = CycleSizes(num_nodes, num_cycles)
+	} else {
+		sizes, err = ParseSizes(spec, num_nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewSizeLimits(num_nodes, sizes, tolerance, min_size, max_size)
+}
+
+func (l *SizeLimits) NumCycles() int {
+	return len(l.Target)
+}
+
+// czy rozmiary cykli mogą się zmieniać
+func (l *SizeLimits) Flexible() bool {
+	if l == nil {
+		return false
+	}
+	for c := range l.Target {
+		if l.Min[c] != l.Max[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// czy można przenieść wierzchołek z cyklu from do cyklu to
+func (l *SizeLimits) CanRelocate(order [][]int, from int, to int) bool {
+	return l.CanRelocateNodes(order, from, to, 1)
+}
+
+// czy można przenieść count wierzchołków z cyklu from do cyklu to
+func (l *SizeLimits) CanRelocateNodes(order [][]int, from int, to int, count int) bool {
+	if l == nil || from == to {
+		return false
+	}
+	return len(order[from])-count >= l.Min[from] && len(order[to])+count <= l.Max[to]
+}
+
+// pary cykli (A - skąd, B - dokąd), między którymi przeniesienie count wierzchołków stało się dozwolone dopiero po
+// przeniesieniu moved wierzchołków z cyklu from do cyklu to - from zszedł poniżej granicy Max, a to wyszedł powyżej Min;
+// order - cykle po przeniesieniu
+func (l *SizeLimits) Unlocked(order [][]int, from int, to int, moved int, count int) []Pair[int] {
+	if l == nil || from == to || moved == 0 {
+		return nil
+	}
+	allowed := func(size_from int, size_to int, c1 int, c2 int) bool {
+		return size_from-count >= l.Min[c1] && size_to+count <= l.Max[c2]
+	}
+	size_before := func(c int) int { // rozmiar cyklu przed przeniesieniem
+		switch c {
+		case from:
+			return len(order[c]) + moved
+		case to:
+			return len(order[c]) - moved
+		}
+		return len(order[c])
+	}
+	var pairs []Pair[int]
+	for c1 := range order {
+		for c2 := range order {
+			if c1 == c2 || (c1 != to && c2 != from) {
+				continue // rozmiary obu cykli bez zmian lub tylko zmniejszona swoboda
+			}
+			if allowed(len(order[c1]), len(order[c2]), c1, c2) && !allowed(size_before(c1), size_before(c2), c1, c2) {
+				pairs = append(pairs, Pair[int]{A: c1, B: c2})
+			}
+		}
+	}
+	return pairs
+}
+
+// sprawdzenie czy rozmiary cykli mieszczą się w przedziałach
+func (l *SizeLimits) Check(order [][]int) error {
+	if len(order) != len(l.Target) {
+		return fmt.Errorf("%w: got %d cycles, expected %d", ErrInfeasible, len(order), len(l.Target))
+	}
+	for c := range order {
+		if len(order[c]) < l.Min[c] || len(order[c]) > l.Max[c] {
+			return fmt.Errorf("%w: cycle %d has %d nodes, expected [%d, %d]", ErrInfeasible, c, len(order[c]), l.Min[c], l.Max[c])
+		}
+	}
+	return nil
+}
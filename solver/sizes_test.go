@@ -0,0 +1,27 @@
+package solver
+
+import (
+	"errors"
+	"testing"
+)
+
+// cykle nie mogą zejść poniżej MinCycleSize, bo delty ruchów zakładają różnych sąsiadów wierzchołka
+func TestNewSizeLimitsMin(t *testing.T) {
+	limits, err := NewSizeLimits(12, []int{3, 4, 5}, 4, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c, m := range limits.Min {
+		if m < MinCycleSize {
+			t.Errorf("cycle %d: minimal size %d below %d", c, m, MinCycleSize)
+		}
+	}
+	for _, min_size := range []int{1, 2} {
+		if _, err := NewSizeLimits(12, []int{4, 4, 4}, 0, min_size, 0); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("min size %d: expected ErrInvalidConfig, got %v", min_size, err)
+		}
+	}
+	if _, err := NewSizeLimits(12, []int{2, 5, 5}, 3, 0, 0); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("cycle of 2 nodes: expected ErrInvalidConfig, got %v", err)
+	}
+}
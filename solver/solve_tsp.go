@@ -3,432 +3,317 @@ package solver
 import (
 	"IMO/reader"
 	"IMO/utils"
+	"fmt"
 	"math"
 	"math/rand"
 )
 
+func init() {
+	RegisterConstructor(AlgorithmInfo{Name: string(HeuristicNearestNeighbour), Description: "nearest neighbour"}, plainConstructor(NearestNeighbour))
+	RegisterConstructor(AlgorithmInfo{Name: string(HeuristicGreedyCycle), Description: "greedy cycle"}, plainConstructor(GreedyCycle))
+	RegisterConstructor(AlgorithmInfo{Name: string(HeuristicRegret), Description: "2-regret"}, plainConstructor(Regret))
+	RegisterConstructor(AlgorithmInfo{Name: string(HeuristicWeightedRegret), Description: "weighted 2-regret", Parameters: []string{"regret_weight", "change_weight"}},
+		func(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, cfg *Config, rng *rand.Rand) error {
+			return weightedRegret(distance_matrix, order, nodes, cfg.RegretWeight, cfg.ChangeWeight, rng)
+		})
+	RegisterConstructor(AlgorithmInfo{Name: string(HeuristicRandom), Description: "random assignment and order"}, plainConstructor(Random))
+	RegisterConstructor(AlgorithmInfo{Name: string(HeuristicInOrder), Description: "nodes in input order"}, plainConstructor(InOrder))
+}
+
 // testowo jak może struktura wyglądać funkcji - paramtetry
-func InOrder(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
-	for i := range distance_matrix {
-		if i < len(order[0]) {
-			order[0][i] = i
-		} else {
-			order[1][i-len(order[0])] = i
+func InOrder(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, rng *rand.Rand) error {
+	i := 0
+	for c := range order {
+		for j := range order[c] {
+			order[c][j] = i
+			i++
 		}
 	}
 	return nil
 }
 
-func NearestNeighbour(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
-	start_node_1, start_node_2, _ := PickRandomNodes(nodes) // wybór startowych punktów
-
-	order[0][len(order[0])-1] = -1
-	order[1][len(order[1])-1] = -1 // znakowanie końca tablic order
-	order[0][0] = start_node_1
-	order[1][0] = start_node_2 // przypisywanie pierwszych wierzchołków
+func NearestNeighbour(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, rng *rand.Rand) error {
+	start_nodes, err := PickRandomNodes(nodes, len(order), rng) // wybór startowych punktów
+	if err != nil {
+		return err
+	}
 
 	var visited []bool = make([]bool, len(nodes)) // tablica dodanych wierzchołków
 
-	visited[start_node_1] = true
-	visited[start_node_2] = true
-	for j := 1; order[0][len(order[0])-1] == -1 || order[1][len(order[1])-1] == -1; j++ {
-		min_1 := -1
-		min_2 := -1 // wartości najbliższych krawędzi
-		if j < len(order[0]) {
-			order[0][j] = -1
-		}
-		if j < len(order[1]) {
-			order[1][j] = -1
-		}
-		for i := range nodes {
-			if visited[i] {
-				continue // pomijanie wierzchołków dodanych
-			}
-
-			if j >= len(order[0]) { // po osiągnięciu maksymalnej długości na jednycm cyklu resztę sąsiadów szuka dla jednego cyklu
-				order2_nn := distance_matrix[i][order[1][j-1]]
-				if min_2 == -1 || order2_nn < min_2 {
-					min_2 = order2_nn
-					order[1][j] = i
-				}
-				continue
-			}
-			if j >= len(order[1]) {
-				order1_nn := distance_matrix[i][order[0][j-1]]
-				if min_1 == -1 || order1_nn < min_1 {
-					min_1 = order1_nn
-					order[0][j] = i
-				}
+	for c := range order {
+		order[c][0] = start_nodes[c] // przypisywanie pierwszych wierzchołków
+		visited[start_nodes[c]] = true
+	}
+	// cykle rozbudowywane na zmianę; po osiągnięciu docelowej długości cykl jest pomijany
+	for j, added := 1, true; added; j++ {
+		added = false
+		for c := range order {
+			if j >= len(order[c]) {
 				continue
 			}
-			order1_nn := distance_matrix[i][order[0][j-1]]
-			order2_nn := distance_matrix[i][order[1][j-1]]
-			switch {
-			case min_1 == -1:
-				min_1 = order1_nn
-				order[0][j] = i
-			case min_2 == -1:
-				min_2 = order2_nn
-				order[1][j] = i
-			case order1_nn < min_1:
-				min_1 = order1_nn
-				order[0][j] = i
-			case order2_nn < min_2:
-				min_2 = order2_nn
-				order[1][j] = i
+			nearest, err := utils.NearestNode(nodes, distance_matrix, order[c][j-1], visited)
+			if err != nil {
+				return err
 			}
-		}
-		if j < len(order[0]) {
-			visited[order[0][j]] = true
-		}
-		if j < len(order[1]) {
-			visited[order[1][j]] = true
+			order[c][j] = nearest
+			visited[nearest] = true
+			added = true
 		}
 	}
 	return nil
 }
 
-func GreedyCycle(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
-	start_node_1, start_node_2, _ := PickRandomNodes(nodes) // wybór startowych punktów
+func GreedyCycle(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, rng *rand.Rand) error {
+	start_nodes, err := PickRandomNodes(nodes, len(order), rng) // wybór startowych punktów
+	if err != nil {
+		return err
+	}
 
 	var (
-		visited      []bool = make([]bool, len(nodes)) // tablica dodanych wierzchołków
-		cycle1       []int
-		cycle2       []int
-		new_cycle    []int
-		visit        int
-		cost         int
-		temp_cycle   []int
-		minimal_cost int
+		visited []bool  = make([]bool, len(nodes)) // tablica dodanych wierzchołków
+		cycles  [][]int = make([][]int, len(order))
+		sizes   []int   = make([]int, len(order)) // docelowe długości cykli
 	)
+	for c := range order {
+		cycles[c] = append(cycles[c], start_nodes[c])
+		visited[start_nodes[c]] = true
+		sizes[c] = len(order[c])
+	}
 
-	visited[start_node_1] = true
-	visited[start_node_2] = true
-
-	cycle1 = append(cycle1, start_node_1)
-	cycle2 = append(cycle2, start_node_2)
-
-	lenCycle1 := len(order[0])
-	lenCycle2 := len(order[1])
-
-	for len(cycle1) < lenCycle1 || len(cycle2) < lenCycle2 {
-		if len(cycle1) < lenCycle1 {
-			visit = -1
-			minimal_cost = -1
-			for i := range nodes {
-				if visited[i] {
-					continue
-				}
-				for j := range cycle1 {
-					temp_cycle = utils.Insert(cycle1, j, i)
-					cost = 0
-					for node_idx := range temp_cycle {
-						cost += distance_matrix[temp_cycle[node_idx]][temp_cycle[(node_idx+1)%len(temp_cycle)]]
-					}
-					if minimal_cost == -1 || cost < minimal_cost {
-						new_cycle = append(temp_cycle[:0:0], temp_cycle...)
-						minimal_cost = cost
-						visit = i
-					}
-				}
-			}
-
-			cycle1 = append(new_cycle[:0:0], new_cycle...)
-			visited[visit] = true
-
-		}
-		if len(cycle2) < lenCycle2 {
-			visit = -1
-			minimal_cost = -1
-			for i := range nodes {
-				if visited[i] {
-					continue
-				}
-				for j := range cycle2 {
-					temp_cycle = utils.Insert(cycle2, j, i)
-					cost = 0
-					for node_idx := range temp_cycle {
-						cost += distance_matrix[temp_cycle[node_idx]][temp_cycle[(node_idx+1)%len(temp_cycle)]]
-					}
-					if minimal_cost == -1 || cost < minimal_cost {
-						new_cycle = append(temp_cycle[:0:0], temp_cycle...)
-						minimal_cost = cost
-						visit = i
-					}
-				}
-			}
-			cycle2 = append(new_cycle[:0:0], new_cycle...)
-			visited[visit] = true
-		}
+	err = GrowCycles(distance_matrix, cycles, sizes, visited)
+	if err != nil {
+		return err
 	}
-	order[0] = cycle1
-	order[1] = cycle2
+	copy(order, cycles)
 
 	return nil
 }
-func ContinueGreedyCycle(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
+
+// dokończenie budowy częściowych cykli z order tak, by rozmiary mieściły się w przedziałach z limits
+func ContinueGreedyCycle(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, limits *SizeLimits, rng *rand.Rand) error {
 	var (
-		visited      []bool = make([]bool, len(nodes)) // tablica dodanych wierzchołków
-		cycle1       []int
-		cycle2       []int
-		new_cycle    []int
-		visit        int
-		cost         int
-		temp_cycle   []int
-		minimal_cost int
+		visited []bool  = make([]bool, len(nodes)) // tablica dodanych wierzchołków
+		cycles  [][]int = make([][]int, len(order))
 	)
-	for i := range int(math.Max(float64(len(order[0])), float64(len(order[1])))) {
-		if i < len(order[0]) {
-			cycle1 = append(cycle1, order[0][i])
-			visited[order[0][i]] = true
-		}
-		if i < len(order[1]) {
-			cycle2 = append(cycle2, order[1][i])
-			visited[order[1][i]] = true
+	if len(order) != limits.NumCycles() {
+		return fmt.Errorf("%w: got %d cycles, expected %d", ErrInfeasible, len(order), limits.NumCycles())
+	}
+	for c := range order {
+		cycles[c] = append(cycles[c], order[c]...)
+		for _, n := range order[c] {
+			visited[n] = true
 		}
 	}
-	lenCycle1 := len(nodes) / 2
-	lenCycle2 := len(nodes) / 2
 
 	// gdy w cyklu nie ma wierzchołków dodaj losowy z nieodwiedzonych
-	for len(cycle1) == 0 {
-		// wylosuj wierzchołek do cyklu
-		rand_idx := rand.Intn(len(nodes))
-		if visited[rand_idx] {
-			continue
+	for c := range cycles {
+		for len(cycles[c]) == 0 {
+			// wylosuj wierzchołek do cyklu
+			rand_idx := rng.Intn(len(nodes))
+			if visited[rand_idx] {
+				continue
+			}
+			cycles[c] = append(cycles[c], rand_idx)
+			visited[rand_idx] = true
 		}
-		cycle1 = append(cycle1, rand_idx)
-		visited[rand_idx] = true
 	}
-	for len(cycle2) == 0 {
-		// wylosuj wierzchołek do cyklu
-		rand_idx := rand.Intn(len(nodes))
-		if visited[rand_idx] {
-			continue
-		}
-		cycle2 = append(cycle2, rand_idx)
-		visited[rand_idx] = true
+
+	err := GrowCyclesInBand(distance_matrix, cycles, limits.Min, limits.Max, visited)
+	if err != nil {
+		return err
 	}
+	copy(order, cycles)
+
+	return nil
+}
 
-	for len(cycle1) < lenCycle1 || len(cycle2) < lenCycle2 {
-		if len(cycle1) < lenCycle1 {
-			visit = -1
-			minimal_cost = -1
-			for i := range nodes {
+// rozbudowa cykli (na zmianę) przez wstawianie wierzchołka o najmniejszym przyroście długości, aż do docelowych rozmiarów
+func GrowCycles(distance_matrix *utils.DistanceMatrix, cycles [][]int, sizes []int, visited []bool) error {
+	for grown := true; grown; {
+		grown = false
+		for c := range cycles {
+			if len(cycles[c]) >= sizes[c] {
+				continue
+			}
+			visit, position := -1, -1
+			minimal_cost := math.MaxInt
+			for i := range visited {
 				if visited[i] {
 					continue
 				}
-				for j := range cycle1 {
-					temp_cycle = utils.Insert(cycle1, j, i)
-					cost = 0
-					for node_idx := range temp_cycle {
-						cost += distance_matrix[temp_cycle[node_idx]][temp_cycle[(node_idx+1)%len(temp_cycle)]]
-					}
-					if minimal_cost == -1 || cost < minimal_cost {
-						new_cycle = append(temp_cycle[:0:0], temp_cycle...)
-						minimal_cost = cost
-						visit = i
-					}
+				idx, cost := BestInsertion(cycles[c], i, distance_matrix)
+				if cost < minimal_cost {
+					minimal_cost = cost
+					visit, position = i, idx
 				}
 			}
-
-			cycle1 = append(new_cycle[:0:0], new_cycle...)
+			if visit == -1 {
+				return fmt.Errorf("%w: no unvisited node left for cycle %d", ErrInfeasible, c)
+			}
+			cycle, err := utils.Insert(cycles[c], position, visit)
+			if err != nil {
+				return err
+			}
+			cycles[c] = cycle
 			visited[visit] = true
+			grown = true
+		}
+	}
+	return nil
+}
 
+// wstawianie wierzchołków w miejsce o najmniejszym przyroście długości spośród wszystkich cykli, które nie osiągnęły max_sizes;
+// gdy pozostałe wierzchołki są potrzebne do uzupełnienia cykli do min_sizes, rozważane są tylko cykle poniżej minimum
+func GrowCyclesInBand(distance_matrix *utils.DistanceMatrix, cycles [][]int, min_sizes []int, max_sizes []int, visited []bool) error {
+	remaining := 0 // liczba nieodwiedzonych wierzchołków
+	for i := range visited {
+		if !visited[i] {
+			remaining++
+		}
+	}
+	for ; remaining > 0; remaining-- {
+		missing := 0 // brakujące wierzchołki do minimalnych rozmiarów
+		for c := range cycles {
+			missing += max(0, min_sizes[c]-len(cycles[c]))
 		}
-		if len(cycle2) < lenCycle2 {
-			visit = -1
-			minimal_cost = -1
-			for i := range nodes {
+		if missing > remaining {
+			return fmt.Errorf("%w: not enough nodes left to reach minimal cycle sizes", ErrInfeasible)
+		}
+		visit, position, cycle := -1, -1, -1
+		minimal_cost := math.MaxInt
+		for c := range cycles {
+			if len(cycles[c]) >= max_sizes[c] || (missing == remaining && len(cycles[c]) >= min_sizes[c]) {
+				continue
+			}
+			for i := range visited {
 				if visited[i] {
 					continue
 				}
-				for j := range cycle2 {
-					temp_cycle = utils.Insert(cycle2, j, i)
-					cost = 0
-					for node_idx := range temp_cycle {
-						cost += distance_matrix[temp_cycle[node_idx]][temp_cycle[(node_idx+1)%len(temp_cycle)]]
-					}
-					if minimal_cost == -1 || cost < minimal_cost {
-						new_cycle = append(temp_cycle[:0:0], temp_cycle...)
-						minimal_cost = cost
-						visit = i
-					}
+				idx, cost := BestInsertion(cycles[c], i, distance_matrix)
+				if cost < minimal_cost {
+					minimal_cost = cost
+					visit, position, cycle = i, idx, c
 				}
 			}
-			cycle2 = append(new_cycle[:0:0], new_cycle...)
-			visited[visit] = true
 		}
+		if visit == -1 {
+			return fmt.Errorf("%w: no cycle can take %d remaining nodes", ErrInfeasible, remaining)
+		}
+		extended, err := utils.Insert(cycles[cycle], position, visit)
+		if err != nil {
+			return err
+		}
+		cycles[cycle] = extended
+		visited[visit] = true
 	}
-	order[0] = cycle1
-	order[1] = cycle2
-
 	return nil
 }
-func Regret(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
-	start_node_1, start_node_2, _ := PickRandomNodes(nodes) // wybór startowych punktów
-
-	var (
-		visited []bool = make([]bool, len(nodes)) // tablica dodanych wierzchołków
-		cycle1  []int  = make([]int, 0, len(order[0]))
-		cycle2  []int  = make([]int, 0, len(order[1]))
-	)
-	visited[start_node_1] = true
-	visited[start_node_2] = true
-
-	cycle1 = append(cycle1, start_node_1)
-	cycle2 = append(cycle2, start_node_2)
 
-	node_val := 10000
-	node_idx := -1
-	for i, val := range distance_matrix[start_node_1] {
-		if val == 0 || visited[i] {
-			continue
-		}
-		if val < node_val {
-			node_val = val
-			node_idx = i
+// najlepsze miejsce wstawienia wierzchołka do cyklu (indeks przed którym wstawiamy) i przyrost długości cyklu
+func BestInsertion(cycle []int, node int, distance_matrix *utils.DistanceMatrix) (int, int) {
+	best_idx, best_cost := -1, math.MaxInt
+	for j := range cycle {
+		prev := utils.ElemBefore(cycle, j)
+		cost := distance_matrix.At(prev, node) + distance_matrix.At(node, cycle[j]) - distance_matrix.At(prev, cycle[j])
+		if cost < best_cost {
+			best_idx, best_cost = j, cost
 		}
 	}
-	cycle1 = append(cycle1, node_idx)
-	visited[node_idx] = true
+	return best_idx, best_cost
+}
 
-	node_val = 10000
-	node_idx = -1
-	for i, val := range distance_matrix[start_node_2] {
-		if val == 0 || visited[i] {
-			continue
-		}
-		if val < node_val {
-			node_val = val
-			node_idx = i
-		}
+func Regret(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, rng *rand.Rand) error {
+	start_nodes, err := PickRandomNodes(nodes, len(order), rng) // wybór startowych punktów
+	if err != nil {
+		return err
 	}
-	cycle2 = append(cycle2, node_idx)
-	visited[node_idx] = true
-
-	for len(cycle1) < len(order[0]) || len(cycle2) < len(order[1]) {
-		node1, node2, _ := BestNodes(cycle1, distance_matrix, visited)
-		best_score1, second_best_score1, idx1, _ := Calculate4Regret(node1, cycle1, distance_matrix)
-		regret1 := second_best_score1 - best_score1
-		best_score2, second_best_score2, idx2, _ := Calculate4Regret(node2, cycle1, distance_matrix)
-		regret2 := second_best_score2 - best_score2
-		if regret1 > regret2 {
-			cycle1 = utils.Insert(cycle1, idx1, node1)
-			visited[node1] = true
-		} else {
-			cycle1 = utils.Insert(cycle1, idx2, node2)
-			visited[node2] = true
-		}
-
-		node3, node4, _ := BestNodes(cycle2, distance_matrix, visited)
+	return RegretCycles(distance_matrix, order, nodes, start_nodes, 1, 0)
+}
 
-		best_score3, second_best_score3, idx3, _ := Calculate4Regret(node3, cycle2, distance_matrix)
-		regret3 := second_best_score3 - best_score3
-		best_score4, second_best_score4, idx4, _ := Calculate4Regret(node4, cycle2, distance_matrix)
-		regret4 := second_best_score4 - best_score4
-		if regret3 > regret4 {
-			cycle2 = utils.Insert(cycle2, idx3, node3)
-			visited[node3] = true
-		} else {
-			cycle2 = utils.Insert(cycle2, idx4, node4)
-			visited[node4] = true
-		}
+func WeightedRegret(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, rng *rand.Rand) error {
+	return weightedRegret(distance_matrix, order, nodes, DefaultRegretWeight, DefaultChangeWeight, rng)
+}
 
+func weightedRegret(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, weight_regret int, weight_change int, rng *rand.Rand) error {
+	start_nodes, err := PickRandomClosestNodes(distance_matrix, nodes, len(order), rng) // wybór startowych punktów
+	if err != nil {
+		return err
 	}
-	order[0] = cycle1
-	order[1] = cycle2
-	return nil
+	return RegretCycles(distance_matrix, order, nodes, start_nodes, weight_regret, weight_change)
 }
-func WeightedRegret(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
-	start_node_1, start_node_2, _ := PickRandomClosestNodes(distance_matrix, nodes) // wybór startowych punktów
 
+// budowa cykli heurystyką żalu ważonego: koszt = żal * weight_regret + najlepszy przyrost * weight_change
+// dla weight_change = 0 zwykły 2-żal
+func RegretCycles(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, start_nodes []int, weight_regret int, weight_change int) error {
 	var (
-		visited       []bool = make([]bool, len(nodes)) // tablica dodanych wierzchołków
-		cycle1        []int  = make([]int, 0, len(order[0]))
-		cycle2        []int  = make([]int, 0, len(order[1]))
-		weight_regret int    = 1
-		weight_change int    = -4
+		visited []bool  = make([]bool, len(nodes)) // tablica dodanych wierzchołków
+		cycles  [][]int = make([][]int, len(order))
 	)
-	visited[start_node_1] = true
-	visited[start_node_2] = true
-
-	cycle1 = append(cycle1, start_node_1)
-	cycle2 = append(cycle2, start_node_2)
-
-	node_val := 10000
-	node_idx := -1
-	for i, val := range distance_matrix[start_node_1] {
-		if val == 0 || visited[i] {
-			continue
-		}
-		if val < node_val {
-			node_val = val
-			node_idx = i
-		}
+	for c := range order {
+		cycles[c] = make([]int, 0, len(order[c]))
+		cycles[c] = append(cycles[c], start_nodes[c])
+		visited[start_nodes[c]] = true
 	}
-	cycle1 = append(cycle1, node_idx)
-	visited[node_idx] = true
-
-	node_val = 10000
-	node_idx = -1
-	for i, val := range distance_matrix[start_node_2] {
-		if val == 0 || visited[i] {
+	// drugi wierzchołek - najbliższy sąsiad startowego
+	for c := range order {
+		if len(order[c]) < 2 {
 			continue
 		}
-		if val < node_val {
-			node_val = val
-			node_idx = i
+		node_idx, err := utils.NearestNode(nodes, distance_matrix, start_nodes[c], visited)
+		if err != nil {
+			return err
 		}
+		cycles[c] = append(cycles[c], node_idx)
+		visited[node_idx] = true
 	}
-	cycle2 = append(cycle2, node_idx)
-	visited[node_idx] = true
 
-	for len(cycle1) < len(order[0]) || len(cycle2) < len(order[1]) {
-		node1, node2, _ := BestNodes(cycle1, distance_matrix, visited)
-
-		best_score1, second_best_score1, idx1, _ := Calculate4Regret(node1, cycle1, distance_matrix)
-		regret1 := second_best_score1 - best_score1
-		total_cost1 := regret1*weight_regret + best_score1*weight_change
-		best_score2, second_best_score2, idx2, _ := Calculate4Regret(node2, cycle1, distance_matrix)
-		regret2 := second_best_score2 - best_score2
-		total_cost2 := regret2*weight_regret + best_score2*weight_change
-		if total_cost1 > total_cost2 {
-			cycle1 = utils.Insert(cycle1, idx1, node1)
-			visited[node1] = true
-		} else {
-			cycle1 = utils.Insert(cycle1, idx2, node2)
-			visited[node2] = true
-		}
-
-		node3, node4, _ := BestNodes(cycle2, distance_matrix, visited)
+	for grown := true; grown; {
+		grown = false
+		for c := range cycles {
+			if len(cycles[c]) >= len(order[c]) {
+				continue
+			}
+			node1, node2, err := BestNodes(cycles[c], distance_matrix, visited)
+			if err != nil {
+				return err
+			}
 
-		best_score3, second_best_score3, idx3, _ := Calculate4Regret(node3, cycle2, distance_matrix)
-		regret3 := second_best_score3 - best_score3
-		total_cost3 := regret3*weight_regret + best_score3*weight_change
-		best_score4, second_best_score4, idx4, _ := Calculate4Regret(node4, cycle2, distance_matrix)
-		regret4 := second_best_score4 - best_score4
-		total_cost4 := regret4*weight_regret + best_score4*weight_change
-		if total_cost3 > total_cost4 {
-			cycle2 = utils.Insert(cycle2, idx3, node3)
-			visited[node3] = true
-		} else {
-			cycle2 = utils.Insert(cycle2, idx4, node4)
-			visited[node4] = true
+			best_score1, second_best_score1, idx1, err := Calculate4Regret(node1, cycles[c], distance_matrix)
+			if err != nil {
+				return err
+			}
+			regret1 := second_best_score1 - best_score1
+			total_cost1 := regret1*weight_regret + best_score1*weight_change
+			best_score2, second_best_score2, idx2, err := Calculate4Regret(node2, cycles[c], distance_matrix)
+			if err != nil {
+				return err
+			}
+			regret2 := second_best_score2 - best_score2
+			total_cost2 := regret2*weight_regret + best_score2*weight_change
+			node, idx := node2, idx2
+			if total_cost1 > total_cost2 {
+				node, idx = node1, idx1
+			}
+			if cycles[c], err = utils.Insert(cycles[c], idx, node); err != nil {
+				return err
+			}
+			visited[node] = true
+			grown = true
 		}
-
 	}
-	order[0] = cycle1
-	order[1] = cycle2
+	copy(order, cycles)
 	return nil
 }
 
-func Calculate4Regret(node1 int, cycle []int, distance_matrix [][]int) (int, int, int, error) {
+func Calculate4Regret(node1 int, cycle []int, distance_matrix *utils.DistanceMatrix) (int, int, int, error) {
 	minimal_cost := -1
 	second_minimal_cost := -1
 	idx := -1
 	for i := range cycle {
-		temp_cycle := utils.Insert(cycle, i, node1)
+		temp_cycle, err := utils.Insert(cycle, i, node1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
 		cost := utils.CalculateCycleLen(temp_cycle, distance_matrix)
 		if minimal_cost == -1 || cost < minimal_cost {
 			minimal_cost = cost
@@ -439,7 +324,7 @@ func Calculate4Regret(node1 int, cycle []int, distance_matrix [][]int) (int, int
 	}
 	return minimal_cost, second_minimal_cost, idx, nil
 }
-func BestNodes(cycle []int, distance_matrix [][]int, visited []bool) (int, int, error) {
+func BestNodes(cycle []int, distance_matrix *utils.DistanceMatrix, visited []bool) (int, int, error) {
 	var (
 		node1 int
 		node2 int
@@ -451,7 +336,10 @@ func BestNodes(cycle []int, distance_matrix [][]int, visited []bool) (int, int,
 			continue
 		}
 		for j := range cycle {
-			temp_cycle := utils.Insert(cycle, j, i)
+			temp_cycle, err := utils.Insert(cycle, j, i)
+			if err != nil {
+				return 0, 0, err
+			}
 			cost := utils.CalculateCycleLen(temp_cycle, distance_matrix)
 			if cost > second_worst_cost {
 				second_worst_cost = cost
@@ -465,29 +353,20 @@ func BestNodes(cycle []int, distance_matrix [][]int, visited []bool) (int, int,
 	return node1, node2, nil
 }
 
-func Random(distance_matrix [][]int, order [][]int, nodes []reader.Node) error {
-	result := make([][]int, NumCycles)
-	nodes_copy := make([]reader.Node, len(nodes))
-	copy(nodes_copy, nodes)             // kopiowanie tablicy nodes do nowej tablicy
+// losowy przydział wierzchołków do cykli o długościach z order
+func Random(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node, rng *rand.Rand) error {
 	nodes_nr := make([]int, len(nodes)) // tablica z numerami wierzchołków
 	for i := range nodes {
 		nodes_nr[i] = i
 	}
-	for len(nodes_copy) > 0 {
-		for i := 0; i < NumCycles; i++ {
-			if len(nodes_copy) == 0 {
-				break
-			}
-			node_idx, err := PickRandomNode(nodes_copy)
-			if err != nil {
-				return err
-			}
-			result[i] = append(result[i], nodes_nr[node_idx]) // dodanie wierzchołka do cyklu
-			// usunięcie wierzchołka z list
-			nodes_copy = append(nodes_copy[:node_idx], nodes_copy[node_idx+1:]...)
-			nodes_nr = append(nodes_nr[:node_idx], nodes_nr[node_idx+1:]...)
+	nodes_nr = FisherYatesShuffle(nodes_nr, rng)
+	start := 0
+	for c := range order {
+		if start+len(order[c]) > len(nodes_nr) {
+			return fmt.Errorf("%w: cycle sizes exceed number of nodes (%d)", ErrInfeasible, len(nodes))
 		}
+		order[c] = append([]int(nil), nodes_nr[start:start+len(order[c])]...) // dodanie wierzchołków do cyklu
+		start += len(order[c])
 	}
-	copy(order, result)
 	return nil
 }
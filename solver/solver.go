@@ -3,166 +3,222 @@ package solver
 import (
 	"IMO/reader"
 	"IMO/utils"
+	"context"
 	"fmt"
-	"math"
 	"math/rand"
 )
 
-const (
-	NumCycles int     = 2
-	Split     float64 = 0.5
-)
+// domyślna liczba cykli (zadania z kursu - 2 cykle)
+const DefaultNumCycles int = 2
+
+// równy podział wierzchołków między cykle; przy reszcie z dzielenia ostatnie cykle dostają o 1 wierzchołek więcej
+func CycleSizes(num_nodes int, num_cycles int) []int {
+	sizes := make([]int, num_cycles)
+	for c := range sizes {
+		sizes[c] = num_nodes / num_cycles
+		if c >= num_cycles-num_nodes%num_cycles {
+			sizes[c]++
+		}
+	}
+	return sizes
+}
+
+// zajęcie pamięci dla macierzy order o podanych długościach cykli
+func NewOrder(sizes []int) [][]int {
+	order := make([][]int, len(sizes))
+	for c := range sizes {
+		order[c] = make([]int, sizes[c])
+	}
+	return order
+}
+
+// instancja gotowa do obliczeń: niepusta, z macierzą odległości dla wszystkich wierzchołków
+func checkInstance(nodes []reader.Node, distance_matrix *utils.DistanceMatrix) error {
+	if len(nodes) == 0 {
+		return fmt.Errorf("%w: no nodes", ErrInvalidInstance)
+	}
+	if distance_matrix == nil || distance_matrix.Dimension != len(nodes) {
+		return fmt.Errorf("%w: distance matrix does not match %d nodes", ErrInvalidInstance, len(nodes))
+	}
+	return nil
+}
 
-func Solve(nodes []reader.Node, algorithm string, distance_matrix [][]int) ([][]int, error) {
-	var (
-		order           [][]int = make([][]int, NumCycles) // kolejność odwiedzania wierzchołków dla obydwu cykli
-		nodes_cycle_one int                                // liczba wierzchołków w cyklu 1
-	)
-	// stworzenie macierzy odległości
+func checkLimits(nodes []reader.Node, limits *SizeLimits) error {
+	if limits == nil {
+		return fmt.Errorf("%w: no cycle size limits given", ErrInvalidConfig)
+	}
+	sum := 0
+	for _, size := range limits.Target {
+		sum += size
+	}
+	if sum != len(nodes) {
+		return fmt.Errorf("%w: cycle sizes sum up to %d, expected %d nodes", ErrInvalidConfig, sum, len(nodes))
+	}
+	return nil
+}
 
+// konstrukcja rozwiązania startowego heurystyką cfg.Heuristic; cfg == nil - konfiguracja domyślna
+func Solve(nodes []reader.Node, cfg *Config, distance_matrix *utils.DistanceMatrix, limits *SizeLimits) ([][]int, error) {
+	cfg, err := resolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInstance(nodes, distance_matrix); err != nil {
+		return nil, err
+	}
+	if err := checkLimits(nodes, limits); err != nil {
+		return nil, err
+	}
 	// zajęcie pamięci dla macierzy order
-	nodes_cycle_one = int(float64(len(distance_matrix)) * Split)
-	order[0] = make([]int, nodes_cycle_one)
-	order[1] = make([]int, len(nodes)-nodes_cycle_one)
-
-	// wybór algorytmu
-	var f func([][]int, [][]int, []reader.Node) error
-	switch algorithm {
-	case "nn": // nearest neighbour - najbliższy sąsiad
-		f = NearestNeighbour
-	case "gc": // greedy cycle - rozbudowa cyklu
-		f = GreedyCycle
-	case "reg": // regret - żal
-		f = Regret
-	case "wreg": // weighted regret - żal ważony
-		f = WeightedRegret
-	case "rand": // rozwiązanie losowe
-		f = Random
-	default:
-		f = InOrder
-	}
-
-	err := f(distance_matrix, order, nodes)
+	var order [][]int = NewOrder(limits.Target) // kolejność odwiedzania wierzchołków dla wszystkich cykli
+
+	f, err := HeuristicFunc(cfg, cfg.Rand())
 	if err != nil {
 		return nil, err
 	}
+	err = f(distance_matrix, order, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.Heuristic, err)
+	}
 
 	return order, nil
 }
 
-func Local_search(start_order [][]int, algorithm string, distance_matrix [][]int) ([][]int, error) {
-	var order [][]int = make([][]int, NumCycles)
-	copy(order, start_order)
-	order = append(start_order[:0:0], start_order...)
-	var f func([][]int, [][]int) error
-	switch algorithm {
-	case "sn":
-		f = SteepestNode
-	case "se":
-		f = SteepestEdge
-	case "gn":
-		f = GreedyNode
-	case "ge":
-		f = GreedyEdge
-	case "rw":
-		f = RandomWalk
-	case "fls":
-		f = FastLocalSearch
-	case "c":
-		f = CandidateSearch
-	default:
-		f = SteepestEdge
-	}
-	err := f(distance_matrix, order)
+// zarejestrowana heurystyka konstrukcyjna cfg.Heuristic z parametrami z cfg i generatorem rng
+func HeuristicFunc(cfg *Config, rng *rand.Rand) (func(*utils.DistanceMatrix, [][]int, []reader.Node) error, error) {
+	construct, err := constructors.lookup(string(cfg.Heuristic))
 	if err != nil {
 		return nil, err
 	}
+	return func(distance_matrix *utils.DistanceMatrix, order [][]int, nodes []reader.Node) error {
+		return construct(distance_matrix, order, nodes, cfg, rng)
+	}, nil
+}
+
+// lokalne przeszukiwanie cfg.LocalSearch na kopii start_order; cfg == nil - konfiguracja domyślna.
+// Zakończenie ctx przerywa przeszukiwanie - zwracane jest rozwiązanie po dotychczasowych ruchach
+func Local_search(ctx context.Context, start_order [][]int, cfg *Config, distance_matrix *utils.DistanceMatrix, limits *SizeLimits) ([][]int, error) {
+	cfg, err := resolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if distance_matrix == nil {
+		return nil, fmt.Errorf("%w: no distance matrix given", ErrInvalidInstance)
+	}
+	if limits == nil {
+		return nil, fmt.Errorf("%w: no cycle size limits given", ErrInvalidConfig)
+	}
+	if err := limits.Check(start_order); err != nil {
+		return nil, fmt.Errorf("start solution: %w", err)
+	}
+	local_search, err := LocalSearchFunc(cfg, cfg.Rand())
+	if err != nil {
+		return nil, err
+	}
+	var order [][]int = make([][]int, len(start_order)) // kopia - przeniesienia wierzchołków zmieniają długości cykli
+	if err := utils.CopyCycles(order, start_order); err != nil {
+		return nil, fmt.Errorf("start solution: %w", err)
+	}
+	err = local_search(ctx, distance_matrix, order, limits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.LocalSearch, err)
+	}
 	return order, nil
 }
 
-func Local_search_alternatives(nodes []reader.Node, algorithm string, distance_matrix [][]int, num_of_iterations int) ([][]int, int, error) {
-	var (
-		order           [][]int = make([][]int, NumCycles)
-		nodes_cycle_one int
-	)
-	nodes_cycle_one = int(float64(len(distance_matrix)) * Split)
-	order[0] = make([]int, nodes_cycle_one)
-	order[1] = make([]int, len(nodes)-nodes_cycle_one)
-	var f func([][]int, [][]int, []reader.Node, int) (int, error)
-	switch algorithm {
-	case "msls":
-		f = MSLS
-	case "ils":
-		f = ILS
-	case "lns-ls":
-		f = LNSWithLS
-	case "lns":
-		f = LNSWithoutLS
-	}
-	iter, err := f(distance_matrix, order, nodes, num_of_iterations)
+// zarejestrowane lokalne przeszukiwanie cfg.LocalSearch z parametrami z cfg i generatorem rng
+func LocalSearchFunc(cfg *Config, rng *rand.Rand) (func(context.Context, *utils.DistanceMatrix, [][]int, *SizeLimits) error, error) {
+	improve, err := improvers.lookup(string(cfg.LocalSearch))
 	if err != nil {
-		panic("Error")
+		return nil, err
 	}
-	return order, iter, nil
+	return func(ctx context.Context, distance_matrix *utils.DistanceMatrix, order [][]int, limits *SizeLimits) error {
+		return improve(ctx, distance_matrix, order, limits, cfg, rng)
+	}, nil
 }
 
-func HAE(nodes []reader.Node, distance_matrix [][]int, time_limit int, heuristic_algorithm string, local_search_algorithm string, local_search bool, population_size int) ([][]int, int, error) {
-	var (
-		order           [][]int = make([][]int, NumCycles)
-		nodes_cycle_one int
-	)
-	nodes_cycle_one = int(float64(len(distance_matrix)) * Split)
-	order[0] = make([]int, nodes_cycle_one)
-	order[1] = make([]int, len(nodes)-nodes_cycle_one)
-	var f func([][]int, [][]int, []reader.Node, int, string, string, int) (int, error)
-	if local_search {
-		f = HAEWithLS
-	} else {
-		f = HAEWithoutLS
-	}
-	iter, err := f(distance_matrix, order, nodes, time_limit, heuristic_algorithm, local_search_algorithm, population_size)
+// zarejestrowana metaheurystyka cfg.Metaheuristic z wewnętrznym lokalnym przeszukiwaniem cfg.LocalSearch;
+// cfg == nil - konfiguracja domyślna. Zakończenie ctx kończy algorytm z najlepszym dotąd rozwiązaniem
+func Local_search_alternatives(ctx context.Context, nodes []reader.Node, cfg *Config, distance_matrix *utils.DistanceMatrix, limits *SizeLimits) ([][]int, int, error) {
+	cfg, err := resolveConfig(cfg)
 	if err != nil {
-		panic("Error")
+		return nil, 0, err
 	}
-	return order, iter, nil
+	return runMetaheuristic(ctx, nodes, cfg, distance_matrix, limits)
 }
 
-func EucDist(a, b reader.Node) int {
-	return int(
-		math.Round(
-			math.Sqrt(
-				math.Pow(float64(a.X-b.X), 2) + math.Pow(float64(a.Y-b.Y), 2),
-			),
-		), // fajna opcja żeby lepszą czytelność mieć ale śmieszne, że przecinki się daje przed nową linią żeby dobrze parsował kompilator
-	)
+// hybrydowy algorytm ewolucyjny: cfg.Metaheuristic hae lub hae-ls (z lokalnym przeszukiwaniem potomków),
+// ihae lub ihae-ls na wyspach cfg.Islands; cfg.Replacement - wymiana osobników z uwzględnieniem różnorodności;
+// cfg == nil - konfiguracja domyślna z hae. Zakończenie ctx kończy algorytm z najlepszym dotąd rozwiązaniem
+func HAE(ctx context.Context, nodes []reader.Node, cfg *Config, distance_matrix *utils.DistanceMatrix, limits *SizeLimits) ([][]int, int, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+		cfg.Metaheuristic = MetaheuristicHAE
+	}
+	cfg, err := resolveConfig(cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return runMetaheuristic(ctx, nodes, cfg, distance_matrix, limits)
 }
 
-func PickFarthestNodes(distance_matrix [][]int, nodes []reader.Node) (int, int, error) {
-	x, y, _ := utils.MatrixMax(distance_matrix)
+func runMetaheuristic(ctx context.Context, nodes []reader.Node, cfg *Config, distance_matrix *utils.DistanceMatrix, limits *SizeLimits) ([][]int, int, error) {
+	if err := checkInstance(nodes, distance_matrix); err != nil {
+		return nil, 0, err
+	}
+	if err := checkLimits(nodes, limits); err != nil {
+		return nil, 0, err
+	}
+	f, err := metaheuristics.lookup(string(cfg.Metaheuristic))
+	if err != nil {
+		return nil, 0, err
+	}
+	var order [][]int = NewOrder(limits.Target)
+	iter, err := f(ctx, distance_matrix, order, nodes, cfg, limits, cfg.Rand())
+	if err != nil {
+		return nil, iter, fmt.Errorf("%s: %w", cfg.Metaheuristic, err)
+	}
+	return order, iter, nil
+}
+
+func PickFarthestNodes(distance_matrix *utils.DistanceMatrix, nodes []reader.Node) (int, int, error) {
+	x, y, _ := distance_matrix.Max()
 	return x, y, nil
 }
 
-func PickRandomNodes(nodes []reader.Node) (int, int, error) {
-	node1 := rand.Intn(len(nodes))
-	node2 := node1
-	for node1 == node2 {
-		node2 = rand.Intn(len(nodes))
+// num różnych losowych wierzchołków
+func PickRandomNodes(nodes []reader.Node, num int, rng *rand.Rand) ([]int, error) {
+	if num > len(nodes) {
+		return nil, fmt.Errorf("%w: cannot pick %d distinct nodes out of %d", ErrInfeasible, num, len(nodes))
 	}
-	return node1, node2, nil
+	picked := make([]int, 0, num)
+	used := make(map[int]utils.Empty, num)
+	for len(picked) < num {
+		node := rng.Intn(len(nodes))
+		if _, ok := used[node]; ok {
+			continue
+		}
+		used[node] = utils.Empty{}
+		picked = append(picked, node)
+	}
+	return picked, nil
 }
 
-func PickRandomNode(nodes []reader.Node) (int, error) {
-	node1 := rand.Intn(len(nodes))
+func PickRandomNode(nodes []reader.Node, rng *rand.Rand) (int, error) {
+	if len(nodes) == 0 {
+		return -1, fmt.Errorf("%w: no nodes to pick from", ErrInvalidInstance)
+	}
+	node1 := rng.Intn(len(nodes))
 	return node1, nil
 }
 
-func PickRandomFarthest(distance_matrix [][]int, nodes []reader.Node) (int, int, error) {
+func PickRandomFarthest(distance_matrix *utils.DistanceMatrix, nodes []reader.Node, rng *rand.Rand) (int, int, error) {
 	visited := make([]bool, len(nodes))
-	node1, err := PickRandomNode(nodes)
-	visited[node1] = true
+	node1, err := PickRandomNode(nodes, rng)
 	if err != nil {
 		return -1, -1, err
 	}
+	visited[node1] = true
 	node2, err := utils.FarthestNode(nodes, distance_matrix, node1, visited)
 	if err != nil {
 		return -1, -1, err
@@ -170,31 +226,42 @@ func PickRandomFarthest(distance_matrix [][]int, nodes []reader.Node) (int, int,
 	return node1, node2, nil
 }
 
-func PickRandomClosestNodes(distance_matrix [][]int, nodes []reader.Node) (int, int, error) {
-	idx := rand.Intn(len(nodes))
-	node_val := 10000
-	node2_idx := -1
-	for i, val := range distance_matrix[idx] {
-		if val == 0 {
-			continue
-		}
-		if val < node_val {
-			node_val = val
-			node2_idx = i
+// losowy wierzchołek i jego num-1 najbliższych sąsiadów
+func PickRandomClosestNodes(distance_matrix *utils.DistanceMatrix, nodes []reader.Node, num int, rng *rand.Rand) ([]int, error) {
+	if num > len(nodes) {
+		return nil, fmt.Errorf("%w: cannot pick %d distinct nodes out of %d", ErrInfeasible, num, len(nodes))
+	}
+	visited := make([]bool, len(nodes))
+	idx := rng.Intn(len(nodes))
+	visited[idx] = true
+	picked := []int{idx}
+	for len(picked) < num {
+		nearest, err := utils.NearestNode(nodes, distance_matrix, idx, visited)
+		if err != nil {
+			return nil, err
 		}
+		visited[nearest] = true
+		picked = append(picked, nearest)
 	}
-	return idx, node2_idx, nil
+	return picked, nil
 }
 
 func ValidateOrder(order [][]int, nodes []reader.Node) error {
 	var visited []bool = make([]bool, len(nodes))
-	if len(order[0])+len(order[1]) < len(nodes) {
-		return fmt.Errorf("not all nodes visited")
+	num_visited := 0
+	for i := range order {
+		num_visited += len(order[i])
+	}
+	if num_visited < len(nodes) {
+		return fmt.Errorf("%w: not all nodes visited", ErrInfeasible)
 	}
 	for i := range order {
 		for j := range order[i] {
+			if order[i][j] < 0 || order[i][j] >= len(nodes) {
+				return fmt.Errorf("%w: node %v out of range", ErrInfeasible, order[i][j])
+			}
 			if visited[order[i][j]] {
-				return fmt.Errorf("node %v visited more than once", order[i][j])
+				return fmt.Errorf("%w: node %v visited more than once", ErrInfeasible, order[i][j])
 			}
 			visited[order[i][j]] = true
 		}
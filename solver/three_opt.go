@@ -0,0 +1,163 @@
+package solver
+
+import (
+	"IMO/utils"
+)
+
+// sposób połączenia segmentów w ruchu 3-opt; cykl dzielony na A = [0..I], S1 = [I+1..J], S2 = [J+1..K], C = [K+1..].
+// Tylko cztery połączenia z trzema nowymi krawędziami - pozostałe trzy zostawiają jedną z usuniętych krawędzi,
+// czyli są ruchami 2-opt (MoveEdgeDetail)
+type ThreeOptCase int
+
+const (
+	ThreeOptReverseEach       ThreeOptCase = iota + 1 // A rev(S1) rev(S2) C
+	ThreeOptSwapReverseSecond                         // A rev(S2) S1 C
+	ThreeOptSwap                                      // A S2 S1 C - or3opt, przeniesienie segmentu bez odwracania
+	ThreeOptSwapReverseFirst                          // A S2 rev(S1) C
+)
+
+var ThreeOptCaseType = map[ThreeOptCase]string{
+	ThreeOptReverseEach:       "ReverseEach",
+	ThreeOptSwapReverseSecond: "SwapReverseSecond",
+	ThreeOptSwap:              "Swap",
+	ThreeOptSwapReverseFirst:  "SwapReverseFirst",
+}
+
+func (c ThreeOptCase) String() string {
+	return ThreeOptCaseType[c]
+}
+
+// ruch - usunięcie krawędzi po wierzchołkach o indeksach I < J < K w cyklu Cycle i ponowne połączenie segmentów
+type ThreeOptMove struct {
+	Cycle int
+	I     int          // koniec segmentu A - nr w cyklu
+	J     int          // koniec segmentu S1 - nr w cyklu
+	K     int          // koniec segmentu S2 - nr w cyklu
+	Case  ThreeOptCase // sposób połączenia segmentów
+	Delta int          // zmiana długości cyklu
+}
+
+func (m *ThreeOptMove) ExecuteMove(tour *Tour) error {
+	cycle := tour.Order[m.Cycle]
+	s1 := append([]int(nil), cycle[m.I+1:m.J+1]...)
+	s2 := append([]int(nil), cycle[m.J+1:m.K+1]...)
+	switch m.Case {
+	case ThreeOptReverseEach:
+		utils.Reverse(s1)
+		utils.Reverse(s2)
+	case ThreeOptSwapReverseSecond:
+		utils.Reverse(s2)
+		s1, s2 = s2, s1
+	case ThreeOptSwap:
+		s1, s2 = s2, s1
+	case ThreeOptSwapReverseFirst:
+		utils.Reverse(s1)
+		s1, s2 = s2, s1
+	}
+	tour.Assign(m.Cycle, m.I+1, s1)
+	tour.Assign(m.Cycle, m.I+1+len(s1), s2)
+	return nil
+}
+
+func (m *ThreeOptMove) GetDelta() int {
+	return m.Delta // zmiana długości cyklu
+}
+
+func (m *ThreeOptMove) SetDelta(delta int) {
+	m.Delta = delta
+}
+
+// zmiana długości cyklu po ruchu 3-opt
+func ThreeOptDelta(distance_matrix *utils.DistanceMatrix, cycle []int, i int, j int, k int, reconnection ThreeOptCase) int {
+	var (
+		a, an = cycle[i], cycle[i+1]                // krawędź po A
+		b, bn = cycle[j], cycle[j+1]                // krawędź po S1
+		c, cn = cycle[k], utils.ElemAfter(cycle, k) // krawędź po S2
+		d     = distance_matrix.At                  // skrót
+		added int                                   // suma nowych krawędzi
+	)
+	switch reconnection {
+	case ThreeOptReverseEach:
+		added = d(a, b) + d(an, c) + d(bn, cn)
+	case ThreeOptSwapReverseSecond:
+		added = d(a, c) + d(bn, an) + d(b, cn)
+	case ThreeOptSwap:
+		added = d(a, bn) + d(c, an) + d(b, cn)
+	case ThreeOptSwapReverseFirst:
+		added = d(a, bn) + d(c, b) + d(an, cn)
+	}
+	return added - d(a, an) - d(b, bn) - d(c, cn)
+}
+
+// ruchy 3-opt poprawiające wynik ograniczone do list kandydatów: nowa krawędź od wierzchołka a (indeks I) do kandydata
+// oraz druga nowa krawędź do kandydata sąsiada; wszystkie cztery przypadki ThreeOptCase (2-opt jest w AllCandidateMoves).
+// Pełnego sąsiedztwa 3-opt (O(n^3) ruchów) nie ma - tylko wariant kandydacki
+func CandidateThreeOptMoves(distance_matrix *utils.DistanceMatrix, tour *Tour, candidates [][]int) []Move {
+	var (
+		order  [][]int = tour.Order
+		moves  []ThreeOptMove
+		result []Move
+	)
+	add := func(c int, i int, j int, k int, reconnection ThreeOptCase) {
+		delta := ThreeOptDelta(distance_matrix, order[c], i, j, k, reconnection)
+		if delta < 0 {
+			moves = append(moves, ThreeOptMove{Cycle: c, I: i, J: j, K: k, Case: reconnection, Delta: delta})
+		}
+	}
+
+	for c, cycle := range order {
+		n := len(cycle)
+		if n < 5 {
+			continue
+		}
+		// pozycje kandydatów w tym samym cyklu
+		positions := func(node int) []int {
+			var result []int
+			for _, candidate := range candidates[node] {
+				if tour.Contains(c, candidate) {
+					result = append(result, tour.Position(candidate))
+				}
+			}
+			return result
+		}
+		for i := 0; i < n-2; i++ {
+			a, an := cycle[i], cycle[i+1]
+			an_positions := positions(an)
+			for _, x := range positions(a) {
+				// x = b: nowe krawędzie a - b, a' - c, b' - c'
+				if j := x; j > i && j < n-1 {
+					for _, k := range an_positions {
+						if k > j {
+							add(c, i, j, k, ThreeOptReverseEach)
+						}
+					}
+				}
+				// x = b': nowe krawędzie a - b' oraz c - a' (or3opt) lub c - b
+				if j := x - 1; j > i && j < n-1 {
+					for _, k := range an_positions {
+						if k > j {
+							add(c, i, j, k, ThreeOptSwap)
+						}
+					}
+					for _, k := range positions(cycle[j]) {
+						if k > j {
+							add(c, i, j, k, ThreeOptSwapReverseFirst)
+						}
+					}
+				}
+				// x = c: nowe krawędzie a - c, b' - a', b - c'
+				if k := x; k > i+1 {
+					for _, y := range an_positions {
+						if j := y - 1; j > i && j < k {
+							add(c, i, j, k, ThreeOptSwapReverseSecond)
+						}
+					}
+				}
+			}
+		}
+	}
+	for m := range moves {
+		result = append(result, &moves[m])
+	}
+	return result
+}
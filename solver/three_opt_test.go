@@ -0,0 +1,55 @@
+package solver
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// delta każdego z czterech połączeń 3-opt dla wszystkich I < J < K równa różnicy przeliczonych długości cykli
+func TestThreeOptDelta(t *testing.T) {
+	for _, reconnection := range []ThreeOptCase{ThreeOptReverseEach, ThreeOptSwapReverseSecond, ThreeOptSwap, ThreeOptSwapReverseFirst} {
+		t.Run(reconnection.String(), func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				_, distance_matrix := testInstance(t, 14, rng)
+				order := randomOrder([]int{9, 5}, rng)
+				var moves []Move
+				for c := range order {
+					n := len(order[c])
+					for i := 0; i < n; i++ {
+						for j := i + 1; j < n; j++ {
+							for k := j + 1; k < n; k++ {
+								moves = append(moves, &ThreeOptMove{Cycle: c, I: i, J: j, K: k, Case: reconnection,
+									Delta: ThreeOptDelta(distance_matrix, order[c], i, j, k, reconnection)})
+							}
+						}
+					}
+				}
+				checkDeltas(t, reconnection.String(), distance_matrix, order, moves)
+			}
+		})
+	}
+}
+
+// ruchy kandydackie 3-opt poprawiają wynik o swoją deltę
+func TestCandidateThreeOptMoves(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		_, distance_matrix := testInstance(t, 30, rng)
+		order := randomOrder([]int{16, 14}, rng)
+		candidates, err := CalculateCandidates(distance_matrix, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves := CandidateThreeOptMoves(distance_matrix, NewTour(cloneOrder(order)), candidates)
+		if len(moves) == 0 {
+			t.Errorf("seed %d: no improving 3-opt moves in a random solution", seed)
+		}
+		for _, move := range moves {
+			if move.GetDelta() >= 0 {
+				t.Errorf("seed %d: %+v does not improve", seed, move)
+			}
+		}
+		checkDeltas(t, "candidate", distance_matrix, order, moves)
+	}
+}
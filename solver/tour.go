@@ -0,0 +1,179 @@
+package solver
+
+import (
+	"IMO/utils"
+	"fmt"
+)
+
+// cykle z tablicami pozycji wierzchołków - wyszukiwanie cyklu, indeksu, następnika i poprzednika w O(1);
+// wszystkie zmiany cykli w ruchach przechodzą przez Tour, żeby pozycje były aktualne.
+// Koszt zmian: SwapNodes O(1), Reverse - krótsza strona, O(n/2). Remove, Insert, RemoveSegment i InsertSegment
+// przesuwają w tablicy cyklu wierzchołki za miejscem zmiany i aktualizują tylko ich pozycje - O(n - i),
+// więc przeniesienia i Or-opt nadal kosztują O(n) na ruch (tablica cyklu jest współdzielona z order,
+// dlatego nie jest listą wiązaną)
+type Tour struct {
+	Order    [][]int // cykle - współdzielone z order przekazanym do NewTour
+	cycle_of []int   // numer cyklu wierzchołka; -1 - wierzchołek poza cyklami
+	position []int   // indeks wierzchołka w cyklu
+}
+
+func NewTour(order [][]int) *Tour {
+	size := 0 // największy numer wierzchołka + 1
+	for c := range order {
+		for _, n := range order[c] {
+			size = max(size, n+1)
+		}
+	}
+	t := &Tour{
+		Order:    order,
+		cycle_of: make([]int, size),
+		position: make([]int, size),
+	}
+	t.Sync()
+	return t
+}
+
+// przeliczenie pozycji wszystkich wierzchołków - po zmianach cykli wykonanych poza Tour
+func (t *Tour) Sync() {
+	for n := range t.cycle_of {
+		t.cycle_of[n] = -1
+	}
+	for c := range t.Order {
+		t.updatePositions(c, 0, len(t.Order[c]))
+	}
+}
+
+// aktualizacja pozycji wierzchołków o indeksach from..to-1 w cyklu c
+func (t *Tour) updatePositions(c int, from int, to int) {
+	for i := from; i < to; i++ {
+		n := t.Order[c][i]
+		t.cycle_of[n] = c
+		t.position[n] = i
+	}
+}
+
+// indeksy wierzchołków w ich cyklach
+func (t *Tour) Positions(nodes []int) []int {
+	positions := make([]int, len(nodes))
+	for k, n := range nodes {
+		positions[k] = t.position[n]
+	}
+	return positions
+}
+
+// numer cyklu wierzchołka lub -1
+func (t *Tour) Cycle(node int) int {
+	if node < 0 || node >= len(t.cycle_of) {
+		return -1
+	}
+	return t.cycle_of[node]
+}
+
+// indeks wierzchołka w jego cyklu
+func (t *Tour) Position(node int) int {
+	return t.position[node]
+}
+
+// czy wierzchołek należy do cyklu c
+func (t *Tour) Contains(c int, node int) bool {
+	return t.Cycle(node) == c
+}
+
+// następnik wierzchołka w cyklu
+func (t *Tour) Succ(node int) int {
+	return utils.ElemAfter(t.Order[t.cycle_of[node]], t.position[node])
+}
+
+// poprzednik wierzchołka w cyklu
+func (t *Tour) Pred(node int) int {
+	return utils.ElemBefore(t.Order[t.cycle_of[node]], t.position[node])
+}
+
+// czy wierzchołki a i b są połączone krawędzią
+func (t *Tour) Adjacent(a int, b int) bool {
+	return t.Cycle(a) != -1 && t.Cycle(a) == t.Cycle(b) && (t.Succ(a) == b || t.Pred(a) == b)
+}
+
+// odwrócenie ścieżki od indeksu i do j (w przód, z zawinięciem) w cyklu c; odwracana jest krótsza strona -
+// odwrócenie dopełnienia daje ten sam cykl przechodzony w przeciwną stronę
+func (t *Tour) Reverse(c int, i int, j int) {
+	cycle := t.Order[c]
+	n := len(cycle)
+	length := (j-i+n)%n + 1 // liczba wierzchołków na ścieżce
+	if 2*length > n {
+		i, j = (j+1)%n, (i-1+n)%n
+		length = n - length
+	}
+	for k := 0; k < length/2; k++ {
+		a, b := (i+k)%n, (j-k+n)%n
+		cycle[a], cycle[b] = cycle[b], cycle[a]
+		t.position[cycle[a]], t.position[cycle[b]] = a, b
+	}
+}
+
+// zamiana miejscami dwóch wierzchołków (w tym samym lub różnych cyklach)
+func (t *Tour) SwapNodes(n1 int, n2 int) {
+	c1, c2 := t.cycle_of[n1], t.cycle_of[n2]
+	i, j := t.position[n1], t.position[n2]
+	t.Order[c1][i], t.Order[c2][j] = n2, n1
+	t.cycle_of[n1], t.cycle_of[n2] = c2, c1
+	t.position[n1], t.position[n2] = j, i
+}
+
+// zamiana wierzchołków o indeksach i w cyklu c1 oraz j w cyklu c2
+func (t *Tour) SwapAt(c1 int, i int, c2 int, j int) {
+	t.SwapNodes(t.Order[c1][i], t.Order[c2][j])
+}
+
+// usunięcie wierzchołka z jego cyklu
+func (t *Tour) Remove(node int) {
+	c, i := t.cycle_of[node], t.position[node]
+	t.Order[c] = utils.Remove(t.Order[c], i)
+	t.cycle_of[node] = -1
+	t.updatePositions(c, i, len(t.Order[c]))
+}
+
+// wstawienie wierzchołka przed indeks idx w cyklu c; idx == długość cyklu - na końcu
+func (t *Tour) Insert(c int, idx int, node int) error {
+	cycle, err := utils.Insert(t.Order[c], idx, node)
+	if err != nil {
+		return fmt.Errorf("%w: cycle %d: %w", ErrInfeasible, c, err)
+	}
+	t.Order[c] = cycle
+	t.updatePositions(c, idx, len(t.Order[c]))
+	return nil
+}
+
+// usunięcie length wierzchołków od indeksu start (z zawinięciem) z cyklu c
+func (t *Tour) RemoveSegment(c int, start int, length int) {
+	n := len(t.Order[c])
+	for k := 0; k < length; k++ {
+		t.cycle_of[t.Order[c][(start+k)%n]] = -1
+	}
+	from := start // wierzchołki przed segmentem zostają na miejscu
+	if start+length > n {
+		from = 0 // segment z zawinięciem - usunięty też początek cyklu, przesuwają się wszystkie
+	}
+	t.Order[c] = RemoveSegment(t.Order[c], start, length)
+	t.updatePositions(c, from, len(t.Order[c]))
+}
+
+// wstawienie segmentu przed indeks idx w cyklu c
+func (t *Tour) InsertSegment(c int, idx int, segment []int) {
+	t.Order[c] = InsertSegment(t.Order[c], idx, segment)
+	t.updatePositions(c, idx, len(t.Order[c]))
+}
+
+// nadpisanie wierzchołków cyklu c od indeksu start
+func (t *Tour) Assign(c int, start int, nodes []int) {
+	copy(t.Order[c][start:], nodes)
+	t.updatePositions(c, start, start+len(nodes))
+}
+
+// indeksy kolejnych wierzchołków segmentu w cyklu c (w przód lub w tył); posortowane od pierwszego indeksu usuwanego fragmentu
+func (t *Tour) SegmentPositions(c int, segment []int) ([]int, bool) {
+	if !t.Contains(c, segment[0]) {
+		return nil, false
+	}
+	return SegmentPositions(t.Order[c], segment, t.position[segment[0]])
+}
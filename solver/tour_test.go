@@ -0,0 +1,164 @@
+package solver
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// sprawdzenie niezmienników Tour: pozycje i cykle wierzchołków zgodne z Order, wierzchołki spoza Order bez cyklu
+func checkTour(t *testing.T, tour *Tour, num_nodes int, step int, op string) {
+	t.Helper()
+	in_order := make([]bool, len(tour.cycle_of))
+	count := 0
+	for c := range tour.Order {
+		for i, n := range tour.Order[c] {
+			if tour.Cycle(n) != c || tour.Position(n) != i {
+				t.Fatalf("step %d (%s): node %d at cycle %d index %d, tour says cycle %d index %d", step, op, n, c, i, tour.Cycle(n), tour.Position(n))
+			}
+			in_order[n] = true
+			count++
+		}
+	}
+	for n := range in_order {
+		if !in_order[n] && tour.Cycle(n) != -1 {
+			t.Fatalf("step %d (%s): node %d outside of cycles has cycle %d", step, op, n, tour.Cycle(n))
+		}
+	}
+	if count != num_nodes {
+		t.Fatalf("step %d (%s): %d nodes in cycles, expected %d", step, op, count, num_nodes)
+	}
+}
+
+// nieskierowane krawędzie cyklu
+func cycleEdges(cycle []int) []edgeKey {
+	edges := make([]edgeKey, len(cycle))
+	for i := range cycle {
+		edges[i] = newEdgeKey(cycle[i], cycle[(i+1)%len(cycle)])
+	}
+	slices.SortFunc(edges, func(a, b edgeKey) int { return cmp.Or(a[0]-b[0], a[1]-b[1]) })
+	return edges
+}
+
+// losowe operacje na Tour i ruchy wykonywane przez Tour nie mogą rozspójnić pozycji z Order
+func TestTourRandomOperations(t *testing.T) {
+	const num_nodes = 30
+	rng := rand.New(rand.NewSource(1))
+	order := randomOrder([]int{12, 10, 8}, rng)
+	tour := NewTour(order)
+	checkTour(t, tour, num_nodes, 0, "NewTour")
+
+	// losowy wierzchołek cyklu c
+	node := func(c int) int { return order[c][rng.Intn(len(order[c]))] }
+	// losowy cykl o co najmniej size wierzchołkach
+	cycle := func(size int) int {
+		for {
+			if c := rng.Intn(len(order)); len(order[c]) >= size {
+				return c
+			}
+		}
+	}
+	for step := 1; step <= 2000; step++ {
+		var op string
+		switch rng.Intn(10) {
+		case 0:
+			op = "SwapNodes"
+			tour.SwapNodes(node(rng.Intn(len(order))), node(rng.Intn(len(order))))
+		case 1:
+			op = "Reverse"
+			c := cycle(2)
+			n := len(order[c])
+			i, j := rng.Intn(n), rng.Intn(n)
+			// oczekiwany cykl - odwrócenie ścieżki i..j wprost
+			expected := append([]int(nil), order[c]...)
+			length := (j-i+n)%n + 1
+			for k := 0; k < length/2; k++ {
+				a, b := (i+k)%n, (j-k+n)%n
+				expected[a], expected[b] = expected[b], expected[a]
+			}
+			tour.Reverse(c, i, j)
+			if !slices.Equal(cycleEdges(order[c]), cycleEdges(expected)) {
+				t.Fatalf("step %d: Reverse(%d, %d, %d) gives %v, expected the cycle %v", step, c, i, j, order[c], expected)
+			}
+		case 2:
+			op = "Remove/Insert"
+			c1 := cycle(2)
+			n := node(c1)
+			tour.Remove(n)
+			c2 := rng.Intn(len(order))
+			if err := tour.Insert(c2, rng.Intn(len(order[c2])+1), n); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		case 3:
+			op = "RemoveSegment/InsertSegment"
+			c1 := cycle(MaxOrOptSegment + 1)
+			length := 1 + rng.Intn(MaxOrOptSegment)
+			start := rng.Intn(len(order[c1])) // także segmenty z zawinięciem
+			segment := make([]int, length)
+			for k := range segment {
+				segment[k] = order[c1][(start+k)%len(order[c1])]
+			}
+			tour.RemoveSegment(c1, start, length)
+			c2 := rng.Intn(len(order))
+			tour.InsertSegment(c2, rng.Intn(len(order[c2])+1), segment)
+		case 4:
+			op = "Assign"
+			c := cycle(2)
+			start := rng.Intn(len(order[c]))
+			nodes := slices.Clone(order[c][start:])
+			rng.Shuffle(len(nodes), func(a, b int) { nodes[a], nodes[b] = nodes[b], nodes[a] })
+			tour.Assign(c, start, nodes)
+		case 5:
+			op = "MoveEdge"
+			c := cycle(2)
+			i, j := rng.Intn(len(order[c])), rng.Intn(len(order[c]))
+			move := &MoveEdge{Cycle: c, N1: min(i, j), N2: max(i, j)}
+			if err := move.ExecuteMove(tour); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		case 6:
+			op = "RelocateMove"
+			c1 := cycle(2)
+			c2 := rng.Intn(len(order))
+			move := &RelocateMove{C1: c1, C2: c2, N1: rng.Intn(len(order[c1])), N2: rng.Intn(len(order[c2]))}
+			if c1 == c2 {
+				continue
+			}
+			if err := move.ExecuteMove(tour); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		case 7:
+			op = "OrOptMove"
+			c1 := cycle(MaxOrOptSegment + 2)
+			length := 1 + rng.Intn(MaxOrOptSegment)
+			i := rng.Intn(len(order[c1]))
+			c2 := rng.Intn(len(order))
+			j := rng.Intn(len(order[c2]))
+			if c1 == c2 && !orOptIntraValid(len(order[c1]), i, length, j) {
+				continue
+			}
+			move := &OrOptMove{C1: c1, C2: c2, N1: i, Len: length, N2: j, Reversed: rng.Intn(2) == 0}
+			if err := move.ExecuteMove(tour); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		case 8:
+			op = "ThreeOptMove"
+			c := cycle(5)
+			picks := rng.Perm(len(order[c]) - 1)[:3] // I < J < K < n - 1
+			slices.Sort(picks)
+			move := &ThreeOptMove{Cycle: c, I: picks[0], J: picks[1], K: picks[2], Case: ThreeOptCase(1 + rng.Intn(4))}
+			if err := move.ExecuteMove(tour); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		case 9:
+			op = "SwapMove"
+			c1, c2 := rng.Intn(len(order)), rng.Intn(len(order))
+			move := &SwapMove{C1: c1, C2: c2, N1: rng.Intn(len(order[c1])), N2: rng.Intn(len(order[c2]))}
+			if err := move.ExecuteMove(tour); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		}
+		checkTour(t, tour, num_nodes, step, op)
+	}
+}
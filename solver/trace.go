@@ -0,0 +1,61 @@
+package solver
+
+import (
+	"time"
+)
+
+// próbka przebiegu metaheurystyki
+type TracePoint struct {
+	Elapsed   float64    `json:"elapsed"`             // sekundy od startu metaheurystyki
+	Iteration int        `json:"iteration"`           // liczba zakończonych iteracji
+	Current   int        `json:"current"`             // koszt bieżącego rozwiązania
+	Best      int        `json:"best"`                // koszt najlepszego dotąd rozwiązania
+	Diversity *Diversity `json:"diversity,omitempty"` // różnorodność populacji po ostatniej zmianie; nil - bez populacji
+}
+
+// zapis przebiegu jednego uruchomienia (obserwator do cfg.Observer, osobny dla każdego powtórzenia);
+// rozwiązania startowe i nowe najlepsze zapisywane zawsze, iteracje co najmniej co Interval
+type Trace struct {
+	Interval  time.Duration // minimalny odstęp czasu między zapisanymi iteracjami; 0 - każda iteracja
+	Points    []TracePoint
+	last      time.Duration // czas ostatniej zapisanej próbki
+	diversity *Diversity    // różnorodność z ostatniej zmiany populacji
+}
+
+func NewTrace(interval time.Duration) *Trace {
+	return &Trace{Interval: interval}
+}
+
+func (t *Trace) add(event Event) {
+	point := TracePoint{Elapsed: event.Elapsed.Seconds(), Iteration: event.Iteration, Current: event.Current, Best: event.Best, Diversity: t.diversity}
+	if n := len(t.Points); n > 0 {
+		prev := t.Points[n-1]
+		if prev.Iteration == point.Iteration && prev.Current == point.Current && prev.Best == point.Best {
+			return // ten sam stan zgłoszony kilkoma zdarzeniami
+		}
+	}
+	t.Points = append(t.Points, point)
+	t.last = event.Elapsed
+}
+
+func (t *Trace) OnNewBest(event Event) {
+	t.add(event)
+}
+
+func (t *Trace) OnIteration(event Event) {
+	if len(t.Points) == 0 || event.Elapsed-t.last >= t.Interval {
+		t.add(event)
+	}
+}
+
+// zmiany populacji bez nowego najlepszego nie zmieniają krzywej - różnorodność trafia do kolejnych próbek
+func (t *Trace) OnPopulationChange(event PopulationEvent) {
+	diversity := event.Diversity
+	t.diversity = &diversity
+}
+
+func (t *Trace) OnRestart(event Event) {
+	t.add(event)
+}
+
+func (t *Trace) OnCrossover(event CrossoverEvent) {} // potomek bez wstawienia nie zmienia krzywej
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"IMO/reader"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// macierz odległości przechowywana w jednej tablicy (wiersz po wierszu)
+type DistanceMatrix struct {
+	Dimension int    // liczba wierzchołków
+	Metric    string // nazwa metryki użytej do zbudowania macierzy
+	Symmetric bool   // czy d(i, j) == d(j, i) dla wszystkich par
+	data      []int  // odległości; d(i, j) = data[i*Dimension+j]
+}
+
+// metryka odległości między wierzchołkami instancji
+type Metric interface {
+	Name() string                                     // nazwa metryki (np. do wyboru z linii poleceń)
+	Check(instance *reader.Instance) error            // czy metryka może być użyta dla instancji
+	Distance(instance *reader.Instance, i, j int) int // odległość między wierzchołkami i oraz j
+}
+
+// metryka liczona ze współrzędnych wierzchołków
+type CoordMetric struct {
+	MetricName string
+	Dist       func(a, b reader.Node) int
+}
+
+func (m CoordMetric) Name() string {
+	return m.MetricName
+}
+
+func (m CoordMetric) Check(instance *reader.Instance) error {
+	if !instance.HasCoordinates {
+		return fmt.Errorf("%w: metric %s requires node coordinates", ErrInvalidInstance, m.MetricName)
+	}
+	return nil
+}
+
+func (m CoordMetric) Distance(instance *reader.Instance, i, j int) int {
+	return m.Dist(instance.Nodes[i], instance.Nodes[j])
+}
+
+// metryka z EDGE_WEIGHT_TYPE instancji
+type InstanceMetric struct{}
+
+func (InstanceMetric) Name() string {
+	return "auto"
+}
+
+func (InstanceMetric) Check(instance *reader.Instance) error {
+	return nil // sprawdzone przy wczytywaniu instancji
+}
+
+func (InstanceMetric) Distance(instance *reader.Instance, i, j int) int {
+	return instance.Distance(i, j)
+}
+
+// jawne wagi z EDGE_WEIGHT_SECTION
+type ExplicitMetric struct{}
+
+func (ExplicitMetric) Name() string {
+	return "explicit"
+}
+
+func (ExplicitMetric) Check(instance *reader.Instance) error {
+	if instance.Weights == nil {
+		return fmt.Errorf("%w: metric explicit requires EDGE_WEIGHT_SECTION", ErrInvalidInstance)
+	}
+	return nil
+}
+
+func (ExplicitMetric) Distance(instance *reader.Instance, i, j int) int {
+	return instance.Weights[i][j]
+}
+
+// odległość euklidesowa obcięta do części całkowitej
+func truncEucDist(a, b reader.Node) int {
+	return int(math.Sqrt(math.Pow(a.X-b.X, 2) + math.Pow(a.Y-b.Y, 2)))
+}
+
+var (
+	Euclidean        Metric = CoordMetric{"euc", truncEucDist}
+	RoundedEuclidean Metric = CoordMetric{"euc_2d", reader.EucDist2D}
+	CeilEuclidean    Metric = CoordMetric{"ceil_2d", reader.CeilDist2D}
+	Manhattan        Metric = CoordMetric{"man_2d", reader.ManDist2D}
+	Chebyshev        Metric = CoordMetric{"max_2d", reader.MaxDist2D}
+	Geographic       Metric = CoordMetric{"geo", reader.GeoDist}
+	PseudoEuclidean  Metric = CoordMetric{"att", reader.AttDist}
+	Explicit         Metric = ExplicitMetric{}
+	FromInstance     Metric = InstanceMetric{}
+)
+
+var metrics = map[string]Metric{}
+
+func init() {
+	for _, m := range []Metric{Euclidean, RoundedEuclidean, CeilEuclidean, Manhattan, Chebyshev, Geographic, PseudoEuclidean, Explicit, FromInstance} {
+		RegisterMetric(m)
+	}
+}
+
+// rejestracja metryki pod jej nazwą - dostępna w MetricByName
+func RegisterMetric(metric Metric) {
+	metrics[metric.Name()] = metric
+}
+
+func MetricByName(name string) (Metric, error) {
+	metric, ok := metrics[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %s)", ErrUnknownMetric, name, strings.Join(MetricNames(), ", "))
+	}
+	return metric, nil
+}
+
+// nazwy zarejestrowanych metryk, posortowane
+func MetricNames() []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// budowa macierzy odległości dla instancji w podanej metryce
+func NewDistanceMatrix(instance *reader.Instance, metric Metric) (*DistanceMatrix, error) {
+	if instance == nil || instance.Dimension < 1 || len(instance.Nodes) != instance.Dimension {
+		return nil, fmt.Errorf("%w: no nodes or DIMENSION not matching the number of nodes", ErrInvalidInstance)
+	}
+	if metric == nil {
+		return nil, fmt.Errorf("%w: no metric given", ErrInvalidArgument)
+	}
+	if err := metric.Check(instance); err != nil {
+		return nil, err
+	}
+	n := instance.Dimension
+	distance_matrix := &DistanceMatrix{
+		Dimension: n,
+		Metric:    metric.Name(),
+		Symmetric: true,
+		data:      make([]int, n*n),
+	}
+	if _, ok := metric.(InstanceMetric); ok {
+		distance_matrix.Metric = instance.EdgeWeightType
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				distance_matrix.data[i*n+j] = metric.Distance(instance, i, j)
+			}
+		}
+	}
+	for i := 0; i < n && distance_matrix.Symmetric; i++ {
+		for j := i + 1; j < n; j++ {
+			if distance_matrix.data[i*n+j] != distance_matrix.data[j*n+i] {
+				distance_matrix.Symmetric = false
+				break
+			}
+		}
+	}
+	return distance_matrix, nil
+}
+
+// odległość z i do j
+func (dm *DistanceMatrix) At(i, j int) int {
+	return dm.data[i*dm.Dimension+j]
+}
+
+// odległości z i do wszystkich wierzchołków - fragment macierzy, nie modyfikować
+func (dm *DistanceMatrix) Row(i int) []int {
+	return dm.data[i*dm.Dimension : (i+1)*dm.Dimension]
+}
+
+// największa odległość w macierzy: (kolumna, wiersz, wartość) jak w MatrixMax
+func (dm *DistanceMatrix) Max() (int, int, int) {
+	max := math.MinInt64
+	x := 0
+	y := 0
+	for i := 0; i < dm.Dimension; i++ {
+		for j, value := range dm.Row(i) {
+			if value > max {
+				max = value
+				x = j
+				y = i
+			}
+		}
+	}
+	return x, y, max
+}
@@ -0,0 +1,10 @@
+package utils
+
+import "errors"
+
+// rodzaje błędów pakietu - sprawdzane przez errors.Is; zwracane błędy opakowują je (%w) z kontekstem
+var (
+	ErrInvalidInstance = errors.New("invalid instance") // instancja lub macierz odległości nie nadaje się do obliczeń
+	ErrUnknownMetric   = errors.New("unknown metric")   // brak zarejestrowanej metryki o podanej nazwie
+	ErrInvalidArgument = errors.New("invalid argument") // niepoprawny argument funkcji pomocniczej
+)
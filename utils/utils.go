@@ -58,19 +58,19 @@ func (edge *Edge) String() string {
 	return buf.String()
 }
 
-func NewEdge(from int, to int, distance_matrix [][]int, prev *Edge, next *Edge) *Edge {
+func NewEdge(from int, to int, distance_matrix *DistanceMatrix, prev *Edge, next *Edge) *Edge {
 	return &Edge{
 		From:   from,
 		To:     to,
 		Prev:   prev,
 		Next:   next,
-		Length: distance_matrix[from][to],
+		Length: distance_matrix.At(from, to),
 	}
 }
 
 // o ile zwiększy się cykl po dodaniu wierzchołka w miejsce krawędzi
-func EdgeInsertValue(distance_matrix [][]int, node int, edge *Edge) int {
-	return distance_matrix[node][edge.To] + distance_matrix[node][edge.From] - edge.Length
+func EdgeInsertValue(distance_matrix *DistanceMatrix, node int, edge *Edge) int {
+	return distance_matrix.At(node, edge.To) + distance_matrix.At(node, edge.From) - edge.Length
 }
 
 func EdgeToNodeCycle(edge *Edge) []int {
@@ -88,7 +88,7 @@ func EdgeToNodeCycle(edge *Edge) []int {
 	return cycle
 }
 
-func UpdateDistances(eLL *EdgeLinkedList, distance_matrix [][]int, delEdges []int, newEdges []EdgeLinkedList, newEdgesSorted bool) *EdgeLinkedList {
+func UpdateDistances(eLL *EdgeLinkedList, distance_matrix *DistanceMatrix, delEdges []int, newEdges []EdgeLinkedList, newEdgesSorted bool) *EdgeLinkedList {
 	var remainingDelete int = len(delEdges)
 	if !newEdgesSorted {
 		// sortuje rosnąco - chcemy malejąco (najlepsze na końcu) więc przeciwnie: j-i zamiast i-j
@@ -175,15 +175,16 @@ func MatrixMax(matrix [][]int) (int, int, int) {
 	return x, y, max
 }
 
-func Insert[T any](array []T, i int, j T) []T {
-	var new_arr []T = make([]T, len(array)+1)
-	if i < 0 || i >= len(array) {
-		panic("Index out of range")
+// wstawienie j przed indeks i - nowa tablica; i == len(array) - dopisanie na końcu
+func Insert[T any](array []T, i int, j T) ([]T, error) {
+	if i < 0 || i > len(array) {
+		return nil, fmt.Errorf("%w: insert index %d out of range [0, %d]", ErrInvalidArgument, i, len(array))
 	}
+	var new_arr []T = make([]T, len(array)+1)
 	copy(new_arr[:i], array[:i])
 	new_arr[i] = j
 	copy(new_arr[i+1:], array[i:])
-	return new_arr
+	return new_arr, nil
 }
 func MaxOfArray(arr []int) (int, int, error) {
 	idx := -1
@@ -196,38 +197,49 @@ func MaxOfArray(arr []int) (int, int, error) {
 	}
 	return max, idx, nil
 }
-func CalculateCycleLen(order []int, distance_matrix [][]int) int {
+func CalculateCycleLen(order []int, distance_matrix *DistanceMatrix) int {
+	cost := 0
+	for i := range order {
+		cost += distance_matrix.At(order[i], order[(i+1)%len(order)])
+	}
+	return cost
+}
+
+// suma długości wszystkich cykli
+func CalculateCyclesLen(order [][]int, distance_matrix *DistanceMatrix) int {
 	cost := 0
 	for i := range order {
-		cost += distance_matrix[order[i]][order[(i+1)%len(order)]]
+		cost += CalculateCycleLen(order[i], distance_matrix)
 	}
 	return cost
 }
 
-func FarthestNode(nodes []reader.Node, distance_matrix [][]int, node int, visited []bool) (farthest int, err error) {
+func FarthestNode(nodes []reader.Node, distance_matrix *DistanceMatrix, node int, visited []bool) (farthest int, err error) {
 	max := math.MinInt64
-	for i := range distance_matrix[node] {
-		if !visited[i] && i != node && distance_matrix[node][i] > max {
-			max = distance_matrix[node][i]
+	farthest = -1
+	for i := range distance_matrix.Row(node) {
+		if !visited[i] && i != node && distance_matrix.At(node, i) > max {
+			max = distance_matrix.At(node, i)
 			farthest = i
 		}
 	}
 	if farthest == -1 {
-		err = fmt.Errorf("no farthest node found for %v - invalid distance matrix", node)
+		err = fmt.Errorf("%w: no farthest node found for %v", ErrInvalidInstance, node)
 	}
 	return
 }
 
-func NearestNode(nodes []reader.Node, distance_matrix [][]int, node int, visited []bool) (nearest int, err error) {
+func NearestNode(nodes []reader.Node, distance_matrix *DistanceMatrix, node int, visited []bool) (nearest int, err error) {
 	min := math.MaxInt64
-	for i := range distance_matrix[node] {
-		if !visited[i] && i != node && distance_matrix[node][i] < min {
-			min = distance_matrix[node][i]
+	nearest = -1
+	for i := range distance_matrix.Row(node) {
+		if !visited[i] && i != node && distance_matrix.At(node, i) < min {
+			min = distance_matrix.At(node, i)
 			nearest = i
 		}
 	}
 	if nearest == -1 {
-		err = fmt.Errorf("no nearest node found for %v - invalid distance matrix", node)
+		err = fmt.Errorf("%w: no nearest node found for %v", ErrInvalidInstance, node)
 	}
 	return
 }
@@ -284,27 +296,49 @@ func IndexesOf[T comparable](arr []T, values []T) []int {
 
 type Empty struct{}
 
+// usunięcie elementów o indeksach index (dowolna kolejność, powtórzenia i indeksy spoza s pomijane) w miejscu;
+// zachowane elementy przed ostatnim usuwanym indeksem przesuwane w prawo, więc koszt zależy od tego indeksu,
+// a nie od długości s - wynik jest końcówką s
 func RemoveIndexes[T any](s []T, index []int) []T {
-	indexSet := make(map[int]Empty, len(index))
-	for _, idx := range index {
-		indexSet[idx] = Empty{}
+	sorted := slices.Clone(index)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	for len(sorted) > 0 && sorted[len(sorted)-1] >= len(s) {
+		sorted = sorted[:len(sorted)-1]
+	}
+	for len(sorted) > 0 && sorted[0] < 0 {
+		sorted = sorted[1:]
+	}
+	if len(sorted) == 0 {
+		return s
 	}
 
-	ret := make([]T, 0, len(s)-len(index)) // prealokacja
-	for i, v := range s {
-		if _, found := indexSet[i]; !found { // tylko sprawdzenie czy nie ma w mapie
-			ret = append(ret, v)
+	end := sorted[len(sorted)-1] + 1 // elementy od end zostają na miejscu
+	w, k := end, len(sorted)-1       // miejsce zapisu i następny usuwany indeks, od prawej
+	for r := end - 1; r >= 0; r-- {
+		if k >= 0 && sorted[k] == r {
+			k--
+			continue
 		}
+		w--
+		s[w] = s[r]
 	}
-	return ret
+	clear(s[:w]) // bez referencji do usuniętych elementów
+	return s[w:]
 }
+
 func Remove(slice []int, s int) []int {
 	return append(slice[:s], slice[s+1:]...)
 }
 
+// kopiowanie wartości cykli (nie wskaźników), długości cykli w dst dopasowywane do cycles
 func CopyCycles(dst [][]int, cycles [][]int) error {
-	copy(dst[0], cycles[0])
-	copy(dst[1], cycles[1])
+	if len(dst) != len(cycles) {
+		return fmt.Errorf("%w: cannot copy %d cycles into %d", ErrInvalidArgument, len(cycles), len(dst))
+	}
+	for i := range cycles {
+		dst[i] = append(dst[i][:0], cycles[i]...)
+	}
 	return nil
 }
 
@@ -319,36 +353,37 @@ func IndexBetterInSortedArray(slice []int, value int) int {
 	return -1 // nie jest lepszy od żadnego elementu
 }
 
-func InsertRetainSize[T any](slice []T, value T, index int) {
+func InsertRetainSize[T any](slice []T, value T, index int) error {
 	// insert to index, push values further back and remove last value to retain size
 	if index < 0 || index >= len(slice) {
-		panic("index out of range")
+		return fmt.Errorf("%w: index %d out of range [0, %d)", ErrInvalidArgument, index, len(slice))
 	}
 
 	copy(slice[index+1:], slice[index:len(slice)-1])
 	slice[index] = value
+	return nil
 }
 
 // max_val non-inclusive
-func Pick2RandomValues(max_val int) (int, int, error) {
+func Pick2RandomValues(max_val int, rng *rand.Rand) (int, int, error) {
 	if max_val < 2 {
-		panic("max_val must be at least 2")
+		return 0, 0, fmt.Errorf("%w: cannot pick 2 distinct values below %d", ErrInvalidArgument, max_val)
 	}
-	val1 := rand.Intn(max_val)
+	val1 := rng.Intn(max_val)
 	val2 := val1
 	for val1 == val2 {
-		val2 = rand.Intn(max_val)
+		val2 = rng.Intn(max_val)
 	}
 	return val1, val2, nil
 }
 
-func MatrixLogicAND(a [][]bool, b [][]bool) [][]bool {
+func MatrixLogicAND(a [][]bool, b [][]bool) ([][]bool, error) {
 	if len(a) != len(b) {
-		panic("Matrixes must be the same size")
+		return nil, fmt.Errorf("%w: matrices with %d and %d rows", ErrInvalidArgument, len(a), len(b))
 	}
 	for i := range a {
 		if len(a[i]) != len(b[i]) {
-			panic("Matrixes must be the same size")
+			return nil, fmt.Errorf("%w: rows %d of matrices differ in length", ErrInvalidArgument, i)
 		}
 	}
 	result := make([][]bool, len(a))
@@ -358,7 +393,7 @@ func MatrixLogicAND(a [][]bool, b [][]bool) [][]bool {
 			result[i][j] = a[i][j] && b[i][j]
 		}
 	}
-	return result
+	return result, nil
 }
 
 func Reverse[T any](slice []T) {